@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/mosesmmoisebidth/music_backend/internal/buildinfo"
 	"github.com/mosesmmoisebidth/music_backend/internal/config"
 	"github.com/mosesmmoisebidth/music_backend/internal/server"
 	"github.com/mosesmmoisebidth/music_backend/internal/storage"
+	"github.com/mosesmmoisebidth/music_backend/internal/tracing"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 )
 
@@ -48,13 +50,28 @@ func main() {
 		logger.Fatal("Failed to load configuration", "error", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, logger, os.Args[2:]))
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, cfg.App)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", "error", err)
+	}
+
 	storage, err := storage.New(cfg.Database, cfg.Redis)
 	if err != nil {
 		logger.Fatal("Failed to initialize storage", "error", err)
 	}
 
-	if err := storage.AutoMigrate(); err != nil {
-		logger.Fatal("Failed to run migrations", "error", err)
+	if cfg.Database.AutoMigrate {
+		sqlDB, err := storage.DB.DB()
+		if err != nil {
+			logger.Fatal("Failed to get database connection", "error", err)
+		}
+		if err := migrateOnStart(sqlDB, logger); err != nil {
+			logger.Fatal("Failed to run migrations", "error", err)
+		}
 	}
 
 	srv, err := server.New(cfg, storage, logger)
@@ -62,6 +79,10 @@ func main() {
 		logger.Fatal("Failed to initialize server", "error", err)
 	}
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	srv.StartBackgroundWorkers(workerCtx)
+
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Server.Port),
 		Handler:      srv.Router(),
@@ -71,7 +92,8 @@ func main() {
 	}
 
 	go func() {
-		logger.Info("Starting server", "port", cfg.Server.Port, "url", fmt.Sprintf("http://localhost:%s", cfg.Server.Port))
+		build := buildinfo.Get()
+		logger.Info("Starting server", "port", cfg.Server.Port, "url", fmt.Sprintf("http://localhost:%s", cfg.Server.Port), "version", build.Version, "commit", build.Commit)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", "error", err)
 		}
@@ -90,9 +112,19 @@ func main() {
 		logger.Fatal("Server forced to shutdown", "error", err)
 	}
 
+	// Stop workers after the HTTP server so no new downloads are queued mid-shutdown
+	if err := srv.StopBackgroundWorkers(ctx); err != nil {
+		logger.Error("Background workers did not stop cleanly", "error", err)
+	}
+
 	if err := storage.Close(); err != nil {
 		logger.Error("Error closing storage connections", "error", err)
 	}
 
+	// Flush spans last so those recorded while shutting down are exported too
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Error flushing traces", "error", err)
+	}
+
 	logger.Info("Server exited")
 }
\ No newline at end of file
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/config"
+	"github.com/mosesmmoisebidth/music_backend/internal/migrations"
+	"github.com/mosesmmoisebidth/music_backend/internal/storage"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    roll back the last n applied migrations (default 1)
+  status      list migrations and whether they are applied`
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code.
+func runMigrate(cfg *config.Config, logger logger.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := storage.NewDatabase(cfg.Database)
+	if err != nil {
+		logger.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("Failed to get database connection", "error", err)
+		return 1
+	}
+	defer sqlDB.Close()
+
+	migrator, err := migrations.New(sqlDB, logger)
+	if err != nil {
+		logger.Error("Failed to load migrations", "error", err)
+		return 1
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Error("Migration failed", "error", err)
+			return 1
+		}
+		logger.Info("Migrations applied", "count", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "down expects a positive number of steps")
+				return 2
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			logger.Error("Rollback failed", "error", err)
+			return 1
+		}
+		logger.Info("Migrations rolled back", "count", rolledBack)
+	case "status":
+		if err := printMigrationStatus(ctx, migrator); err != nil {
+			logger.Error("Failed to read migration status", "error", err)
+			return 1
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
+
+// printMigrationStatus writes a table of migrations and their applied times to stdout.
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
+
+// migrateOnStart applies pending migrations before the server starts serving.
+func migrateOnStart(sqlDB *sql.DB, logger logger.Logger) error {
+	migrator, err := migrations.New(sqlDB, logger)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		logger.Info("Database migrations applied", "count", applied)
+	}
+	return nil
+}
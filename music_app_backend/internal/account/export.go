@@ -0,0 +1,261 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/library"
+)
+
+// profileExport is profile.json in an export archive
+type profileExport struct {
+	ID               uuid.UUID        `json:"id"`
+	Email            *string          `json:"email"`
+	EmailVerifiedAt  *time.Time       `json:"email_verified_at"`
+	DisplayName      *string          `json:"display_name"`
+	PhotoURL         *string          `json:"photo_url"`
+	Roles            []string         `json:"roles"`
+	Preferences      json.RawMessage  `json:"preferences,omitempty"`
+	FavoriteGenres   []string         `json:"favorite_genres"`
+	HasPassword      bool             `json:"has_password"`
+	TwoFactorEnabled bool             `json:"two_factor_enabled"`
+	LinkedLogins     []identityExport `json:"linked_logins"`
+	LastLoginAt      *time.Time       `json:"last_login_at"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+type identityExport struct {
+	Provider   string     `json:"provider"`
+	Subject    string     `json:"subject"`
+	Email      *string    `json:"email"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// playlistExport is an entry of playlists.json in an export archive
+type playlistExport struct {
+	ID          uuid.UUID             `json:"id"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	CoverURL    *string               `json:"cover_url"`
+	IsPublic    bool                  `json:"is_public"`
+	ShareCode   *string               `json:"share_code"`
+	Tracks      []playlistTrackExport `json:"tracks"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+type playlistTrackExport struct {
+	Position        int       `json:"position"`
+	Provider        string    `json:"provider"`
+	ProviderTrackID string    `json:"provider_track_id"`
+	Title           string    `json:"title"`
+	Artist          string    `json:"artist"`
+	Album           string    `json:"album"`
+	DurationMs      int       `json:"duration_ms"`
+	AddedAt         time.Time `json:"added_at"`
+}
+
+// writeArchive writes a ZIP of everything stored about a user: their profile and playlists
+// as JSON, and their favorites, listening history, downloads and sessions as CSV.
+func (s *Service) writeArchive(ctx context.Context, w io.Writer, userID uuid.UUID) error {
+	archive := zip.NewWriter(w)
+
+	if err := s.writeProfile(ctx, archive, userID); err != nil {
+		return err
+	}
+	if err := s.writePlaylists(ctx, archive, userID); err != nil {
+		return err
+	}
+
+	err := writeCSV(archive, "favorites.csv", []string{"added_at", "provider", "provider_track_id", "title", "artist", "album", "duration_ms"},
+		func(write func([]string) error) error {
+			return s.repo.EachFavorite(ctx, userID, func(batch []library.Favorite) error {
+				for _, f := range batch {
+					if err := write([]string{formatTime(f.AddedAt), f.Provider, f.ProviderTrackID, f.Title, f.Artist, f.Album, strconv.Itoa(f.DurationMs)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	if err != nil {
+		return err
+	}
+
+	err = writeCSV(archive, "history.csv", []string{"played_at", "provider", "provider_track_id", "title", "artist", "album", "duration_ms"},
+		func(write func([]string) error) error {
+			return s.repo.EachHistory(ctx, userID, func(batch []library.History) error {
+				for _, h := range batch {
+					if err := write([]string{formatTime(h.PlayedAt), h.Provider, h.ProviderTrackID, h.Title, h.Artist, h.Album, strconv.Itoa(h.DurationMs)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	if err != nil {
+		return err
+	}
+
+	err = writeCSV(archive, "downloads.csv", []string{"created_at", "provider", "provider_track_id", "title", "artist", "album", "quality", "state"},
+		func(write func([]string) error) error {
+			return s.repo.EachDownload(ctx, userID, func(batch []library.Download) error {
+				for _, d := range batch {
+					if err := write([]string{formatTime(d.CreatedAt), d.Provider, d.ProviderTrackID, d.Title, d.Artist, d.Album, d.Quality, string(d.State)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	if err != nil {
+		return err
+	}
+
+	err = writeCSV(archive, "sessions.csv", []string{"session_id", "issued_at", "expires_at", "revoked", "ip", "user_agent"},
+		func(write func([]string) error) error {
+			return s.repo.EachRefreshToken(ctx, userID, func(batch []auth.RefreshToken) error {
+				for _, t := range batch {
+					if err := write([]string{t.FamilyID.String(), formatTime(t.IssuedAt), formatTime(t.ExpiresAt), strconv.FormatBool(t.Revoked), stringValue(t.IP), stringValue(t.UserAgent)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (s *Service) writeProfile(ctx context.Context, archive *zip.Writer, userID uuid.UUID) error {
+	u, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	identities, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load linked logins: %w", err)
+	}
+	mfaEnabled, err := s.repo.HasConfirmedMFA(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load two-factor status: %w", err)
+	}
+
+	profile := profileExport{
+		ID:               u.ID,
+		Email:            u.Email,
+		EmailVerifiedAt:  u.EmailVerifiedAt,
+		DisplayName:      u.DisplayName,
+		PhotoURL:         u.PhotoURL,
+		Roles:            u.Roles,
+		FavoriteGenres:   u.FavoriteGenres,
+		HasPassword:      u.Password != nil,
+		TwoFactorEnabled: mfaEnabled,
+		LinkedLogins:     make([]identityExport, 0, len(identities)),
+		LastLoginAt:      u.LastLoginAt,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
+	}
+	if len(u.Preferences) > 0 {
+		profile.Preferences = json.RawMessage(u.Preferences)
+	}
+	for _, identity := range identities {
+		profile.LinkedLogins = append(profile.LinkedLogins, identityExport{
+			Provider:   identity.Provider,
+			Subject:    identity.Subject,
+			Email:      identity.Email,
+			LastUsedAt: identity.LastUsedAt,
+			CreatedAt:  identity.CreatedAt,
+		})
+	}
+
+	return writeJSON(archive, "profile.json", profile)
+}
+
+func (s *Service) writePlaylists(ctx context.Context, archive *zip.Writer, userID uuid.UUID) error {
+	playlists, err := s.repo.ListPlaylists(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load playlists: %w", err)
+	}
+
+	exports := make([]playlistExport, 0, len(playlists))
+	for _, p := range playlists {
+		export := playlistExport{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Description,
+			CoverURL:    p.CoverURL,
+			IsPublic:    p.IsPublic,
+			ShareCode:   p.ShareCode,
+			Tracks:      make([]playlistTrackExport, 0, len(p.Tracks)),
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+		}
+		for _, t := range p.Tracks {
+			export.Tracks = append(export.Tracks, playlistTrackExport{
+				Position:        t.Position,
+				Provider:        string(t.Provider),
+				ProviderTrackID: t.ProviderTrackID,
+				Title:           t.Title,
+				Artist:          t.Artist,
+				Album:           t.Album,
+				DurationMs:      t.DurationMs,
+				AddedAt:         t.AddedAt,
+			})
+		}
+		exports = append(exports, export)
+	}
+
+	return writeJSON(archive, "playlists.json", exports)
+}
+
+func writeJSON(archive *zip.Writer, name string, value interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writeCSV adds a CSV file to the archive, with rows written by the rows function
+func writeCSV(archive *zip.Writer, name string, header []string, rows func(write func([]string) error) error) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	records := csv.NewWriter(w)
+	if err := records.Write(header); err != nil {
+		return err
+	}
+	if err := rows(records.Write); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	records.Flush()
+	return records.Error()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
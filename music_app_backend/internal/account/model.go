@@ -0,0 +1,51 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportState represents the state of a data export.
+type ExportState string
+
+const (
+	ExportPending    ExportState = "pending"
+	ExportProcessing ExportState = "processing"
+	ExportCompleted  ExportState = "completed"
+	ExportFailed     ExportState = "failed"
+)
+
+// DataExport is a ZIP archive of everything stored about a user, generated in the background
+// and kept until it expires.
+type DataExport struct {
+	ID             uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID         uuid.UUID   `gorm:"type:uuid;not null;index"`
+	State          ExportState `gorm:"not null;size:20;index"`
+	ObjectKey      *string     `gorm:"size:1024"` // blob key of the archive once completed
+	FileSize       *int64
+	FailureReason  *string    `gorm:"size:1024"`
+	Attempts       int        `gorm:"not null;default:0"`
+	LeaseExpiresAt *time.Time // a processing export whose lease has lapsed may be reclaimed
+	CompletedAt    *time.Time
+	ExpiresAt      *time.Time // when the archive is removed
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TableName returns the table name for the DataExport model
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// isCurrent reports whether the export is still in progress or its archive can still be
+// downloaded at now
+func (e *DataExport) isCurrent(now time.Time) bool {
+	switch e.State {
+	case ExportPending, ExportProcessing:
+		return true
+	case ExportCompleted:
+		return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+	}
+	return false
+}
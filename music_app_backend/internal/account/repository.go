@@ -0,0 +1,266 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/library"
+	"github.com/mosesmmoisebidth/music_backend/internal/playlist"
+	"github.com/mosesmmoisebidth/music_backend/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// exportBatchSize is how many rows are read at a time while writing an export.
+const exportBatchSize = 500
+
+// Repository provides access to the data exports and, for exporting and purging accounts,
+// to every table that holds a user's data.
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new account repository.
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// --- Data Exports ---
+
+// CreateExport records a new export request.
+func (r *Repository) CreateExport(ctx context.Context, export *DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+// GetLatestExport returns the user's most recent export.
+func (r *Repository) GetLatestExport(ctx context.Context, userID uuid.UUID) (*DataExport, error) {
+	var export DataExport
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Take(&export).Error
+	return &export, err
+}
+
+// ClaimExport locks the next export waiting to be generated, marks it processing and leases
+// it to the caller until the lease expires. Rows locked by other replicas are skipped. It
+// returns gorm.ErrRecordNotFound when nothing is waiting.
+func (r *Repository) ClaimExport(ctx context.Context, lease time.Duration) (*DataExport, error) {
+	var export DataExport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("state = ? OR (state = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?))", ExportPending, ExportProcessing, now).
+			Order("created_at ASC").
+			Take(&export).Error
+		if err != nil {
+			return err
+		}
+
+		leaseExpiresAt := now.Add(lease)
+		err = tx.Model(&export).UpdateColumns(map[string]interface{}{
+			"state":            ExportProcessing,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"lease_expires_at": leaseExpiresAt,
+			"updated_at":       now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		export.State = ExportProcessing
+		export.Attempts++
+		export.LeaseExpiresAt = &leaseExpiresAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// UpdateClaimedExport applies updates to an export claimed by ClaimExport. It returns
+// gorm.ErrRecordNotFound if the claim was lost to another replica or the export was removed.
+func (r *Repository) UpdateClaimedExport(ctx context.Context, export *DataExport, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&DataExport{}).
+		Where("id = ? AND state = ? AND attempts = ?", export.ID, ExportProcessing, export.Attempts).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListExpiredExports returns up to limit exports whose archives expired before now.
+func (r *Repository) ListExpiredExports(ctx context.Context, now time.Time, limit int) ([]DataExport, error) {
+	var exports []DataExport
+	err := r.db.WithContext(ctx).Where("expires_at < ?", now).Order("expires_at ASC").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// DeleteExport removes an export record.
+func (r *Repository) DeleteExport(ctx context.Context, exportID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", exportID).Delete(&DataExport{}).Error
+}
+
+// --- Exported Data ---
+
+// GetUser returns the user an export is for.
+func (r *Repository) GetUser(ctx context.Context, userID uuid.UUID) (*user.User, error) {
+	var u user.User
+	err := r.db.WithContext(ctx).Where("id = ?", userID).Take(&u).Error
+	return &u, err
+}
+
+// ListIdentities returns the external logins linked to a user.
+func (r *Repository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]user.Identity, error) {
+	var identities []user.Identity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// HasConfirmedMFA reports whether a user has two-factor authentication enabled.
+func (r *Repository) HasConfirmedMFA(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&auth.TOTPFactor{}).Where("user_id = ? AND confirmed_at IS NOT NULL", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// ListPlaylists returns a user's playlists with their tracks in order.
+func (r *Repository) ListPlaylists(ctx context.Context, userID uuid.UUID) ([]playlist.Playlist, error) {
+	var playlists []playlist.Playlist
+	err := r.db.WithContext(ctx).
+		Preload("Tracks", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&playlists).Error
+	return playlists, err
+}
+
+// EachFavorite calls fn with successive batches of a user's favorites, oldest first.
+func (r *Repository) EachFavorite(ctx context.Context, userID uuid.UUID, fn func([]library.Favorite) error) error {
+	return eachBatch(ctx, r.db.Model(&library.Favorite{}).Where("user_id = ?", userID).Order("added_at ASC, id ASC"), fn)
+}
+
+// EachHistory calls fn with successive batches of a user's listening history, oldest first.
+func (r *Repository) EachHistory(ctx context.Context, userID uuid.UUID, fn func([]library.History) error) error {
+	return eachBatch(ctx, r.db.Model(&library.History{}).Where("user_id = ?", userID).Order("played_at ASC, id ASC"), fn)
+}
+
+// EachDownload calls fn with successive batches of a user's downloads, oldest first.
+func (r *Repository) EachDownload(ctx context.Context, userID uuid.UUID, fn func([]library.Download) error) error {
+	return eachBatch(ctx, r.db.Model(&library.Download{}).Where("user_id = ?", userID).Order("created_at ASC, id ASC"), fn)
+}
+
+// EachRefreshToken calls fn with successive batches of the refresh tokens issued to a user,
+// including revoked and expired ones, oldest first.
+func (r *Repository) EachRefreshToken(ctx context.Context, userID uuid.UUID, fn func([]auth.RefreshToken) error) error {
+	return eachBatch(ctx, r.db.Model(&auth.RefreshToken{}).Where("user_id = ?", userID).Order("issued_at ASC, id ASC"), fn)
+}
+
+// eachBatch pages through the rows an ordered query matches, so large histories are never
+// loaded at once.
+func eachBatch[T any](ctx context.Context, query *gorm.DB, fn func([]T) error) error {
+	query = query.WithContext(ctx)
+	for offset := 0; ; offset += exportBatchSize {
+		var batch []T
+		if err := query.Offset(offset).Limit(exportBatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// --- Deleted Accounts ---
+
+// ListUsersDueForPurge returns up to limit users whose deletion was requested before cutoff.
+func (r *Repository) ListUsersDueForPurge(ctx context.Context, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&user.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PurgeUser deletes a user whose deletion was requested before cutoff, along with every row
+// they own, in one transaction. Auth events are kept for security auditing, stripped of
+// anything identifying the user. It returns the blob keys of the user's files, which the
+// caller deletes once the transaction has committed, or gorm.ErrRecordNotFound if the user
+// signed in again and is no longer due.
+func (r *Repository) PurgeUser(ctx context.Context, userID uuid.UUID, cutoff time.Time) ([]string, error) {
+	var keys []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the user serializes the purge with a sign-in that restores the account: the
+		// restore waits for the lock and then finds no pending deletion, failing the sign-in
+		var u user.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", userID, cutoff).
+			Take(&u).Error
+		if err != nil {
+			return err
+		}
+
+		var downloadKeys, exportKeys []string
+		if err := tx.Model(&library.Download{}).Where("user_id = ? AND local_path IS NOT NULL", userID).Pluck("local_path", &downloadKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&DataExport{}).Where("user_id = ? AND object_key IS NOT NULL", userID).Pluck("object_key", &exportKeys).Error; err != nil {
+			return err
+		}
+		keys = append(downloadKeys, exportKeys...)
+
+		playlistIDs := tx.Model(&playlist.Playlist{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("playlist_id IN (?)", playlistIDs).Delete(&playlist.PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+
+		owned := []interface{}{
+			&playlist.Playlist{},
+			&library.Favorite{},
+			&library.History{},
+			&library.Download{},
+			&auth.RefreshToken{},
+			&auth.OneTimeToken{},
+			&auth.TOTPFactor{},
+			&auth.RecoveryCode{},
+			&user.Identity{},
+			&DataExport{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		events := tx.Model(&auth.AuthEvent{}).Where("user_id = ?", userID)
+		if u.Email != nil {
+			events = events.Or("email = ?", *u.Email)
+		}
+		err = events.Updates(map[string]interface{}{
+			"user_id":    nil,
+			"email":      nil,
+			"ip":         "",
+			"user_agent": "",
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&u).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
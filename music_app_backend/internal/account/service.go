@@ -0,0 +1,249 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/blob"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	exportLease       = 15 * time.Minute // how long a replica may spend generating one export
+	maxExportAttempts = 3
+	purgeBatchSize    = 100
+)
+
+var (
+	ErrExportNotFound = errors.New("data export not found")
+	ErrExportNotReady = errors.New("data export has not completed")
+)
+
+// Config controls how long deleted accounts and finished exports are kept.
+type Config struct {
+	DeletionGracePeriod time.Duration // how long a deleted account can be restored by signing in
+	ExportTTL           time.Duration // how long a finished export can be downloaded
+}
+
+// Service exports users' personal data and purges the accounts they deleted.
+type Service struct {
+	repo   *Repository
+	blobs  blob.Store
+	config Config
+	logger logger.Logger
+}
+
+// NewService creates a new account service. Export archives are stored in blobs.
+func NewService(repo *Repository, blobs blob.Store, config Config, logger logger.Logger) *Service {
+	return &Service{repo: repo, blobs: blobs, config: config, logger: logger}
+}
+
+// PurgeAfter returns when an account deleted at deletedAt is purged.
+func (s *Service) PurgeAfter(deletedAt time.Time) time.Time {
+	return deletedAt.Add(s.config.DeletionGracePeriod)
+}
+
+// --- Data Exports ---
+
+// RequestExport returns the user's current export, queueing a new export of everything
+// stored about them when there is none. An export counts as current while it is queued, being
+// generated, or completed and not yet expired; failed and expired exports are replaced.
+func (s *Service) RequestExport(ctx context.Context, userIDStr string) (*DataExport, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	latest, err := s.repo.GetLatestExport(ctx, userID)
+	if err == nil && latest.isCurrent(time.Now()) {
+		return latest, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	export := &DataExport{ID: uuid.New(), UserID: userID, State: ExportPending}
+	if err := s.repo.CreateExport(ctx, export); err != nil {
+		s.logger.WithContext(ctx).Error("failed to create data export", "error", err, "userID", userID)
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Data export requested", "userID", userID, "exportID", export.ID)
+	return export, nil
+}
+
+// GetLatestExport returns the user's most recent export.
+func (s *Service) GetLatestExport(ctx context.Context, userIDStr string) (*DataExport, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	export, err := s.repo.GetLatestExport(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// OpenExportFile opens the archive of the user's most recent export. The caller must close it.
+func (s *Service) OpenExportFile(ctx context.Context, userIDStr string) (*DataExport, *blob.Object, error) {
+	export, err := s.GetLatestExport(ctx, userIDStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.State != ExportCompleted || export.ObjectKey == nil {
+		return nil, nil, ErrExportNotReady
+	}
+
+	object, err := s.blobs.Open(ctx, *export.ObjectKey)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, nil, ErrExportNotFound
+		}
+		return nil, nil, err
+	}
+	return export, object, nil
+}
+
+// GenerateExports generates every queued export and returns how many completed.
+func (s *Service) GenerateExports(ctx context.Context) (int, error) {
+	completed := 0
+	for ctx.Err() == nil {
+		export, err := s.repo.ClaimExport(ctx, exportLease)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return completed, nil
+			}
+			return completed, fmt.Errorf("failed to claim data export: %w", err)
+		}
+
+		if s.generate(ctx, export) {
+			completed++
+		}
+	}
+	return completed, ctx.Err()
+}
+
+// generate writes a claimed export's archive and records the outcome.
+func (s *Service) generate(ctx context.Context, export *DataExport) bool {
+	log := s.logger.With("exportID", export.ID, "userID", export.UserID, "attempt", export.Attempts)
+	key := fmt.Sprintf("exports/%s/%s.zip", export.UserID, export.ID)
+
+	// The archive is streamed into the blob store as it is written
+	reader, writer := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := s.writeArchive(ctx, writer, export.UserID)
+		writer.CloseWithError(err)
+		written <- err
+	}()
+	size, err := s.blobs.Put(ctx, key, reader)
+	reader.CloseWithError(err)
+	if writeErr := <-written; writeErr != nil {
+		err = writeErr
+	}
+
+	if err == nil {
+		now := time.Now()
+		err = s.repo.UpdateClaimedExport(ctx, export, map[string]interface{}{
+			"state":            ExportCompleted,
+			"object_key":       key,
+			"file_size":        size,
+			"failure_reason":   nil,
+			"lease_expires_at": nil,
+			"completed_at":     now,
+			"expires_at":       now.Add(s.config.ExportTTL),
+		})
+		if err == nil {
+			log.Info("Data export completed", "size", size)
+			return true
+		}
+	}
+
+	if deleteErr := s.blobs.Delete(context.Background(), key); deleteErr != nil {
+		log.Warn("failed to delete unfinished data export", "error", deleteErr)
+	}
+
+	state := ExportPending
+	if export.Attempts >= maxExportAttempts {
+		state = ExportFailed
+	}
+	updateErr := s.repo.UpdateClaimedExport(context.Background(), export, map[string]interface{}{
+		"state":            state,
+		"failure_reason":   err.Error(),
+		"lease_expires_at": nil,
+	})
+	if updateErr != nil && !errors.Is(updateErr, gorm.ErrRecordNotFound) {
+		log.Error("failed to record data export failure", "error", updateErr)
+	}
+	log.Warn("Data export failed", "error", err, "state", state)
+	return false
+}
+
+// RemoveExpiredExports deletes expired export archives and their records, and returns how
+// many were removed.
+func (s *Service) RemoveExpiredExports(ctx context.Context) (int, error) {
+	removed := 0
+	for ctx.Err() == nil {
+		exports, err := s.repo.ListExpiredExports(ctx, time.Now(), purgeBatchSize)
+		if err != nil || len(exports) == 0 {
+			return removed, err
+		}
+
+		for _, export := range exports {
+			if export.ObjectKey != nil {
+				if err := s.blobs.Delete(ctx, *export.ObjectKey); err != nil {
+					return removed, fmt.Errorf("failed to delete export archive: %w", err)
+				}
+			}
+			if err := s.repo.DeleteExport(ctx, export.ID); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, ctx.Err()
+}
+
+// --- Deleted Accounts ---
+
+// PurgeDeletedAccounts permanently removes accounts whose deletion grace period has ended,
+// along with everything they own, and returns how many were purged.
+func (s *Service) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.config.DeletionGracePeriod)
+	purged := 0
+	for ctx.Err() == nil {
+		userIDs, err := s.repo.ListUsersDueForPurge(ctx, cutoff, purgeBatchSize)
+		if err != nil || len(userIDs) == 0 {
+			return purged, err
+		}
+
+		for _, userID := range userIDs {
+			keys, err := s.repo.PurgeUser(ctx, userID, cutoff)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue // restored by signing in since it was listed
+				}
+				return purged, fmt.Errorf("failed to purge user %s: %w", userID, err)
+			}
+
+			for _, key := range keys {
+				if err := s.blobs.Delete(ctx, key); err != nil {
+					s.logger.WithContext(ctx).Warn("failed to delete file of purged user", "error", err, "userID", userID, "key", key)
+				}
+			}
+			purged++
+			s.logger.WithContext(ctx).Info("Deleted account purged", "userID", userID, "files", len(keys))
+		}
+	}
+	return purged, ctx.Err()
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+)
+
+// AuthEventRepository defines the auth event repository interface
+type AuthEventRepository interface {
+	Create(ctx context.Context, event *AuthEvent) error
+	DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error)
+}
+
+// auditPruneBatchSize bounds the rows deleted per statement when pruning the audit log
+const auditPruneBatchSize = 5000
+
+// AuditLog records security-relevant authentication events in the database and the log
+type AuditLog struct {
+	repo   AuthEventRepository
+	logger logger.Logger
+}
+
+// NewAuditLog creates a new audit log
+func NewAuditLog(repo AuthEventRepository, logger logger.Logger) *AuditLog {
+	return &AuditLog{repo: repo, logger: logger.With("component", "auth_audit")}
+}
+
+// Record stores event. Failing to store it is logged rather than returned, so auditing never
+// blocks the request that triggered it.
+func (a *AuditLog) Record(ctx context.Context, event AuthEvent) {
+	fields := []interface{}{"event", event.Event, "ip", event.IP, "user_agent", event.UserAgent}
+	if event.UserID != nil {
+		fields = append(fields, "user_id", *event.UserID)
+	}
+	if event.Email != nil {
+		fields = append(fields, "email", *event.Email)
+	}
+	if event.Detail != "" {
+		fields = append(fields, "detail", event.Detail)
+	}
+
+	if err := a.repo.Create(ctx, &event); err != nil {
+		a.logger.Error("Failed to store auth event", append(fields, "error", err)...)
+	}
+	a.logger.Warn("Security event: "+event.Event, fields...)
+}
+
+// Prune deletes events older than retention and returns how many were deleted
+func (a *AuditLog) Prune(ctx context.Context, retention time.Duration) (int64, error) {
+	deleted, err := a.repo.DeleteBefore(ctx, time.Now().Add(-retention), auditPruneBatchSize)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to prune auth events: %w", err)
+	}
+	return deleted, nil
+}
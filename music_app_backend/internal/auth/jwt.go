@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -17,12 +18,31 @@ const (
 	TokenTypeRefresh TokenType = "refresh"
 )
 
+// tokenIssuer is the iss claim of every token this service issues
+const tokenIssuer = "music-app-backend"
+
+// tokenAudiences are the aud claims of each token type. Access tokens are meant for the API
+// and any service trusting the JWKS; refresh tokens are only ever accepted back here.
+var tokenAudiences = map[TokenType]string{
+	TokenTypeAccess:  "music-app-api",
+	TokenTypeRefresh: "music-app-backend/refresh",
+}
+
+// tokenHeaderTypes are the typ headers of each token type (RFC 9068 for access tokens), so a
+// refresh token can't be passed off as an access token even by a verifier ignoring claims
+var tokenHeaderTypes = map[TokenType]string{
+	TokenTypeAccess:  "at+jwt",
+	TokenTypeRefresh: "refresh+jwt",
+}
+
 // Claims represents JWT claims with custom fields
 type Claims struct {
-	UserID uuid.UUID `json:"uid"`
-	Email  string    `json:"email"`
-	Roles  []string  `json:"roles"`
-	Type   TokenType `json:"type"`
+	UserID    uuid.UUID `json:"uid"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	Type      TokenType `json:"type"`
+	Epoch     int64     `json:"sep,omitempty"` // session epoch at issue time, see SessionStore
+	SessionID uuid.UUID `json:"sid"`           // refresh token family the token belongs to
 	jwt.RegisteredClaims
 }
 
@@ -34,67 +54,146 @@ type TokenPair struct {
 	TokenType    string `json:"token_type"`
 }
 
+// JWTConfig configures how tokens are signed and verified
+type JWTConfig struct {
+	SigningMethod string // HS256/384/512, RS256/384/512, ES256/384/512 or EdDSA
+
+	// HMAC methods sign access and refresh tokens with separate shared secrets. Tokens signed
+	// with a previous secret are still accepted, so secrets can be rotated.
+	AccessSecret           string
+	RefreshSecret          string
+	PreviousAccessSecrets  []string
+	PreviousRefreshSecrets []string
+
+	// Asymmetric methods sign access tokens with a PEM private key, and publish its public key
+	// as a JWKS so other services can verify them. Tokens signed with a previous key, given as
+	// a PEM private or public key, are still accepted. Refresh tokens are only ever verified by
+	// this service, so they stay on HS256 with RefreshSecret and never use a published key.
+	PrivateKeyPEM   []byte
+	KeyID           string // defaults to the key's JWK thumbprint
+	PreviousKeysPEM [][]byte
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
 // JWTService provides JWT token operations
 type JWTService struct {
-	accessSecret     []byte
-	refreshSecret    []byte
-	accessTokenTTL   time.Duration
-	refreshTokenTTL  time.Duration
-	signingMethod    jwt.SigningMethod
+	accessKeys      *keySet
+	refreshKeys     *keySet
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
 }
 
 // NewJWTService creates a new JWT service
-func NewJWTService(accessSecret, refreshSecret string, accessTTL, refreshTTL time.Duration, method string) *JWTService {
-	var signingMethod jwt.SigningMethod
-	switch method {
-	case "HS256":
-		signingMethod = jwt.SigningMethodHS256
-	case "HS384":
-		signingMethod = jwt.SigningMethodHS384
-	case "HS512":
-		signingMethod = jwt.SigningMethodHS512
-	case "RS256":
-		signingMethod = jwt.SigningMethodRS256
-	case "RS384":
-		signingMethod = jwt.SigningMethodRS384
-	case "RS512":
-		signingMethod = jwt.SigningMethodRS512
-	default:
-		signingMethod = jwt.SigningMethodHS256
-	}
-
-	return &JWTService{
-		accessSecret:     []byte(accessSecret),
-		refreshSecret:    []byte(refreshSecret),
-		accessTokenTTL:   accessTTL,
-		refreshTokenTTL:  refreshTTL,
-		signingMethod:    signingMethod,
+func NewJWTService(config JWTConfig) (*JWTService, error) {
+	method, ok := signingMethods[config.SigningMethod]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT signing method %q", config.SigningMethod)
+	}
+
+	service := &JWTService{
+		accessTokenTTL:  config.AccessTokenTTL,
+		refreshTokenTTL: config.RefreshTokenTTL,
+	}
+
+	if _, isHMAC := method.(*jwt.SigningMethodHMAC); isHMAC {
+		var err error
+		if service.accessKeys, err = hmacKeySet(method, config.AccessSecret, config.PreviousAccessSecrets); err != nil {
+			return nil, fmt.Errorf("invalid access token secrets: %w", err)
+		}
+		if service.refreshKeys, err = hmacKeySet(method, config.RefreshSecret, config.PreviousRefreshSecrets); err != nil {
+			return nil, fmt.Errorf("invalid refresh token secrets: %w", err)
+		}
+		return service, nil
+	}
+
+	if len(config.PrivateKeyPEM) == 0 {
+		return nil, fmt.Errorf("a private key is required for %s", method.Alg())
 	}
+	current, err := parseKeyPEM(config.PrivateKeyPEM, method, config.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT private key: %w", err)
+	}
+	if current.sign == nil {
+		return nil, errors.New("invalid JWT private key: got a public key")
+	}
+
+	previous := make([]*signingKey, 0, len(config.PreviousKeysPEM))
+	for i, data := range config.PreviousKeysPEM {
+		key, err := parseKeyPEM(data, nil, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous JWT key %d: %w", i+1, err)
+		}
+		// Previous keys only verify. RSA keys keep the configured algorithm if they can.
+		key.sign = nil
+		if sameKeyFamily(method, key.method) {
+			key.method = method
+		}
+		previous = append(previous, key)
+	}
+
+	if service.accessKeys, err = newKeySet(current, previous...); err != nil {
+		return nil, err
+	}
+	if service.refreshKeys, err = hmacKeySet(jwt.SigningMethodHS256, config.RefreshSecret, config.PreviousRefreshSecrets); err != nil {
+		return nil, fmt.Errorf("invalid refresh token secrets: %w", err)
+	}
+	return service, nil
 }
 
-// GenerateTokenPair generates a new access and refresh token pair
-func (j *JWTService) GenerateTokenPair(userID uuid.UUID, email string, roles []string) (*TokenPair, error) {
+// hmacKeySet builds a key set that signs with secret and also accepts previous secrets
+func hmacKeySet(method jwt.SigningMethod, secret string, previous []string) (*keySet, error) {
+	if secret == "" {
+		return nil, errors.New("secret is required")
+	}
+	keys := make([]*signingKey, 0, len(previous))
+	for _, p := range previous {
+		if p != "" {
+			keys = append(keys, newHMACKey(method, p))
+		}
+	}
+	return newKeySet(newHMACKey(method, secret), keys...)
+}
+
+// JWKS returns the public keys access tokens can be verified with. It is empty when tokens
+// are signed with shared secrets.
+func (j *JWTService) JWKS() *JWKS {
+	set := &JWKS{Keys: []JWK{}}
+	for _, key := range j.accessKeys.all {
+		if jwk, ok := key.publicJWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// GenerateTokenPair generates a new access and refresh token pair for a session, stamped with
+// the user's session epoch
+func (j *JWTService) GenerateTokenPair(userID uuid.UUID, email string, roles []string, epoch int64, sessionID uuid.UUID) (*TokenPair, error) {
 	now := time.Now()
-	
+
 	// Generate access token
 	accessTokenID := uuid.New().String()
 	accessClaims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Roles:  roles,
-		Type:   TokenTypeAccess,
+		UserID:    userID,
+		Email:     email,
+		Roles:     roles,
+		Type:      TokenTypeAccess,
+		Epoch:     epoch,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        accessTokenID,
 			Subject:   userID.String(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessTokenTTL)),
 			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "music-app-backend",
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{tokenAudiences[TokenTypeAccess]},
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(j.signingMethod, accessClaims)
-	accessTokenString, err := accessToken.SignedString(j.accessSecret)
+	accessTokenString, err := j.accessKeys.sign(accessClaims, tokenHeaderTypes[TokenTypeAccess])
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign access token: %w", err)
 	}
@@ -102,22 +201,24 @@ func (j *JWTService) GenerateTokenPair(userID uuid.UUID, email string, roles []s
 	// Generate refresh token
 	refreshTokenID := uuid.New().String()
 	refreshClaims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Roles:  roles,
-		Type:   TokenTypeRefresh,
+		UserID:    userID,
+		Email:     email,
+		Roles:     roles,
+		Type:      TokenTypeRefresh,
+		Epoch:     epoch,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        refreshTokenID,
 			Subject:   userID.String(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.refreshTokenTTL)),
 			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "music-app-backend",
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{tokenAudiences[TokenTypeRefresh]},
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(j.signingMethod, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(j.refreshSecret)
+	refreshTokenString, err := j.refreshKeys.sign(refreshClaims, tokenHeaderTypes[TokenTypeRefresh])
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -132,48 +233,28 @@ func (j *JWTService) GenerateTokenPair(userID uuid.UUID, email string, roles []s
 
 // VerifyAccessToken validates and parses an access token
 func (j *JWTService) VerifyAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if token.Method != j.signingMethod {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.accessSecret, nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse access token: %w", err)
-	}
-
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid access token")
-	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok {
-		return nil, fmt.Errorf("invalid token claims")
-	}
-
-	if claims.Type != TokenTypeAccess {
-		return nil, fmt.Errorf("invalid token type")
-	}
-
-	return claims, nil
+	return verifyToken(tokenString, j.accessKeys, TokenTypeAccess)
 }
 
 // VerifyRefreshToken validates and parses a refresh token
 func (j *JWTService) VerifyRefreshToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if token.Method != j.signingMethod {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.refreshSecret, nil
-	})
+	return verifyToken(tokenString, j.refreshKeys, TokenTypeRefresh)
+}
+
+// verifyToken validates a token signed with keys and checks it was issued as tokenType
+func verifyToken(tokenString string, keys *keySet, tokenType TokenType) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyFunc,
+		jwt.WithValidMethods(keys.methods),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithAudience(tokenAudiences[tokenType]),
+	)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse refresh token: %w", err)
+		return nil, fmt.Errorf("failed to parse %s token: %w", tokenType, err)
 	}
 
 	if !token.Valid {
-		return nil, fmt.Errorf("invalid refresh token")
+		return nil, fmt.Errorf("invalid %s token", tokenType)
 	}
 
 	claims, ok := token.Claims.(*Claims)
@@ -181,7 +262,7 @@ func (j *JWTService) VerifyRefreshToken(tokenString string) (*Claims, error) {
 		return nil, fmt.Errorf("invalid token claims")
 	}
 
-	if claims.Type != TokenTypeRefresh {
+	if typ, _ := token.Header["typ"].(string); typ != tokenHeaderTypes[tokenType] || claims.Type != tokenType {
 		return nil, fmt.Errorf("invalid token type")
 	}
 
@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethods are the JWT algorithms tokens can be signed with
+var signingMethods = map[string]jwt.SigningMethod{
+	"HS256": jwt.SigningMethodHS256,
+	"HS384": jwt.SigningMethodHS384,
+	"HS512": jwt.SigningMethodHS512,
+	"RS256": jwt.SigningMethodRS256,
+	"RS384": jwt.SigningMethodRS384,
+	"RS512": jwt.SigningMethodRS512,
+	"ES256": jwt.SigningMethodES256,
+	"ES384": jwt.SigningMethodES384,
+	"ES512": jwt.SigningMethodES512,
+	"EdDSA": jwt.SigningMethodEdDSA,
+}
+
+// signingKey is a key tokens are signed or verified with, identified by the kid header
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{} // HMAC secret or private key; nil for keys that only verify
+	verify interface{} // HMAC secret or public key
+}
+
+// keySet holds the key new tokens are signed with along with earlier keys that tokens are
+// still accepted from, so keys can be rotated without invalidating issued tokens.
+type keySet struct {
+	current *signingKey
+	all     []*signingKey // the current key first, then previous keys
+	byID    map[string]*signingKey
+	methods []string // algorithms of every key in the set
+}
+
+func newKeySet(current *signingKey, previous ...*signingKey) (*keySet, error) {
+	set := &keySet{current: current, all: append([]*signingKey{current}, previous...), byID: make(map[string]*signingKey)}
+	for _, key := range set.all {
+		if _, exists := set.byID[key.id]; exists {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", key.id)
+		}
+		set.byID[key.id] = key
+
+		alg := key.method.Alg()
+		known := false
+		for _, method := range set.methods {
+			known = known || method == alg
+		}
+		if !known {
+			set.methods = append(set.methods, alg)
+		}
+	}
+	return set, nil
+}
+
+// sign signs claims with the current key, stamping its key ID and the token's typ header
+func (s *keySet) sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(s.current.method, claims)
+	token.Header["kid"] = s.current.id
+	token.Header["typ"] = typ
+	return token.SignedString(s.current.sign)
+}
+
+// keyFunc finds the key a token was signed with. Tokens without a key ID predate key IDs and
+// are checked against the current key.
+func (s *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := s.current
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = s.byID[kid]; !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verify, nil
+}
+
+// newHMACKey creates a key from a shared secret. Its ID is derived from the secret so the same
+// secret gets the same ID on every replica and across restarts.
+func newHMACKey(method jwt.SigningMethod, secret string) *signingKey {
+	sum := sha256.Sum256([]byte("jwt-kid:" + secret))
+	return &signingKey{
+		id:     "hs-" + hex.EncodeToString(sum[:8]),
+		method: method,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// parseKeyPEM parses a PEM encoded private or public key for method, or for the algorithm
+// matching the key type when method is nil. The key ID defaults to the key's JWK thumbprint.
+func parseKeyPEM(data []byte, method jwt.SigningMethod, id string) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key: %w", err)
+	}
+
+	key := &signingKey{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.sign = parsed
+		key.verify = signer.Public()
+	} else {
+		key.verify = parsed
+	}
+
+	inferred, err := methodForKey(key.verify)
+	if err != nil {
+		return nil, err
+	}
+	if method == nil {
+		method = inferred
+	} else if !sameKeyFamily(method, inferred) {
+		return nil, fmt.Errorf("key type does not match signing method %s", method.Alg())
+	}
+	key.method = method
+
+	if id == "" {
+		if id, err = thumbprint(key.verify); err != nil {
+			return nil, err
+		}
+	}
+	key.id = id
+	return key, nil
+}
+
+// methodForKey returns the default algorithm for a public key
+func methodForKey(public interface{}) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
+
+// sameKeyFamily reports whether a key suited to inferred can sign with method. RSA keys work
+// with any RSA algorithm; EC algorithms are tied to a curve.
+func sameKeyFamily(method, inferred jwt.SigningMethod) bool {
+	if _, ok := method.(*jwt.SigningMethodRSA); ok {
+		_, isRSA := inferred.(*jwt.SigningMethodRSA)
+		return isRSA
+	}
+	return method.Alg() == inferred.Alg()
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// publicJWK returns the public half of an asymmetric key as a JWK. HMAC keys are secret and
+// have none.
+func (k *signingKey) publicJWK() (JWK, bool) {
+	jwk, err := publicKeyParams(k.verify)
+	if err != nil {
+		return JWK{}, false
+	}
+	jwk.KeyID = k.id
+	jwk.Use = "sig"
+	jwk.Alg = k.method.Alg()
+	return jwk, true
+}
+
+// publicKeyParams returns the key type and parameters of a public key in JWK form
+func publicKeyParams(public interface{}) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{KeyType: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			KeyType: "EC",
+			Curve:   k.Curve.Params().Name,
+			X:       b64(k.X.FillBytes(make([]byte, size))),
+			Y:       b64(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{KeyType: "OKP", Curve: "Ed25519", X: b64(k)}, nil
+	}
+	return JWK{}, fmt.Errorf("unsupported key type %T", public)
+}
+
+// publicKey parses the public key a JWK describes
+func (j JWK) publicKey() (interface{}, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+	switch j.KeyType {
+	case "RSA":
+		n, err := b64(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve %s", j.Curve)
+		}
+		x, err := b64(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return key, nil
+	case "OKP":
+		x, err := b64(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if j.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", j.KeyType)
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint of a public key
+func thumbprint(public interface{}) (string, error) {
+	jwk, err := publicKeyParams(public)
+	if err != nil {
+		return "", err
+	}
+
+	// The thumbprint hashes the required members only, in lexicographic order
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
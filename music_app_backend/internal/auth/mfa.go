@@ -0,0 +1,527 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// MFARepository defines the MFA repository interface
+type MFARepository interface {
+	GetFactor(ctx context.Context, userID uuid.UUID) (*TOTPFactor, error)
+	SaveFactor(ctx context.Context, factor *TOTPFactor) error
+	ConfirmFactor(ctx context.Context, userID uuid.UUID, step int64, codes []RecoveryCode) error
+	AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DeleteFactor(ctx context.Context, userID uuid.UUID) error
+	ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []RecoveryCode) error
+}
+
+var (
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolling     = errors.New("two-factor enrollment has not been started")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrMFAChallengeInvalid = errors.New("MFA challenge is invalid or expired")
+)
+
+const (
+	mfaChallengeKeyPrefix = "auth:mfa_challenge:"
+	// maxMFAChallengeAttempts wrong codes end a challenge, and the user has to sign in again
+	maxMFAChallengeAttempts = 5
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // characters, shown to users in two groups of five
+)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// MFAConfig configures two-factor authentication
+type MFAConfig struct {
+	Issuer        string // the account issuer shown in authenticator apps
+	EncryptionKey string // key material for encrypting TOTP secrets at rest
+	ChallengeTTL  time.Duration
+}
+
+// TOTPEnrollment is a pending TOTP factor for the user to add to their authenticator app
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string // otpauth:// URI, usually rendered as a QR code
+}
+
+// MFAStatus describes a user's two-factor authentication setup
+type MFAStatus struct {
+	Enabled                bool
+	Pending                bool // enrollment started but not yet confirmed
+	EnabledAt              *time.Time
+	RecoveryCodesRemaining int
+}
+
+// MFAService manages TOTP two-factor authentication: enrollment, recovery codes and the
+// challenges that complete a sign-in for users who have it enabled. TOTP secrets are stored
+// encrypted with AES-GCM and recovery codes are hashed with the password hasher.
+type MFAService struct {
+	repo   MFARepository
+	hasher *PasswordHasher
+	redis  *redis.Client
+	aead   cipher.AEAD
+	config MFAConfig
+	audit  *AuditLog
+	logger logger.Logger
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService(
+	repo MFARepository,
+	hasher *PasswordHasher,
+	client *redis.Client,
+	config MFAConfig,
+	audit *AuditLog,
+	logger logger.Logger,
+) (*MFAService, error) {
+	if config.EncryptionKey == "" {
+		return nil, errors.New("MFA encryption key is required")
+	}
+
+	// Derive a fixed-size AES-256 key from the configured key material
+	key := sha256.Sum256([]byte(config.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MFA cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MFA cipher: %w", err)
+	}
+
+	return &MFAService{
+		repo:   repo,
+		hasher: hasher,
+		redis:  client,
+		aead:   aead,
+		config: config,
+		audit:  audit,
+		logger: logger.With("component", "mfa"),
+	}, nil
+}
+
+// ChallengeTTL returns how long a sign-in challenge stays valid
+func (s *MFAService) ChallengeTTL() time.Duration {
+	return s.config.ChallengeTTL
+}
+
+// Status returns the user's two-factor authentication setup
+func (s *MFAService) Status(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	factor, err := s.factor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return &MFAStatus{}, nil
+		}
+		return nil, err
+	}
+	if !factor.IsConfirmed() {
+		return &MFAStatus{Pending: true}, nil
+	}
+
+	codes, err := s.repo.ListUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recovery codes: %w", err)
+	}
+	return &MFAStatus{Enabled: true, EnabledAt: factor.ConfirmedAt, RecoveryCodesRemaining: len(codes)}, nil
+}
+
+// IsEnabled reports whether sign-ins by the user require a second factor
+func (s *MFAService) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	factor, err := s.factor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return factor.IsConfirmed(), nil
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. It is not enforced until
+// ConfirmEnrollment proves the user's app produces matching codes. Starting again replaces
+// a pending secret.
+func (s *MFAService) BeginEnrollment(ctx context.Context, userID uuid.UUID, accountName string) (*TOTPEnrollment, error) {
+	factor, err := s.factor(ctx, userID)
+	if err != nil && !errors.Is(err, ErrMFANotEnabled) {
+		return nil, err
+	}
+	if factor == nil {
+		factor = &TOTPFactor{UserID: userID}
+	} else if factor.IsConfirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	factor.SecretEncrypted = sealed
+	factor.LastUsedStep = 0
+	if err := s.repo.SaveFactor(ctx, factor); err != nil {
+		return nil, fmt.Errorf("failed to store TOTP factor: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(secret, s.config.Issuer, accountName),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once code matches the pending secret,
+// and returns the user's recovery codes. They are only ever shown here. client identifies the
+// caller for the audit log.
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string, client Attempt) ([]string, error) {
+	factor, err := s.factor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return nil, ErrMFANotEnrolling
+		}
+		return nil, err
+	}
+	if factor.IsConfirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.open(factor.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(secret, normalizeTOTPCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, records, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ConfirmFactor(ctx, userID, step, records); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotEnrolling
+		}
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	s.audit.Record(ctx, mfaEvent(EventMFAEnabled, userID, client, ""))
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication after checking a TOTP or recovery code. A
+// pending enrollment is discarded without a code.
+func (s *MFAService) Disable(ctx context.Context, userID uuid.UUID, code string, client Attempt) error {
+	factor, err := s.factor(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if factor.IsConfirmed() {
+		ok, err := s.verify(ctx, factor, code, client)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidMFACode
+		}
+	}
+
+	if err := s.repo.DeleteFactor(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	if factor.IsConfirmed() {
+		s.audit.Record(ctx, mfaEvent(EventMFADisabled, userID, client, ""))
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a TOTP or recovery
+// code, and returns the new ones
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string, client Attempt) ([]string, error) {
+	factor, err := s.factor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !factor.IsConfirmed() {
+		return nil, ErrMFANotEnabled
+	}
+
+	ok, err := s.verify(ctx, factor, code, client)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, records, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// MFAChallenge is a sign-in waiting for its second step
+type MFAChallenge struct {
+	UserID uuid.UUID
+	Email  string // the account the sign-in throttle counts failed codes against
+}
+
+// reserveMFAAttemptScript counts an attempt at a challenge before its code is checked, so
+// parallel guesses can't all get in before the count catches up. It returns the challenge's
+// user ID, email and attempt count, or nil if the challenge doesn't exist.
+var reserveMFAAttemptScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+local fields = redis.call('HMGET', KEYS[1], 'user_id', 'email')
+return {fields[1], fields[2] or '', attempts}
+`)
+
+// CreateChallenge starts the second step of a sign-in for a user who passed the first. The
+// returned token is exchanged for tokens with CompleteChallenge.
+func (s *MFAService) CreateChallenge(ctx context.Context, userID uuid.UUID, email string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate MFA challenge: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	key := mfaChallengeKey(token)
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", userID.String(), "email", email, "attempts", 0)
+		pipe.PExpire(ctx, key, s.config.ChallengeTTL)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store MFA challenge: %w", err)
+	}
+	return token, nil
+}
+
+// GetChallenge returns the sign-in behind a challenge token without using up an attempt
+func (s *MFAService) GetChallenge(ctx context.Context, token string) (*MFAChallenge, error) {
+	fields, err := s.redis.HMGet(ctx, mfaChallengeKey(token), "user_id", "email").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MFA challenge: %w", err)
+	}
+	userIDStr, _ := fields[0].(string)
+	email, _ := fields[1].(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, ErrMFAChallengeInvalid
+	}
+	return &MFAChallenge{UserID: userID, Email: email}, nil
+}
+
+// CompleteChallenge checks code against the user behind a challenge token and returns the
+// user's ID. A challenge can only be completed once, and too many wrong codes end it. Each
+// attempt is counted before its code is checked.
+func (s *MFAService) CompleteChallenge(ctx context.Context, token, code string, client Attempt) (uuid.UUID, error) {
+	key := mfaChallengeKey(token)
+	reserved, err := reserveMFAAttemptScript.Run(ctx, s.redis, []string{key}).Slice()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return uuid.Nil, ErrMFAChallengeInvalid
+		}
+		return uuid.Nil, fmt.Errorf("failed to count MFA attempt: %w", err)
+	}
+	userIDStr, _ := reserved[0].(string)
+	attempts, _ := reserved[2].(int64)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, ErrMFAChallengeInvalid
+	}
+	if attempts > maxMFAChallengeAttempts {
+		s.redis.Del(ctx, key)
+		return uuid.Nil, ErrMFAChallengeInvalid
+	}
+
+	factor, err := s.factor(ctx, userID)
+	if err != nil && !errors.Is(err, ErrMFANotEnabled) {
+		return uuid.Nil, err
+	}
+	if factor == nil || !factor.IsConfirmed() {
+		// Two-factor authentication was turned off since the challenge was issued
+		s.redis.Del(ctx, key)
+		return uuid.Nil, ErrMFAChallengeInvalid
+	}
+
+	ok, err := s.verify(ctx, factor, code, client)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		if attempts >= maxMFAChallengeAttempts {
+			s.redis.Del(ctx, key)
+			return uuid.Nil, ErrMFAChallengeInvalid
+		}
+		return uuid.Nil, ErrInvalidMFACode
+	}
+
+	// Deleting the challenge claims it, so a code can only complete it once
+	deleted, err := s.redis.Del(ctx, key).Result()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to complete MFA challenge: %w", err)
+	}
+	if deleted == 0 {
+		return uuid.Nil, ErrMFAChallengeInvalid
+	}
+	return userID, nil
+}
+
+// factor loads the user's TOTP factor, returning ErrMFANotEnabled if there is none
+func (s *MFAService) factor(ctx context.Context, userID uuid.UUID) (*TOTPFactor, error) {
+	factor, err := s.repo.GetFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, fmt.Errorf("failed to get TOTP factor: %w", err)
+	}
+	return factor, nil
+}
+
+// verify checks code against a confirmed factor. Six digits are a TOTP code, which is accepted
+// once; anything else is tried as a recovery code, which is used up. Failures are audited.
+func (s *MFAService) verify(ctx context.Context, factor *TOTPFactor, code string, client Attempt) (bool, error) {
+	if totp := normalizeTOTPCode(code); len(totp) == totpDigits {
+		secret, err := s.open(factor.SecretEncrypted)
+		if err != nil {
+			return false, err
+		}
+		if step, ok := matchTOTP(secret, totp, time.Now()); ok {
+			advanced, err := s.repo.AdvanceStep(ctx, factor.UserID, step)
+			if err != nil {
+				return false, fmt.Errorf("failed to record TOTP code use: %w", err)
+			}
+			if advanced {
+				return true, nil
+			}
+			s.audit.Record(ctx, mfaEvent(EventMFAFailed, factor.UserID, client, "totp code reused"))
+			return false, nil
+		}
+		s.audit.Record(ctx, mfaEvent(EventMFAFailed, factor.UserID, client, "invalid totp code"))
+		return false, nil
+	}
+
+	if recovery := normalizeRecoveryCode(code); len(recovery) == recoveryCodeLength {
+		codes, err := s.repo.ListUnusedRecoveryCodes(ctx, factor.UserID)
+		if err != nil {
+			return false, fmt.Errorf("failed to list recovery codes: %w", err)
+		}
+		for _, stored := range codes {
+			if match, err := s.hasher.Matches(recovery, stored.CodeHash); err != nil || !match {
+				continue
+			}
+			used, err := s.repo.UseRecoveryCode(ctx, stored.ID)
+			if err != nil {
+				return false, fmt.Errorf("failed to use recovery code: %w", err)
+			}
+			if used {
+				s.audit.Record(ctx, mfaEvent(EventRecoveryCodeUsed, factor.UserID, client, fmt.Sprintf("%d left", len(codes)-1)))
+				return true, nil
+			}
+		}
+	}
+
+	s.audit.Record(ctx, mfaEvent(EventMFAFailed, factor.UserID, client, "invalid recovery code"))
+	return false, nil
+}
+
+// newRecoveryCodes generates a fresh set of recovery codes, returning them for display and
+// hashed for storage
+func (s *MFAService) newRecoveryCodes(userID uuid.UUID) ([]string, []RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeLength*5/8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(raw)
+
+		hash, err := s.hasher.Hash(code)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		records = append(records, RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return codes, records, nil
+}
+
+// seal encrypts a TOTP secret for storage
+func (s *MFAService) seal(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a stored TOTP secret
+func (s *MFAService) open(sealed string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < s.aead.NonceSize() {
+		return "", errors.New("failed to decrypt TOTP secret: malformed ciphertext")
+	}
+	nonceSize := s.aead.NonceSize()
+	secret, err := s.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+func mfaEvent(name string, userID uuid.UUID, client Attempt, detail string) AuthEvent {
+	event := AuthEvent{Event: name, UserID: &userID, IP: client.IP, UserAgent: truncate(client.UserAgent, 500), Detail: detail}
+	if client.Email != "" {
+		email := truncate(client.Email, 255)
+		event.Email = &email
+	}
+	return event
+}
+
+// mfaChallengeKey identifies a challenge by a hash of its token, so tokens are not stored
+func mfaChallengeKey(token string) string {
+	return mfaChallengeKeyPrefix + hashOneTimeToken(token)
+}
+
+// normalizeTOTPCode drops the spaces some apps show in the middle of a code
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// normalizeRecoveryCode accepts recovery codes with or without their separator, in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
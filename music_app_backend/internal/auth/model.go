@@ -12,6 +12,7 @@ type RefreshToken struct {
 	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
 	TokenID    string     `json:"token_id" gorm:"uniqueIndex;not null;size:255"` // JWT ID (jti claim)
+	FamilyID   uuid.UUID  `json:"family_id" gorm:"type:uuid;not null;index"`      // shared by every token rotated from the same sign-in
 	IssuedAt   time.Time  `json:"issued_at"`
 	ExpiresAt  time.Time  `json:"expires_at"`
 	Revoked    bool       `json:"revoked" gorm:"default:false"`
@@ -27,6 +28,9 @@ func (rt *RefreshToken) BeforeCreate(tx *gorm.DB) error {
 	if rt.ID == uuid.Nil {
 		rt.ID = uuid.New()
 	}
+	if rt.FamilyID == uuid.Nil {
+		rt.FamilyID = rt.ID
+	}
 	if rt.IssuedAt.IsZero() {
 		rt.IssuedAt = time.Now()
 	}
@@ -60,3 +64,106 @@ func (rt *RefreshToken) RevokeAndReplace(replacementTokenID string) {
 	rt.ReplacedBy = &replacementTokenID
 	rt.UpdatedAt = time.Now()
 }
+
+// Session is a signed-in device, represented by the active refresh token of a token family
+type Session struct {
+	ID         uuid.UUID // the token family ID, stable across refreshes
+	UserAgent  string
+	IP         string
+	Device     Device
+	LastUsedAt time.Time // when the session last refreshed its tokens
+	ExpiresAt  time.Time
+	Current    bool
+}
+
+// Purposes of one-time tokens. A token can only be consumed for the purpose it was issued for.
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+	PurposeAccountUnlock     = "account_unlock"
+)
+
+// OneTimeToken is a single-use token sent to a user, such as a password reset link. Only a
+// hash of the token is stored.
+type OneTimeToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null;size:32"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;size:64"` // hex SHA-256 of the token
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for the OneTimeToken model
+func (OneTimeToken) TableName() string {
+	return "one_time_tokens"
+}
+
+// Auth events recorded in the audit log
+const (
+	EventLoginFailed       = "login_failed"
+	EventLoginThrottled    = "login_throttled"
+	EventAccountLocked     = "account_locked"
+	EventAccountUnlocked   = "account_unlocked"
+	EventRegisterThrottled = "register_throttled"
+	EventRefreshFailed     = "refresh_failed"
+	EventRefreshThrottled  = "refresh_throttled"
+	EventMFAEnabled        = "mfa_enabled"
+	EventMFADisabled       = "mfa_disabled"
+	EventMFAFailed         = "mfa_failed"
+	EventRecoveryCodeUsed  = "mfa_recovery_code_used"
+)
+
+// AuthEvent is an audit log entry for a security-relevant authentication event
+type AuthEvent struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Event     string     `json:"event" gorm:"not null;size:64"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	Email     *string    `json:"email,omitempty" gorm:"size:255;index"`
+	IP        string     `json:"ip" gorm:"size:45"`
+	UserAgent string     `json:"user_agent" gorm:"size:500"`
+	Detail    string     `json:"detail,omitempty" gorm:"size:255"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+// TableName returns the table name for the AuthEvent model
+func (AuthEvent) TableName() string {
+	return "auth_events"
+}
+
+// TOTPFactor is a user's TOTP authenticator. It is pending until the user confirms it with a
+// code from their app, and only confirmed factors are required at sign-in.
+type TOTPFactor struct {
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;primary_key"`
+	SecretEncrypted string     `json:"-" gorm:"not null;size:255"` // AES-GCM sealed secret
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep    int64      `json:"-" gorm:"not null;default:0"` // time step of the last accepted code, so codes cannot be replayed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for the TOTPFactor model
+func (TOTPFactor) TableName() string {
+	return "totp_factors"
+}
+
+// IsConfirmed reports whether the user has finished enrolling the factor
+func (f *TOTPFactor) IsConfirmed() bool {
+	return f.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the user has lost
+// their authenticator. Codes are hashed like passwords.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;size:255"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for the RecoveryCode model
+func (RecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
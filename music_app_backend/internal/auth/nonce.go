@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const oidcNonceKeyPrefix = "auth:oidc_nonce:"
+
+// ErrNonceInvalid is returned for nonces this service did not issue, that expired or that were
+// already used
+var ErrNonceInvalid = errors.New("nonce is invalid, expired or already used")
+
+// NonceStore issues the nonces clients request provider ID tokens with. Each nonce is accepted
+// once, so a captured ID token cannot be replayed to sign in again.
+type NonceStore struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewNonceStore creates a nonce store. ttl is how long a client has to complete the provider
+// sign-in after requesting a nonce.
+func NewNonceStore(client *redis.Client, ttl time.Duration) *NonceStore {
+	return &NonceStore{redis: client, ttl: ttl}
+}
+
+// TTL returns how long issued nonces stay valid
+func (s *NonceStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue creates a nonce that can be consumed once within the TTL
+func (s *NonceStore) Issue(ctx context.Context) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := s.redis.Set(ctx, oidcNonceKey(nonce), 1, s.ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to store nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// Consume redeems a nonce, failing with ErrNonceInvalid if it was not issued or already used
+func (s *NonceStore) Consume(ctx context.Context, nonce string) error {
+	removed, err := s.redis.Del(ctx, oidcNonceKey(nonce)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to consume nonce: %w", err)
+	}
+	if removed == 0 {
+		return ErrNonceInvalid
+	}
+	return nil
+}
+
+// oidcNonceKey identifies a nonce by its hash, as with other single-use tokens
+func oidcNonceKey(nonce string) string {
+	return oidcNonceKeyPrefix + hashOneTimeToken(nonce)
+}
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcKeysTTL            = time.Hour        // how long a provider's signing keys are cached
+	oidcMinRefreshInterval = time.Minute      // how often keys are refetched, so unknown key IDs cannot flood the provider
+	oidcLeeway             = 30 * time.Second // allowed clock skew with the provider
+)
+
+var (
+	// ErrUnknownIdentityProvider is returned for login providers that are not configured
+	ErrUnknownIdentityProvider = errors.New("unknown login provider")
+	// ErrInvalidIDToken is returned for ID tokens that fail verification
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// oidcMethods are the algorithms accepted on ID tokens. Providers sign with asymmetric keys
+// only, so HMAC is never accepted.
+var oidcMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// wellKnownProviders fills in the issuer of providers configured by name only. Both only
+// mark addresses verified once they have confirmed them, so their emails are trusted. Apple
+// tokens are requested by apps directly, so they must carry a nonce.
+var wellKnownProviders = map[string]OIDCConfig{
+	"google": {Issuer: "https://accounts.google.com", ExtraIssuers: []string{"accounts.google.com"}, TrustEmail: boolPtr(true)},
+	"apple":  {Issuer: "https://appleid.apple.com", RequireNonce: boolPtr(true), TrustEmail: boolPtr(true)},
+}
+
+// IdentityProvider verifies ID tokens issued by an external login provider
+type IdentityProvider interface {
+	Name() string
+	VerifyIDToken(ctx context.Context, idToken, nonce string) (*ProviderIdentity, error)
+}
+
+// ProviderIdentity is the account an ID token was issued for
+type ProviderIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool // only set by providers trusted to verify emails
+	Name          string
+	Picture       string
+}
+
+// OIDCConfig configures an OpenID Connect login provider
+type OIDCConfig struct {
+	Name         string
+	Issuer       string
+	ExtraIssuers []string // other iss values the provider puts on its tokens
+	ClientIDs    []string // accepted audiences
+	DiscoveryURL string   // defaults to the issuer's /.well-known/openid-configuration
+	JWKSURL      string   // skips discovery when set
+	RequireNonce *bool // defaults to true for Apple
+	// TrustEmail accepts the provider's email_verified claim, which lets its users sign in to
+	// existing accounts with the same address. Any IdP can assert it, so it defaults to false
+	// for all but Google and Apple.
+	TrustEmail *bool
+	HTTPClient *http.Client
+}
+
+// OIDCProvider verifies ID tokens against the signing keys an OpenID Connect provider
+// publishes, found through its discovery document.
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	jwksURL     string
+	keys        map[string]interface{} // public keys by key ID
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewOIDCProvider creates a login provider. Google and Apple only need their client IDs.
+func NewOIDCProvider(config OIDCConfig) (*OIDCProvider, error) {
+	if config.Name == "" {
+		return nil, errors.New("OIDC provider name is required")
+	}
+	if known, ok := wellKnownProviders[config.Name]; ok {
+		if config.Issuer == "" {
+			config.Issuer = known.Issuer
+			config.ExtraIssuers = append(config.ExtraIssuers, known.ExtraIssuers...)
+		}
+		if config.RequireNonce == nil {
+			config.RequireNonce = known.RequireNonce
+		}
+		if config.TrustEmail == nil {
+			config.TrustEmail = known.TrustEmail
+		}
+	}
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("OIDC provider %s: issuer is required", config.Name)
+	}
+	if len(config.ClientIDs) == 0 {
+		return nil, fmt.Errorf("OIDC provider %s: at least one client ID is required", config.Name)
+	}
+	if config.DiscoveryURL == "" {
+		config.DiscoveryURL = strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &OIDCProvider{config: config, client: client, jwksURL: config.JWKSURL}, nil
+}
+
+// Name returns the provider name identities are recorded under
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, expiry and nonce. The nonce
+// must match when given or when the provider requires one; tokens may carry it as is or as
+// its hex SHA-256 hash, as Sign in with Apple does. Whether the nonce was issued by us is up
+// to the caller, see AuthService.VerifyIDToken.
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, idToken, nonce string) (*ProviderIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return p.key(ctx, token)
+	}, jwt.WithValidMethods(oidcMethods), jwt.WithExpirationRequired(), jwt.WithIssuedAt(), jwt.WithLeeway(oidcLeeway))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	issuer, _ := claims.GetIssuer()
+	if !p.trustedIssuer(issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, issuer)
+	}
+	audience, _ := claims.GetAudience()
+	if !p.intendedForUs(audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if nonce == "" && p.config.RequireNonce != nil && *p.config.RequireNonce {
+		return nil, fmt.Errorf("%w: nonce is required", ErrInvalidIDToken)
+	}
+	if nonce != "" && !nonceMatches(tokenNonce, nonce) {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	identity := &ProviderIdentity{Provider: p.config.Name, Subject: subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.Picture, _ = claims["picture"].(string)
+	// Apple sends email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	identity.EmailVerified = identity.EmailVerified && p.trustsEmail()
+	return identity, nil
+}
+
+func (p *OIDCProvider) trustsEmail() bool {
+	return p.config.TrustEmail != nil && *p.config.TrustEmail
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func (p *OIDCProvider) trustedIssuer(issuer string) bool {
+	if issuer == p.config.Issuer {
+		return true
+	}
+	for _, extra := range p.config.ExtraIssuers {
+		if issuer == extra {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *OIDCProvider) intendedForUs(audience jwt.ClaimStrings) bool {
+	for _, aud := range audience {
+		for _, clientID := range p.config.ClientIDs {
+			if aud == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func nonceMatches(tokenNonce, nonce string) bool {
+	if tokenNonce == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(nonce))
+	return tokenNonce == nonce || tokenNonce == hex.EncodeToString(sum[:])
+}
+
+// key finds the public key a token was signed with, refetching the provider's keys when they
+// are stale or the token names a key not seen yet, as happens after the provider rotates.
+func (p *OIDCProvider) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, found := p.lookup(kid)
+	stale := time.Since(p.fetchedAt) > oidcKeysTTL
+	if (stale || !found) && time.Since(p.attemptedAt) > oidcMinRefreshInterval {
+		if err := p.fetchKeys(ctx); err != nil {
+			if !found {
+				return nil, err
+			}
+			// Keep using the cached key while the provider is unreachable
+		} else {
+			key, found = p.lookup(kid)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	inferred, err := methodForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !sameKeyFamily(token.Method, inferred) {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key, nil
+}
+
+// lookup returns the cached key with an ID, or the only key when the token names none
+func (p *OIDCProvider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// fetchKeys loads the provider's signing keys, discovering where they are published first
+func (p *OIDCProvider) fetchKeys(ctx context.Context) error {
+	p.attemptedAt = time.Now()
+
+	if p.jwksURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := p.getJSON(ctx, p.config.DiscoveryURL, &discovery); err != nil {
+			return fmt.Errorf("OIDC discovery failed: %w", err)
+		}
+		if discovery.Issuer != p.config.Issuer {
+			return fmt.Errorf("OIDC discovery document is for issuer %q, not %q", discovery.Issuer, p.config.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return errors.New("OIDC discovery document has no jwks_uri")
+		}
+		p.jwksURL = discovery.JWKSURI
+	}
+
+	var set JWKS
+	if err := p.getJSON(ctx, p.jwksURL, &set); err != nil {
+		return fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue // skip key types we cannot verify with
+		}
+		keys[jwk.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("OIDC provider published no usable signing keys")
+	}
+
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testClientID = "music-app-test"
+
+// testIdP is an OpenID Connect provider serving a discovery document and a JWKS
+type testIdP struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	jwksFetches int
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	idp := &testIdP{keys: make(map[string]*rsa.PrivateKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   idp.server.URL,
+			"jwks_uri": idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksFetches++
+
+		set := JWKS{Keys: []JWK{}}
+		for kid, key := range idp.keys {
+			jwk, err := publicKeyParams(&key.PublicKey)
+			if err != nil {
+				t.Errorf("failed to encode key: %v", err)
+				return
+			}
+			jwk.KeyID, jwk.Use, jwk.Alg = kid, "sig", "RS256"
+			set.Keys = append(set.Keys, jwk)
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// addKey publishes a new signing key
+func (idp *testIdP) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys[kid] = key
+	return key
+}
+
+func (idp *testIdP) fetches() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksFetches
+}
+
+// claims returns valid ID token claims, which tests then break
+func (idp *testIdP) claims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"email":          "listener@example.com",
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func newTestProvider(t *testing.T, idp *testIdP) *OIDCProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(OIDCConfig{
+		Name:       "test",
+		Issuer:     idp.server.URL,
+		ClientIDs:  []string{testClientID},
+		HTTPClient: idp.server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	return provider
+}
+
+func TestOIDCProviderVerifyIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addKey(t, "key-1")
+
+	tests := []struct {
+		name    string
+		claims  func(jwt.MapClaims)
+		nonce   string
+		wantErr bool
+	}{
+		{name: "valid token", claims: func(jwt.MapClaims) {}},
+		{name: "wrong issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, wantErr: true},
+		{name: "wrong audience", claims: func(c jwt.MapClaims) { c["aud"] = "another-client" }, wantErr: true},
+		{name: "expired", claims: func(c jwt.MapClaims) {
+			c["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		}, wantErr: true},
+		{name: "matching nonce", claims: func(c jwt.MapClaims) { c["nonce"] = "expected" }, nonce: "expected"},
+		{name: "nonce mismatch", claims: func(c jwt.MapClaims) { c["nonce"] = "other" }, nonce: "expected", wantErr: true},
+		{name: "missing nonce", claims: func(jwt.MapClaims) {}, nonce: "expected", wantErr: true},
+	}
+
+	provider := newTestProvider(t, idp)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.claims()
+			tt.claims(claims)
+
+			identity, err := provider.VerifyIDToken(context.Background(), signRS256(t, key, "key-1", claims), tt.nonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Subject != "user-123" || identity.Email != "listener@example.com" {
+				t.Errorf("unexpected identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderDoesNotTrustEmailByDefault(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addKey(t, "key-1")
+	provider := newTestProvider(t, idp)
+
+	identity, err := provider.VerifyIDToken(context.Background(), signRS256(t, key, "key-1", idp.claims()), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.EmailVerified {
+		t.Error("email_verified was accepted from a provider not trusted to verify emails")
+	}
+}
+
+func TestOIDCProviderRefetchesKeysForUnknownKeyID(t *testing.T) {
+	idp := newTestIdP(t)
+	oldKey := idp.addKey(t, "key-1")
+	provider := newTestProvider(t, idp)
+
+	if _, err := provider.VerifyIDToken(context.Background(), signRS256(t, oldKey, "key-1", idp.claims()), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := idp.fetches(); got != 1 {
+		t.Fatalf("expected 1 key fetch, got %d", got)
+	}
+
+	// The provider rotates to a key we have not seen. Fetches are rate limited, so move the
+	// last attempt back past the minimum interval.
+	newKey := idp.addKey(t, "key-2")
+	provider.mu.Lock()
+	provider.attemptedAt = time.Now().Add(-2 * oidcMinRefreshInterval)
+	provider.mu.Unlock()
+
+	if _, err := provider.VerifyIDToken(context.Background(), signRS256(t, newKey, "key-2", idp.claims()), ""); err != nil {
+		t.Fatalf("token signed with the rotated key was rejected: %v", err)
+	}
+	if got := idp.fetches(); got != 2 {
+		t.Fatalf("expected the unknown key ID to trigger a refetch, got %d fetches", got)
+	}
+
+	// Within the interval another unknown key ID must not reach the provider again
+	if _, err := provider.VerifyIDToken(context.Background(), signRS256(t, newKey, "key-3", idp.claims()), ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken for an unknown key ID, got %v", err)
+	}
+	if got := idp.fetches(); got != 2 {
+		t.Fatalf("expected no refetch within the minimum interval, got %d fetches", got)
+	}
+}
+
+func TestOIDCProviderRejectsAlgorithmConfusion(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addKey(t, "key-1")
+	provider := newTestProvider(t, idp)
+
+	// An attacker signs with HS256 using the published public key as the shared secret
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	secrets := [][]byte{der, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), key.PublicKey.N.Bytes()}
+
+	for _, secret := range secrets {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.claims())
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		if _, err := provider.VerifyIDToken(context.Background(), signed, ""); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected ErrInvalidIDToken for an HS256 token, got %v", err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // refreshTokenRepository implements RefreshTokenRepository interface
@@ -41,6 +42,81 @@ func (r *refreshTokenRepository) RevokeByTokenID(ctx context.Context, tokenID st
 		Update("revoked", true).Error
 }
 
+// Rotate atomically revokes the refresh token identified by tokenID and stores next as its
+// replacement in the same family. It returns the rotated token, or ErrRefreshTokenReused along
+// with the stored token if it had already been rotated.
+func (r *refreshTokenRepository) Rotate(ctx context.Context, tokenID string, next *RefreshToken) (*RefreshToken, error) {
+	var stored RefreshToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_id = ?", tokenID).
+			First(&stored).Error
+		if err != nil {
+			return err
+		}
+
+		if stored.ReplacedBy != nil {
+			return ErrRefreshTokenReused
+		}
+		if !stored.IsValid() {
+			return ErrRefreshTokenRevoked
+		}
+
+		next.FamilyID = stored.FamilyID
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		stored.RevokeAndReplace(next.TokenID)
+		return tx.Model(&stored).Updates(map[string]interface{}{
+			"revoked":     stored.Revoked,
+			"replaced_by": stored.ReplacedBy,
+			"updated_at":  stored.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		if err == ErrRefreshTokenReused {
+			return &stored, err
+		}
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// RevokeFamily revokes every token in one of a user's token families and returns how many
+// were still active
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, userID, familyID uuid.UUID) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked = ?", userID, familyID, false).
+		Update("revoked", true)
+	return result.RowsAffected, result.Error
+}
+
+// RevokeOtherFamilies revokes every active token of a user outside keepFamilyID and returns
+// the families that were revoked
+func (r *refreshTokenRepository) RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) ([]uuid.UUID, error) {
+	var familyIDs []uuid.UUID
+	err := r.db.WithContext(ctx).Raw(
+		`UPDATE refresh_tokens SET revoked = true, updated_at = ?
+		WHERE user_id = ? AND family_id <> ? AND revoked = false
+		RETURNING family_id`,
+		time.Now(), userID, keepFamilyID,
+	).Scan(&familyIDs).Error
+	return familyIDs, err
+}
+
+// ListActiveUserTokens retrieves a user's unrevoked, unexpired tokens, most recently issued first.
+// Rotation leaves at most one active token per family.
+func (r *refreshTokenRepository) ListActiveUserTokens(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	var tokens []RefreshToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Order("issued_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
 // RevokeAllUserTokens revokes all tokens for a user
 func (r *refreshTokenRepository) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error {
 	return r.db.WithContext(ctx).
@@ -49,9 +125,198 @@ func (r *refreshTokenRepository) RevokeAllUserTokens(ctx context.Context, userID
 		Update("revoked", true).Error
 }
 
-// CleanExpiredTokens removes expired refresh tokens
-func (r *refreshTokenRepository) CleanExpiredTokens(ctx context.Context) error {
-	return r.db.WithContext(ctx).
+// CleanExpiredTokens removes expired refresh tokens and returns how many were removed
+func (r *refreshTokenRepository) CleanExpiredTokens(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
 		Where("expires_at < ?", time.Now()).
-		Delete(&RefreshToken{}).Error
+		Delete(&RefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+// oneTimeTokenRepository implements OneTimeTokenRepository interface
+type oneTimeTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewOneTimeTokenRepository creates a new one-time token repository
+func NewOneTimeTokenRepository(db *gorm.DB) OneTimeTokenRepository {
+	return &oneTimeTokenRepository{db: db}
+}
+
+// Create stores a token and marks the user's earlier unused tokens for the same purpose as
+// used, so only the most recently sent link works
+func (r *oneTimeTokenRepository) Create(ctx context.Context, token *OneTimeToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&OneTimeToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// Consume marks the unused, unexpired token with the given hash and purpose as used and
+// returns its user ID. It returns gorm.ErrRecordNotFound if there is no such token.
+func (r *oneTimeTokenRepository) Consume(ctx context.Context, tokenHash, purpose string) (uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(
+		`UPDATE one_time_tokens SET used_at = ?
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING user_id`,
+		now, tokenHash, purpose, now,
+	).Scan(&userIDs).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(userIDs) == 0 {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return userIDs[0], nil
+}
+
+// CleanExpiredTokens removes expired one-time tokens and returns how many were removed
+func (r *oneTimeTokenRepository) CleanExpiredTokens(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&OneTimeToken{})
+	return result.RowsAffected, result.Error
+}
+
+// authEventRepository implements AuthEventRepository interface
+type authEventRepository struct {
+	db *gorm.DB
+}
+
+// NewAuthEventRepository creates a new auth event repository
+func NewAuthEventRepository(db *gorm.DB) AuthEventRepository {
+	return &authEventRepository{db: db}
+}
+
+// Create stores an auth event
+func (r *authEventRepository) Create(ctx context.Context, event *AuthEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// DeleteBefore deletes events recorded before cutoff, batchSize rows at a time, and returns
+// how many were deleted
+func (r *authEventRepository) DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	var deleted int64
+	for {
+		result := r.db.WithContext(ctx).Exec(
+			`DELETE FROM auth_events WHERE id IN (SELECT id FROM auth_events WHERE created_at < ? LIMIT ?)`,
+			cutoff, batchSize,
+		)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return deleted, nil
+		}
+	}
+}
+
+// mfaRepository implements MFARepository interface
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// GetFactor retrieves a user's TOTP factor
+func (r *mfaRepository) GetFactor(ctx context.Context, userID uuid.UUID) (*TOTPFactor, error) {
+	var factor TOTPFactor
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&factor).Error
+	if err != nil {
+		return nil, err
+	}
+	return &factor, nil
+}
+
+// SaveFactor creates or replaces a user's TOTP factor
+func (r *mfaRepository) SaveFactor(ctx context.Context, factor *TOTPFactor) error {
+	return r.db.WithContext(ctx).Save(factor).Error
+}
+
+// ConfirmFactor marks a user's pending factor confirmed, records step as its last used code
+// and replaces the user's recovery codes with codes
+func (r *mfaRepository) ConfirmFactor(ctx context.Context, userID uuid.UUID, step int64, codes []RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&TOTPFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{
+				"confirmed_at":   time.Now(),
+				"last_used_step": step,
+				"updated_at":     time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+// AdvanceStep records step as the last code used with a user's factor if it is later than
+// the one recorded, and reports whether it was. Concurrent uses of the same code cannot both
+// succeed.
+func (r *mfaRepository) AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&TOTPFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "updated_at": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteFactor removes a user's TOTP factor and recovery codes
+func (r *mfaRepository) DeleteFactor(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&TOTPFactor{}).Error
+	})
+}
+
+// ListUnusedRecoveryCodes retrieves a user's recovery codes that have not been used
+func (r *mfaRepository) ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]RecoveryCode, error) {
+	var codes []RecoveryCode
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Find(&codes).Error
+	return codes, err
+}
+
+// UseRecoveryCode marks a recovery code as used and reports whether it was still unused
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReplaceRecoveryCodes replaces all of a user's recovery codes with codes
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codes []RecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
 }
@@ -2,7 +2,10 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 	"gorm.io/gorm"
@@ -13,37 +16,87 @@ type RefreshTokenRepository interface {
 	Create(ctx context.Context, token *RefreshToken) error
 	GetByTokenID(ctx context.Context, tokenID string) (*RefreshToken, error)
 	RevokeByTokenID(ctx context.Context, tokenID string) error
+	Rotate(ctx context.Context, tokenID string, next *RefreshToken) (*RefreshToken, error)
+	RevokeFamily(ctx context.Context, userID, familyID uuid.UUID) (int64, error)
+	RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) ([]uuid.UUID, error)
+	ListActiveUserTokens(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error)
 	RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error
-	CleanExpiredTokens(ctx context.Context) error
+	CleanExpiredTokens(ctx context.Context) (int64, error)
+}
+
+var (
+	// ErrAccountDisabled is returned when tokens are requested for a deactivated account
+	ErrAccountDisabled = errors.New("account is disabled")
+	// ErrRefreshTokenRevoked is returned for refresh tokens that were revoked or have expired
+	ErrRefreshTokenRevoked = errors.New("refresh token is revoked or expired")
+	// ErrSessionNotFound is returned when a session does not exist or is no longer active
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrRefreshTokenReused is returned when a refresh token that was already rotated is
+	// presented again, which means it has leaked
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// Account is the current state of a user account, as needed to issue tokens
+type Account struct {
+	Email    string
+	Roles    []string
+	IsActive bool
+}
+
+// AccountLookup loads the current state of a user account. *user.Service satisfies it.
+type AccountLookup interface {
+	GetAccount(ctx context.Context, userID uuid.UUID) (*Account, error)
 }
 
 // AuthService provides authentication business logic
 type AuthService struct {
 	jwtService      *JWTService
-	googleService   *GoogleService
+	providers       map[string]IdentityProvider
 	refreshTokenRepo RefreshTokenRepository
+	sessions        *SessionStore
+	nonces          *NonceStore
+	accounts        AccountLookup
 	logger          logger.Logger
 }
 
 // NewAuthService creates a new authentication service
 func NewAuthService(
 	jwtService *JWTService,
-	googleService *GoogleService,
+	providers []IdentityProvider,
 	refreshTokenRepo RefreshTokenRepository,
+	sessions *SessionStore,
+	nonces *NonceStore,
+	accounts AccountLookup,
 	logger logger.Logger,
 ) *AuthService {
+	byName := make(map[string]IdentityProvider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
 	return &AuthService{
 		jwtService:      jwtService,
-		googleService:   googleService,
+		providers:       byName,
 		refreshTokenRepo: refreshTokenRepo,
+		sessions:        sessions,
+		nonces:          nonces,
+		accounts:        accounts,
 		logger:          logger,
 	}
 }
 
 // GenerateTokens generates access and refresh tokens for a user
 func (s *AuthService) GenerateTokens(ctx context.Context, userID uuid.UUID, email string, roles []string, userAgent, ip string) (*TokenPair, error) {
+	epoch, err := s.sessions.Epoch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every sign-in starts a new session, identified by its refresh token family
+	sessionID := uuid.New()
+
 	// Generate JWT token pair
-	tokenPair, err := s.jwtService.GenerateTokenPair(userID, email, roles)
+	tokenPair, err := s.jwtService.GenerateTokenPair(userID, email, roles, epoch, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token pair: %w", err)
 	}
@@ -58,6 +111,7 @@ func (s *AuthService) GenerateTokens(ctx context.Context, userID uuid.UUID, emai
 	refreshToken := &RefreshToken{
 		UserID:    userID,
 		TokenID:   refreshClaims.ID,
+		FamilyID:  sessionID,
 		IssuedAt:  refreshClaims.IssuedAt.Time,
 		ExpiresAt: refreshClaims.ExpiresAt.Time,
 		UserAgent: &userAgent,
@@ -79,8 +133,8 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshTokenString, use
 		return nil, fmt.Errorf("invalid refresh token")
 	}
 
-	// Get stored refresh token
-	storedToken, err := s.refreshTokenRepo.GetByTokenID(ctx, refreshClaims.ID)
+	// Find the session the token belongs to. Rotate checks the token again under a lock.
+	presented, err := s.refreshTokenRepo.GetByTokenID(ctx, refreshClaims.ID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("refresh token not found")
@@ -88,13 +142,23 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshTokenString, use
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
-	// Check if token is valid
-	if !storedToken.IsValid() {
-		return nil, fmt.Errorf("refresh token is revoked or expired")
+	// Issue the new pair from the account's current state, so deactivation and role
+	// changes made since the refresh token was issued apply
+	account, err := s.accounts.GetAccount(ctx, refreshClaims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load account: %w", err)
+	}
+	if !account.IsActive {
+		return nil, ErrAccountDisabled
+	}
+
+	epoch, err := s.sessions.Epoch(ctx, refreshClaims.UserID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate new token pair
-	newTokenPair, err := s.jwtService.GenerateTokenPair(refreshClaims.UserID, refreshClaims.Email, refreshClaims.Roles)
+	newTokenPair, err := s.jwtService.GenerateTokenPair(refreshClaims.UserID, account.Email, account.Roles, epoch, presented.FamilyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new token pair: %w", err)
 	}
@@ -105,10 +169,6 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshTokenString, use
 		return nil, fmt.Errorf("failed to parse new refresh token: %w", err)
 	}
 
-	// Revoke old token and create new one
-	storedToken.RevokeAndReplace(newRefreshClaims.ID)
-	
-	// Create new refresh token record
 	newRefreshToken := &RefreshToken{
 		UserID:    refreshClaims.UserID,
 		TokenID:   newRefreshClaims.ID,
@@ -118,14 +178,50 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshTokenString, use
 		IP:        &ip,
 	}
 
-	if err := s.refreshTokenRepo.Create(ctx, newRefreshToken); err != nil {
-		return nil, fmt.Errorf("failed to store new refresh token: %w", err)
+	// Revoke the presented token and store its replacement in one transaction
+	storedToken, err := s.refreshTokenRepo.Rotate(ctx, refreshClaims.ID, newRefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRefreshTokenReused):
+			s.handleRefreshTokenReuse(ctx, storedToken, userAgent, ip)
+			return nil, err
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, fmt.Errorf("refresh token not found")
+		case errors.Is(err, ErrRefreshTokenRevoked):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
 
-	s.logger.Info("Tokens refreshed successfully", "user_id", refreshClaims.UserID)
+	s.logger.WithContext(ctx).Info("Tokens refreshed successfully", "user_id", refreshClaims.UserID)
 	return newTokenPair, nil
 }
 
+// handleRefreshTokenReuse responds to a rotated refresh token being presented again. Either
+// the caller or the legitimate client holds a stolen token and there is no telling which, so
+// the whole family is revoked along with the user's access tokens.
+func (s *AuthService) handleRefreshTokenReuse(ctx context.Context, reused *RefreshToken, userAgent, ip string) {
+	log := s.logger.With(
+		"event", "refresh_token_reuse",
+		"user_id", reused.UserID,
+		"family_id", reused.FamilyID,
+		"token_id", reused.TokenID,
+		"ip", ip,
+		"user_agent", userAgent,
+	)
+
+	revoked, err := s.refreshTokenRepo.RevokeFamily(ctx, reused.UserID, reused.FamilyID)
+	if err != nil {
+		log.Error("Security event: failed to revoke refresh token family after reuse", "error", err)
+		return
+	}
+	if err := s.sessions.Invalidate(ctx, reused.UserID); err != nil {
+		log.Error("Security event: failed to invalidate access tokens after refresh token reuse", "error", err)
+	}
+
+	log.Warn("Security event: refresh token reuse detected, token family revoked", "revoked_tokens", revoked)
+}
+
 // RevokeToken revokes a refresh token (logout)
 func (s *AuthService) RevokeToken(ctx context.Context, refreshTokenString string) error {
 	// Verify refresh token
@@ -139,26 +235,136 @@ func (s *AuthService) RevokeToken(ctx context.Context, refreshTokenString string
 		return fmt.Errorf("failed to revoke token: %w", err)
 	}
 
-	s.logger.Info("Token revoked successfully", "user_id", refreshClaims.UserID, "token_id", refreshClaims.ID)
+	s.logger.WithContext(ctx).Info("Token revoked successfully", "user_id", refreshClaims.UserID, "token_id", refreshClaims.ID)
 	return nil
 }
 
-// RevokeAllUserTokens revokes all tokens for a user
+// RevokeAllUserTokens revokes all tokens for a user, including access tokens already issued
 func (s *AuthService) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error {
 	if err := s.refreshTokenRepo.RevokeAllUserTokens(ctx, userID); err != nil {
 		return fmt.Errorf("failed to revoke user tokens: %w", err)
 	}
+	if err := s.sessions.Invalidate(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("All user tokens revoked", "user_id", userID)
+	return nil
+}
+
+// ListSessions returns a user's active sessions, marking currentSessionID as the current one
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]Session, error) {
+	tokens, err := s.refreshTokenRepo.ListActiveUserTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
 
-	s.logger.Info("All user tokens revoked", "user_id", userID)
+	sessions := make([]Session, 0, len(tokens))
+	for _, token := range tokens {
+		session := Session{
+			ID:         token.FamilyID,
+			LastUsedAt: token.IssuedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID == currentSessionID,
+		}
+		if token.UserAgent != nil {
+			session.UserAgent = *token.UserAgent
+		}
+		if token.IP != nil {
+			session.IP = *token.IP
+		}
+		session.Device = ParseUserAgent(session.UserAgent)
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one session, including its access tokens
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := s.refreshTokenRepo.RevokeFamily(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+	if err := s.sessions.RevokeSessions(ctx, sessionID); err != nil {
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("Session revoked", "user_id", userID, "session_id", sessionID)
 	return nil
 }
 
-// VerifyGoogleIDToken verifies a Google ID token and returns user info
-func (s *AuthService) VerifyGoogleIDToken(ctx context.Context, idToken string) (*GoogleUser, error) {
-	return s.googleService.VerifyIDToken(ctx, idToken)
+// RevokeOtherSessions signs a user out of every session except currentSessionID and returns
+// how many sessions were revoked
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) (int, error) {
+	revoked, err := s.refreshTokenRepo.RevokeOtherFamilies(ctx, userID, currentSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.sessions.RevokeSessions(ctx, revoked...); err != nil {
+		return 0, err
+	}
+
+	s.logger.WithContext(ctx).Info("Other sessions revoked", "user_id", userID, "session_id", currentSessionID, "revoked", len(revoked))
+	return len(revoked), nil
 }
 
-// CleanupExpiredTokens removes expired refresh tokens
-func (s *AuthService) CleanupExpiredTokens(ctx context.Context) error {
-	return s.refreshTokenRepo.CleanExpiredTokens(ctx)
+// InvalidateAccessTokens rejects the user's current access tokens while keeping their
+// refresh tokens, so the next refresh picks up changes such as new roles
+func (s *AuthService) InvalidateAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	if err := s.sessions.Invalidate(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("User access tokens invalidated", "user_id", userID)
+	return nil
+}
+
+// IssueNonce creates a single-use nonce for a client to request a provider ID token with, and
+// returns how long it stays valid
+func (s *AuthService) IssueNonce(ctx context.Context) (string, time.Duration, error) {
+	nonce, err := s.nonces.Issue(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	return nonce, s.nonces.TTL(), nil
+}
+
+// VerifyIDToken verifies an ID token from a configured login provider and returns the account
+// it was issued for. A nonce must have been issued by IssueNonce and is used up, so the same
+// token cannot be presented twice.
+func (s *AuthService) VerifyIDToken(ctx context.Context, provider, idToken, nonce string) (*ProviderIdentity, error) {
+	identityProvider, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownIdentityProvider
+	}
+	identity, err := identityProvider.VerifyIDToken(ctx, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if nonce != "" {
+		if err := s.nonces.Consume(ctx, nonce); err != nil {
+			if errors.Is(err, ErrNonceInvalid) {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+			}
+			return nil, err
+		}
+	}
+	return identity, nil
+}
+
+// JWKS returns the public keys that access tokens can be verified with
+func (s *AuthService) JWKS() *JWKS {
+	return s.jwtService.JWKS()
+}
+
+// CleanupExpiredTokens removes expired refresh tokens and returns how many were removed
+func (s *AuthService) CleanupExpiredTokens(ctx context.Context) (int64, error) {
+	removed, err := s.refreshTokenRepo.CleanExpiredTokens(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean expired tokens: %w", err)
+	}
+	return removed, nil
 }
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	sessionEpochKeyPrefix   = "auth:epoch:"
+	revokedSessionKeyPrefix = "auth:revoked_session:"
+)
+
+// ErrSessionRevoked is returned for tokens whose session was revoked, or that were issued
+// before the user's sessions were invalidated.
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// bumpEpochScript moves the epoch to the current time in milliseconds, or one past the
+// current epoch if clocks disagree, so epochs only ever increase.
+var bumpEpochScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local next = tonumber(ARGV[1])
+if next <= current then
+	next = current + 1
+end
+redis.call('SET', KEYS[1], next, 'PX', ARGV[2])
+return next
+`)
+
+// SessionStore tracks a per-user session epoch in Redis. Tokens carry the epoch that was
+// current when they were issued, and invalidating a user's sessions moves the epoch forward,
+// which rejects every access token issued before it. Single sessions can also be revoked,
+// which rejects the access tokens carrying that session ID.
+//
+// Both only need to outlive the access tokens they reject, so keys expire after the access
+// token TTL. Because epochs are timestamps, a later epoch is always higher than any token
+// issued before the key expired.
+type SessionStore struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewSessionStore creates a session store. ttl should be the access token lifetime.
+func NewSessionStore(client *redis.Client, ttl time.Duration) *SessionStore {
+	return &SessionStore{redis: client, ttl: ttl}
+}
+
+// Epoch returns the user's current session epoch, which is 0 if their sessions have not
+// been invalidated recently.
+func (s *SessionStore) Epoch(ctx context.Context, userID uuid.UUID) (int64, error) {
+	epoch, err := s.redis.Get(ctx, sessionEpochKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read session epoch: %w", err)
+	}
+	return epoch, nil
+}
+
+// Invalidate rejects every access token issued to the user so far.
+func (s *SessionStore) Invalidate(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now().UnixMilli()
+	if err := bumpEpochScript.Run(ctx, s.redis, []string{sessionEpochKey(userID)}, now, s.ttl.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeSessions rejects the access tokens already issued for the given sessions.
+func (s *SessionStore) RevokeSessions(ctx context.Context, sessionIDs ...uuid.UUID) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+	for _, id := range sessionIDs {
+		pipe.Set(ctx, revokedSessionKey(id), 1, s.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that claims were issued no earlier than the user's current epoch and that
+// their session has not been revoked.
+func (s *SessionStore) Validate(ctx context.Context, claims *Claims) error {
+	values, err := s.redis.MGet(ctx, sessionEpochKey(claims.UserID), revokedSessionKey(claims.SessionID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read session state: %w", err)
+	}
+
+	if values[1] != nil {
+		return ErrSessionRevoked
+	}
+	if raw, ok := values[0].(string); ok {
+		epoch, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid session epoch %q: %w", raw, err)
+		}
+		if claims.Epoch < epoch {
+			return ErrSessionRevoked
+		}
+	}
+	return nil
+}
+
+func sessionEpochKey(userID uuid.UUID) string {
+	return sessionEpochKeyPrefix + userID.String()
+}
+
+func revokedSessionKey(sessionID uuid.UUID) string {
+	return revokedSessionKeyPrefix + sessionID.String()
+}
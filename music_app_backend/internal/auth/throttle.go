@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const throttleKeyPrefix = "auth:throttle:"
+
+// Reasons a ThrottleDecision can deny an attempt
+const (
+	ThrottleIPLimited     = "ip_limited"     // too many attempts from the client's IP
+	ThrottleAccountDelay  = "account_delay"  // too soon after the account's last failed sign-in
+	ThrottleAccountLocked = "account_locked" // the account is locked after repeated failures
+)
+
+// ThrottleConfig configures brute-force protection. Counters are sliding windows: an attempt
+// counts until Window (or RegisterWindow) has passed since it was made.
+type ThrottleConfig struct {
+	Window time.Duration
+
+	// LoginIPLimit is the number of failed sign-ins an IP may make per window
+	LoginIPLimit int
+	// After DelayAfter failed sign-ins to an account within the window, each further attempt
+	// must wait BaseDelay after the previous failure, doubling with every failure up to MaxDelay
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// LockoutThreshold failed sign-ins within the window lock the account for LockoutDuration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+
+	// RefreshIPLimit is the number of failed token refreshes an IP may make per window
+	RefreshIPLimit int
+
+	// RegisterIPLimit is the number of registration attempts an IP may make per RegisterWindow
+	RegisterIPLimit int
+	RegisterWindow  time.Duration
+}
+
+// Attempt describes a client attempting an authentication action
+type Attempt struct {
+	Email     string // empty when the action is not tied to an account
+	IP        string
+	UserAgent string
+}
+
+// ThrottleDecision is the outcome of checking an attempt against the throttle
+type ThrottleDecision struct {
+	Allowed    bool
+	Reason     string
+	RetryAfter time.Duration
+}
+
+// slidingWindowScript trims entries older than the window from a sorted set of attempt
+// timestamps, optionally adds one, and returns the count with the oldest and newest timestamps.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+if ARGV[3] ~= '' then
+	redis.call('ZADD', key, now, ARGV[3])
+	redis.call('PEXPIRE', key, window)
+end
+local count = redis.call('ZCARD', key)
+if count == 0 then
+	return {0, 0, 0}
+end
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+return {count, tonumber(oldest[2]), tonumber(newest[2])}
+`)
+
+// Throttle protects sign-in, registration and token refresh against brute force and
+// credential stuffing with Redis sliding-window counters per IP and per account. Failed
+// sign-ins slow down and eventually lock the targeted account, whether or not it exists, so
+// responses do not reveal which accounts exist.
+//
+// When Redis fails the checks return the error with a decision that allows the attempt, so
+// callers can log it and carry on rather than lock everyone out.
+type Throttle struct {
+	redis  *redis.Client
+	config ThrottleConfig
+	audit  *AuditLog
+}
+
+// NewThrottle creates a throttle that records denied and failed attempts in audit
+func NewThrottle(client *redis.Client, config ThrottleConfig, audit *AuditLog) *Throttle {
+	return &Throttle{redis: client, config: config, audit: audit}
+}
+
+// CheckLogin decides whether a sign-in attempt may proceed
+func (t *Throttle) CheckLogin(ctx context.Context, attempt Attempt) (ThrottleDecision, error) {
+	decision, err := t.checkIP(ctx, "login", attempt.IP, t.config.LoginIPLimit, t.config.Window)
+	if err == nil && decision.Allowed {
+		decision, err = t.checkAccount(ctx, attempt.Email)
+	}
+	if err != nil {
+		return ThrottleDecision{Allowed: true}, err
+	}
+
+	if !decision.Allowed {
+		t.record(ctx, EventLoginThrottled, attempt, decision.Reason)
+	}
+	return decision, nil
+}
+
+// LoginFailed records a failed sign-in and reports whether it locked the account
+func (t *Throttle) LoginFailed(ctx context.Context, attempt Attempt, detail string) (bool, error) {
+	t.record(ctx, EventLoginFailed, attempt, detail)
+
+	if _, err := t.hit(ctx, ipKey("login", attempt.IP), t.config.Window); err != nil {
+		return false, err
+	}
+	window, err := t.hit(ctx, accountKey(attempt.Email), t.config.Window)
+	if err != nil {
+		return false, err
+	}
+
+	if t.config.LockoutThreshold <= 0 || window.count < int64(t.config.LockoutThreshold) {
+		return false, nil
+	}
+	locked, err := t.redis.SetNX(ctx, lockoutKey(attempt.Email), time.Now().UTC().Format(time.RFC3339), t.config.LockoutDuration).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to lock account: %w", err)
+	}
+	if locked {
+		t.record(ctx, EventAccountLocked, attempt, fmt.Sprintf("%d failed sign-ins", window.count))
+	}
+	return locked, nil
+}
+
+// LoginSucceeded clears the account's failed sign-ins. Failures from the IP still count.
+func (t *Throttle) LoginSucceeded(ctx context.Context, email string) error {
+	if err := t.redis.Del(ctx, accountKey(email)).Err(); err != nil {
+		return fmt.Errorf("failed to reset sign-in failures: %w", err)
+	}
+	return nil
+}
+
+// Unlock lifts an account lockout and clears its failed sign-ins
+func (t *Throttle) Unlock(ctx context.Context, userID uuid.UUID, attempt Attempt) error {
+	deleted, err := t.redis.Del(ctx, lockoutKey(attempt.Email), accountKey(attempt.Email)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if deleted > 0 {
+		event := t.event(EventAccountUnlocked, attempt, "")
+		event.UserID = &userID
+		t.audit.Record(ctx, event)
+	}
+	return nil
+}
+
+// CheckRegister decides whether a registration attempt may proceed, and counts it
+func (t *Throttle) CheckRegister(ctx context.Context, attempt Attempt) (ThrottleDecision, error) {
+	decision, err := t.checkIP(ctx, "register", attempt.IP, t.config.RegisterIPLimit, t.config.RegisterWindow)
+	if err != nil {
+		return ThrottleDecision{Allowed: true}, err
+	}
+	if !decision.Allowed {
+		t.record(ctx, EventRegisterThrottled, attempt, decision.Reason)
+		return decision, nil
+	}
+
+	if _, err := t.hit(ctx, ipKey("register", attempt.IP), t.config.RegisterWindow); err != nil {
+		return decision, err
+	}
+	return decision, nil
+}
+
+// CheckRefresh decides whether a token refresh may proceed
+func (t *Throttle) CheckRefresh(ctx context.Context, attempt Attempt) (ThrottleDecision, error) {
+	decision, err := t.checkIP(ctx, "refresh", attempt.IP, t.config.RefreshIPLimit, t.config.Window)
+	if err != nil {
+		return ThrottleDecision{Allowed: true}, err
+	}
+	if !decision.Allowed {
+		t.record(ctx, EventRefreshThrottled, attempt, decision.Reason)
+	}
+	return decision, nil
+}
+
+// RefreshFailed records a failed token refresh
+func (t *Throttle) RefreshFailed(ctx context.Context, attempt Attempt, detail string) error {
+	t.record(ctx, EventRefreshFailed, attempt, detail)
+	_, err := t.hit(ctx, ipKey("refresh", attempt.IP), t.config.Window)
+	return err
+}
+
+// checkIP denies the attempt if ip has reached limit within window
+func (t *Throttle) checkIP(ctx context.Context, action, ip string, limit int, window time.Duration) (ThrottleDecision, error) {
+	if limit <= 0 {
+		return ThrottleDecision{Allowed: true}, nil
+	}
+
+	w, err := t.read(ctx, ipKey(action, ip), window)
+	if err != nil {
+		return ThrottleDecision{}, err
+	}
+	if w.count < int64(limit) {
+		return ThrottleDecision{Allowed: true}, nil
+	}
+	// The IP may try again once its oldest counted attempt leaves the window
+	return ThrottleDecision{Reason: ThrottleIPLimited, RetryAfter: time.Until(w.oldest.Add(window))}, nil
+}
+
+// checkAccount denies the attempt if the account is locked or its progressive delay has not
+// passed since the last failure
+func (t *Throttle) checkAccount(ctx context.Context, email string) (ThrottleDecision, error) {
+	ttl, err := t.redis.PTTL(ctx, lockoutKey(email)).Result()
+	if err != nil {
+		return ThrottleDecision{}, fmt.Errorf("failed to read account lockout: %w", err)
+	}
+	if ttl > 0 {
+		return ThrottleDecision{Reason: ThrottleAccountLocked, RetryAfter: ttl}, nil
+	}
+
+	if t.config.DelayAfter <= 0 {
+		return ThrottleDecision{Allowed: true}, nil
+	}
+	w, err := t.read(ctx, accountKey(email), t.config.Window)
+	if err != nil {
+		return ThrottleDecision{}, err
+	}
+	if w.count < int64(t.config.DelayAfter) {
+		return ThrottleDecision{Allowed: true}, nil
+	}
+
+	wait := time.Until(w.newest.Add(t.progressiveDelay(w.count)))
+	if wait <= 0 {
+		return ThrottleDecision{Allowed: true}, nil
+	}
+	return ThrottleDecision{Reason: ThrottleAccountDelay, RetryAfter: wait}, nil
+}
+
+// progressiveDelay returns the wait required after failures failed sign-ins
+func (t *Throttle) progressiveDelay(failures int64) time.Duration {
+	delay := t.config.BaseDelay
+	for i := int64(t.config.DelayAfter); i < failures && delay < t.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.config.MaxDelay {
+		delay = t.config.MaxDelay
+	}
+	return delay
+}
+
+// attemptWindow summarises the attempts counted in a sliding window
+type attemptWindow struct {
+	count          int64
+	oldest, newest time.Time
+}
+
+// read returns the attempts in the window stored at key
+func (t *Throttle) read(ctx context.Context, key string, window time.Duration) (attemptWindow, error) {
+	return t.runWindow(ctx, key, window, "")
+}
+
+// hit counts an attempt now in the window stored at key
+func (t *Throttle) hit(ctx context.Context, key string, window time.Duration) (attemptWindow, error) {
+	return t.runWindow(ctx, key, window, uuid.NewString())
+}
+
+func (t *Throttle) runWindow(ctx context.Context, key string, window time.Duration, member string) (attemptWindow, error) {
+	values, err := slidingWindowScript.Run(ctx, t.redis, []string{key}, time.Now().UnixMilli(), window.Milliseconds(), member).Int64Slice()
+	if err != nil {
+		return attemptWindow{}, fmt.Errorf("failed to update attempt counter: %w", err)
+	}
+	return attemptWindow{
+		count:  values[0],
+		oldest: time.UnixMilli(values[1]),
+		newest: time.UnixMilli(values[2]),
+	}, nil
+}
+
+func (t *Throttle) record(ctx context.Context, name string, attempt Attempt, detail string) {
+	t.audit.Record(ctx, t.event(name, attempt, detail))
+}
+
+func (t *Throttle) event(name string, attempt Attempt, detail string) AuthEvent {
+	event := AuthEvent{Event: name, IP: attempt.IP, UserAgent: truncate(attempt.UserAgent, 500), Detail: truncate(detail, 255)}
+	if attempt.Email != "" {
+		email := truncate(attempt.Email, 255)
+		event.Email = &email
+	}
+	return event
+}
+
+func ipKey(action, ip string) string {
+	return throttleKeyPrefix + action + ":ip:" + ip
+}
+
+// accountKey identifies an account by a hash of its normalised email, which keeps addresses
+// out of Redis and works for accounts that do not exist
+func accountKey(email string) string {
+	return throttleKeyPrefix + "login:account:" + hashEmail(email)
+}
+
+func lockoutKey(email string) string {
+	return throttleKeyPrefix + "lockout:" + hashEmail(email)
+}
+
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,93 @@
+package auth
+
+import "strings"
+
+// Device describes the client behind a session, as far as its User-Agent reveals it
+type Device struct {
+	Browser string // e.g. "Chrome 120", or "Music App" for the mobile client
+	OS      string // e.g. "Android", "iOS", "Windows"
+	Type    string // "mobile", "tablet", "desktop" or "unknown"
+}
+
+// browserTokens lists User-Agent product tokens in match order. Chromium-based browsers
+// also send "Chrome/" and "Safari/", and Chrome sends "Safari/", so more specific tokens
+// come first.
+var browserTokens = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+}
+
+// ParseUserAgent extracts browser, OS and device type from a User-Agent header. It covers
+// the major browsers and the app's own Dart HTTP client rather than every agent.
+func ParseUserAgent(ua string) Device {
+	device := Device{Browser: "Unknown", OS: "Unknown", Type: "unknown"}
+	if ua == "" {
+		return device
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad"):
+		device.OS, device.Type = "iOS", "tablet"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"):
+		device.OS, device.Type = "iOS", "mobile"
+	case strings.Contains(ua, "Android"):
+		device.OS, device.Type = "Android", "tablet"
+		if strings.Contains(ua, "Mobile") {
+			device.Type = "mobile"
+		}
+	case strings.Contains(ua, "Windows"):
+		device.OS, device.Type = "Windows", "desktop"
+	case strings.Contains(ua, "CrOS"):
+		device.OS, device.Type = "ChromeOS", "desktop"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		device.OS, device.Type = "macOS", "desktop"
+	case strings.Contains(ua, "Linux"):
+		device.OS, device.Type = "Linux", "desktop"
+	}
+
+	// The Flutter client identifies itself as e.g. "Dart/3.2 (dart:io)"
+	if strings.HasPrefix(ua, "Dart/") {
+		device.Browser = "Music App"
+		return device
+	}
+
+	for _, b := range browserTokens {
+		if version, ok := productVersion(ua, b.token); ok {
+			device.Browser = b.name
+			if version != "" {
+				device.Browser += " " + version
+			}
+			break
+		}
+	}
+
+	return device
+}
+
+// productVersion returns the major version following token in ua, and whether token was found.
+func productVersion(ua, token string) (string, bool) {
+	i := strings.Index(ua, token)
+	if i < 0 {
+		return "", false
+	}
+
+	version := ua[i+len(token):]
+	if end := strings.IndexAny(version, " ;)"); end >= 0 {
+		version = version[:end]
+	}
+	if dot := strings.IndexByte(version, '.'); dot >= 0 {
+		version = version[:dot]
+	}
+	return version, true
+}
@@ -0,0 +1,31 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist in the store.
+var ErrNotFound = errors.New("blob not found")
+
+// Object is an open blob. Callers must close it when done.
+type Object struct {
+	io.ReadSeekCloser
+	Size    int64
+	ModTime time.Time
+}
+
+// Store persists opaque binary objects, such as downloaded audio, under string keys.
+type Store interface {
+	// Put streams r into the object stored under key, replacing any existing object,
+	// and returns the number of bytes written. Partially written objects are never visible.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+
+	// Open opens the object stored under key for reading.
+	Open(ctx context.Context, key string) (*Object, error)
+
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
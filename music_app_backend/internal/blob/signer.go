@@ -0,0 +1,53 @@
+package blob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid URL signature")
+	ErrSignatureExpired = errors.New("signed URL has expired")
+)
+
+// URLSigner issues and verifies short-lived HMAC signatures that grant access to a
+// resource without other credentials.
+type URLSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewURLSigner creates a signer whose signatures are valid for ttl.
+func NewURLSigner(secret string, ttl time.Duration) *URLSigner {
+	return &URLSigner{key: []byte(secret), ttl: ttl}
+}
+
+// Sign returns a signature for resource and the Unix time at which it expires.
+func (s *URLSigner) Sign(resource string) (int64, string) {
+	expires := time.Now().Add(s.ttl).Unix()
+	return expires, s.signature(resource, expires)
+}
+
+// Verify checks that signature was issued by this signer for resource and has not expired.
+func (s *URLSigner) Verify(resource string, expires int64, signature string) error {
+	expected := s.signature(resource, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+func (s *URLSigner) signature(resource string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(resource))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,64 @@
+// Package buildinfo describes the running binary. Version, Commit and BuildTime are set at
+// build time with -ldflags, for example:
+//
+//	go build -ldflags "-X github.com/mosesmmoisebidth/music_backend/internal/buildinfo.Version=v1.3.0 \
+//		-X github.com/mosesmmoisebidth/music_backend/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X github.com/mosesmmoisebidth/music_backend/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X at build time
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string
+	Commit    string
+	BuildTime string
+	Modified  bool // built from a tree with uncommitted changes, when known
+	GoVersion string
+}
+
+// Get returns the build info. A commit and build time not set with -ldflags fall back to the
+// revision and commit time the Go toolchain embeds when building inside a git checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
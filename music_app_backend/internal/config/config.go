@@ -17,7 +17,17 @@ type Config struct {
 	Auth      AuthConfig      `mapstructure:"auth"`
 	Providers ProvidersConfig `mapstructure:"providers"`
 	Google    GoogleConfig    `mapstructure:"google"`
+	OIDC      OIDCConfig      `mapstructure:"oidc"`
 	Spotify   SpotifyConfig   `mapstructure:"spotify"`
+	Downloads DownloadsConfig `mapstructure:"downloads"`
+	Jobs      JobsConfig      `mapstructure:"jobs"`
+	Account   AccountConfig   `mapstructure:"account"`
+	Mail      MailConfig      `mapstructure:"mail"`
+	Throttle  ThrottleConfig  `mapstructure:"throttle"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Health    HealthConfig    `mapstructure:"health"`
 }
 
 // AppConfig contains general application configuration
@@ -34,6 +44,9 @@ type ServerConfig struct {
 	WriteTimeout time.Duration `mapstructure:"write_timeout" default:"30s"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout" default:"120s"`
 	CORS         CORSConfig    `mapstructure:"cors"`
+	// TrustedProxies are the IPs or CIDRs of load balancers allowed to set X-Forwarded-For.
+	// Empty trusts none, so client IPs are taken from the connection.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // CORSConfig contains CORS configuration
@@ -56,6 +69,7 @@ type DatabaseConfig struct {
 	MaxOpenConns int    `mapstructure:"max_open_conns" default:"25"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns" default:"25"`
 	MaxLifetime  string `mapstructure:"max_lifetime" default:"5m"`
+	AutoMigrate  bool   `mapstructure:"auto_migrate" default:"true"` // apply pending migrations on startup
 }
 
 // DSN returns the database connection string
@@ -75,14 +89,25 @@ type RedisConfig struct {
 
 // AuthConfig contains authentication configuration
 type AuthConfig struct {
-	JWTSigningMethod    string        `mapstructure:"jwt_signing_method" default:"HS256"`
-	JWTAccessSecret     string        `mapstructure:"jwt_access_secret"`
-	JWTRefreshSecret    string        `mapstructure:"jwt_refresh_secret"`
+	JWTSigningMethod string `mapstructure:"jwt_signing_method" default:"HS256"`
+	JWTAccessSecret  string `mapstructure:"jwt_access_secret"`
+	JWTRefreshSecret string `mapstructure:"jwt_refresh_secret"`
+	// Earlier secrets that tokens are still accepted from, so secrets can be rotated
+	JWTPreviousAccessSecrets  []string `mapstructure:"jwt_previous_access_secrets"`
+	JWTPreviousRefreshSecrets []string `mapstructure:"jwt_previous_refresh_secrets"`
+	// PEM signing key for the RS*, ES* and EdDSA methods, given inline or as a file
+	JWTPrivateKey       string        `mapstructure:"jwt_private_key"`
+	JWTPrivateKeyFile   string        `mapstructure:"jwt_private_key_file"`
+	JWTKeyID            string        `mapstructure:"jwt_key_id"`             // kid of the signing key; defaults to its JWK thumbprint
+	JWTPreviousKeyFiles []string      `mapstructure:"jwt_previous_key_files"` // PEM keys rotated out, still accepted and published until their tokens expire
 	AccessTokenTTL      time.Duration `mapstructure:"access_token_ttl" default:"15m"`
 	RefreshTokenTTL     time.Duration `mapstructure:"refresh_token_ttl" default:"720h"`
 	PasswordHashMemory  uint32        `mapstructure:"password_hash_memory" default:"65536"`
 	PasswordHashTime    uint32        `mapstructure:"password_hash_time" default:"3"`
 	PasswordHashThreads uint8         `mapstructure:"password_hash_threads" default:"2"`
+	MFAIssuer           string        `mapstructure:"mfa_issuer" default:"Music App"` // account issuer shown in authenticator apps
+	MFAEncryptionKey    string        `mapstructure:"mfa_encryption_key"`             // encrypts TOTP secrets; required outside development
+	MFAChallengeTTL     time.Duration `mapstructure:"mfa_challenge_ttl" default:"5m"` // how long a sign-in waits for the second factor
 }
 
 // GoogleConfig contains Google OAuth configuration
@@ -92,6 +117,24 @@ type GoogleConfig struct {
 	RedirectURLs []string `mapstructure:"redirect_urls"`
 }
 
+// OIDCConfig contains the OpenID Connect providers users can sign in with
+type OIDCConfig struct {
+	Enabled   []string                      `mapstructure:"enabled" default:"google"`
+	Providers map[string]OIDCProviderConfig `mapstructure:"providers"`               // settings of each enabled provider, by name
+	NonceTTL  string                        `mapstructure:"nonce_ttl" default:"10m"` // how long a nonce from /auth/oidc/nonce can be used
+}
+
+// OIDCProviderConfig configures an OpenID Connect provider. Google and Apple only need client
+// IDs; Google's default to google.client_id.
+type OIDCProviderConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientIDs    []string `mapstructure:"client_ids"`    // accepted token audiences
+	DiscoveryURL string   `mapstructure:"discovery_url"` // defaults to the issuer's /.well-known/openid-configuration
+	JWKSURL      string   `mapstructure:"jwks_url"`      // skips discovery when set
+	RequireNonce *bool    `mapstructure:"require_nonce"` // defaults to true for Apple
+	TrustEmail   *bool    `mapstructure:"trust_email"`   // accept email_verified to sign in to existing accounts; defaults to true for Google and Apple only
+}
+
 // SpotifyConfig contains Spotify API configuration
 type SpotifyConfig struct {
 	ClientID     string `mapstructure:"client_id"`
@@ -101,10 +144,131 @@ type SpotifyConfig struct {
 
 // ProvidersConfig contains music providers configuration
 type ProvidersConfig struct {
-	Enabled        []string `mapstructure:"enabled" default:"itunes"`
-	DefaultTimeout string   `mapstructure:"default_timeout" default:"30s"`
-	CacheTTL       string   `mapstructure:"cache_ttl" default:"300s"`
-	RateLimit      int      `mapstructure:"rate_limit" default:"100"`
+	Enabled                 []string `mapstructure:"enabled" default:"itunes"`
+	DefaultTimeout          string   `mapstructure:"default_timeout" default:"30s"`
+	CacheTTL                string   `mapstructure:"cache_ttl" default:"300s"`
+	TrackCacheTTL           string   `mapstructure:"track_cache_ttl" default:"1h"`
+	CategoryCacheTTL        string   `mapstructure:"category_cache_ttl" default:"24h"`
+	CacheStaleWindow        string   `mapstructure:"cache_stale_window" default:"60s"`
+	RateLimit               int      `mapstructure:"rate_limit" default:"100"` // calls per minute per provider
+	BreakerFailureThreshold int      `mapstructure:"breaker_failure_threshold" default:"5"`
+	BreakerOpenTimeout      string   `mapstructure:"breaker_open_timeout" default:"30s"`
+}
+
+// DownloadsConfig contains background download worker configuration
+type DownloadsConfig struct {
+	StorageDir       string `mapstructure:"storage_dir" default:"./data"`
+	Workers          int    `mapstructure:"workers" default:"2"` // 0 disables the worker
+	PollInterval     string `mapstructure:"poll_interval" default:"5s"`
+	Lease            string `mapstructure:"lease" default:"2m"`
+	ProgressInterval string `mapstructure:"progress_interval" default:"2s"`
+	FetchTimeout     string `mapstructure:"fetch_timeout" default:"5m"`
+	MaxAttempts      int    `mapstructure:"max_attempts" default:"5"`
+	RetryBackoff     string `mapstructure:"retry_backoff" default:"30s"`
+	MaxFileSize      int64  `mapstructure:"max_file_size" default:"52428800"` // bytes
+	URLSigningSecret string `mapstructure:"url_signing_secret"`               // required in production; elsewhere derived from the JWT refresh secret
+	SignedURLTTL     string `mapstructure:"signed_url_ttl" default:"15m"`
+}
+
+// JobsConfig contains scheduled maintenance job configuration. Schedules are five-field
+// cron expressions, @hourly/@daily/@weekly/@monthly or "@every <duration>".
+type JobsConfig struct {
+	Enabled                bool   `mapstructure:"enabled" default:"true"`
+	TokenCleanupSchedule   string `mapstructure:"token_cleanup_schedule" default:"@hourly"`
+	HistoryPruneSchedule   string `mapstructure:"history_prune_schedule" default:"30 3 * * *"`
+	HistoryRetention       string `mapstructure:"history_retention" default:"4320h"` // 180 days
+	AuthEventPruneSchedule string `mapstructure:"auth_event_prune_schedule" default:"45 3 * * *"`
+	AuthEventRetention     string `mapstructure:"auth_event_retention" default:"2160h"` // 90 days
+	DownloadReapSchedule   string `mapstructure:"download_reap_schedule" default:"*/10 * * * *"`
+	StaleDownloadAfter     string `mapstructure:"stale_download_after" default:"1h"`
+	DataExportSchedule     string `mapstructure:"data_export_schedule" default:"@every 1m"`
+	AccountPurgeSchedule   string `mapstructure:"account_purge_schedule" default:"@hourly"` // also removes expired data exports
+}
+
+// AccountConfig contains account deletion and personal data export configuration
+type AccountConfig struct {
+	DeletionGracePeriod string `mapstructure:"deletion_grace_period" default:"720h"` // how long a deleted account can be restored by signing in
+	ExportTTL           string `mapstructure:"export_ttl" default:"168h"`            // how long a data export can be downloaded
+}
+
+// MailConfig contains outgoing email configuration and the links sent in account emails
+type MailConfig struct {
+	Driver               string `mapstructure:"driver" default:"log"` // "smtp", or "log" to log messages instead of sending them (not in production)
+	From                 string `mapstructure:"from" default:"Music App <no-reply@localhost>"`
+	OutboxDir            string `mapstructure:"outbox_dir"` // log driver: also write messages here as .eml files
+	SMTPHost             string `mapstructure:"smtp_host"`
+	SMTPPort             int    `mapstructure:"smtp_port" default:"587"`
+	SMTPUsername         string `mapstructure:"smtp_username"`
+	SMTPPassword         string `mapstructure:"smtp_password"`
+	PasswordResetURL     string `mapstructure:"password_reset_url" default:"http://localhost:3000/reset-password"`
+	EmailVerificationURL string `mapstructure:"email_verification_url" default:"http://localhost:3000/verify-email"`
+	AccountUnlockURL     string `mapstructure:"account_unlock_url" default:"http://localhost:3000/unlock-account"`
+	PasswordResetTTL     string `mapstructure:"password_reset_ttl" default:"1h"`
+	EmailVerificationTTL string `mapstructure:"email_verification_ttl" default:"48h"`
+}
+
+// ThrottleConfig contains brute-force protection for sign-in, registration and token refresh.
+// Attempts are counted in sliding windows per IP and per account.
+type ThrottleConfig struct {
+	Window           string `mapstructure:"window" default:"15m"`
+	LoginIPLimit     int    `mapstructure:"login_ip_limit" default:"50"` // failed sign-ins per IP per window
+	DelayAfter       int    `mapstructure:"delay_after" default:"3"`     // failed sign-ins per account before delays start
+	BaseDelay        string `mapstructure:"base_delay" default:"1s"`     // doubles with each further failure
+	MaxDelay         string `mapstructure:"max_delay" default:"30s"`
+	LockoutThreshold int    `mapstructure:"lockout_threshold" default:"10"` // failed sign-ins per account per window
+	LockoutDuration  string `mapstructure:"lockout_duration" default:"30m"`
+	RefreshIPLimit   int    `mapstructure:"refresh_ip_limit" default:"30"`  // failed refreshes per IP per window
+	RegisterIPLimit  int    `mapstructure:"register_ip_limit" default:"10"` // registration attempts per IP per register window
+	RegisterWindow   string `mapstructure:"register_window" default:"1h"`
+}
+
+// RateLimitConfig contains API rate limits for each route group. Authenticated requests are
+// limited per user and anonymous requests per client IP.
+type RateLimitConfig struct {
+	Enabled   bool            `mapstructure:"enabled" default:"true"`
+	Auth      RateLimitPolicy `mapstructure:"auth"`
+	Users     RateLimitPolicy `mapstructure:"users"`
+	Music     RateLimitPolicy `mapstructure:"music"`
+	Playlists RateLimitPolicy `mapstructure:"playlists"`
+	Library   RateLimitPolicy `mapstructure:"library"`
+	Admin     RateLimitPolicy `mapstructure:"admin"`
+}
+
+// RateLimitPolicy allows Requests per Period, spread evenly, with bursts of up to Burst
+// requests. Every policy must set both while rate limiting is enabled.
+type RateLimitPolicy struct {
+	Requests int    `mapstructure:"requests"`
+	Period   string `mapstructure:"period"`
+	Burst    int    `mapstructure:"burst"` // defaults to Requests
+}
+
+// MetricsConfig contains Prometheus metrics endpoint configuration. Scrapes can be limited to
+// AllowedNetworks and required to present BearerToken; production requires at least one, and
+// elsewhere the endpoint is open without them.
+type MetricsConfig struct {
+	Enabled         bool     `mapstructure:"enabled" default:"true"`
+	Path            string   `mapstructure:"path" default:"/metrics"`
+	BearerToken     string   `mapstructure:"bearer_token"`
+	AllowedNetworks []string `mapstructure:"allowed_networks"` // IPs or CIDRs of the scrapers' connections, such as 10.0.0.0/8
+}
+
+// TracingConfig contains OpenTelemetry tracing configuration. Spans are exported over
+// OTLP/HTTP to a collector such as the OpenTelemetry Collector, Jaeger or Tempo.
+type TracingConfig struct {
+	Enabled     bool     `mapstructure:"enabled" default:"false"`
+	Endpoint    string   `mapstructure:"endpoint" default:"localhost:4318"` // collector host:port
+	URLPath     string   `mapstructure:"url_path"`                          // defaults to /v1/traces
+	Insecure    bool     `mapstructure:"insecure" default:"false"`          // send spans over plain HTTP
+	Headers     []string `mapstructure:"headers"`                           // key=value pairs, such as a collector API key
+	ServiceName string   `mapstructure:"service_name"`                      // defaults to the app name
+	SampleRatio float64  `mapstructure:"sample_ratio" default:"1"`          // share of new traces recorded; incoming sampling decisions are kept
+}
+
+// HealthConfig contains readiness check configuration
+type HealthConfig struct {
+	CheckTimeout     string `mapstructure:"check_timeout" default:"2s"`       // per check for the database, Redis and migrations
+	ProviderTimeout  string `mapstructure:"provider_timeout" default:"5s"`    // per music provider
+	ProviderCacheTTL string `mapstructure:"provider_cache_ttl" default:"30s"` // how long a provider result is reused between probes
 }
 
 // Load loads configuration from environment variables and config files
@@ -121,12 +285,20 @@ func Load() (*Config, error) {
 
 	viper.BindEnv("auth.jwt_access_secret")
 	viper.BindEnv("auth.jwt_refresh_secret")
+	viper.BindEnv("auth.jwt_previous_access_secrets")
+	viper.BindEnv("auth.jwt_previous_refresh_secrets")
+	viper.BindEnv("auth.jwt_private_key")
+	viper.BindEnv("auth.mfa_encryption_key")
 	viper.BindEnv("database.password")
 	// Also bind secrets for providers if you use them
 	viper.BindEnv("google.client_id")
 	viper.BindEnv("google.client_secret")
 	viper.BindEnv("spotify.client_id")
 	viper.BindEnv("spotify.client_secret")
+	viper.BindEnv("downloads.url_signing_secret")
+	viper.BindEnv("mail.smtp_password")
+	viper.BindEnv("metrics.bearer_token")
+	viper.BindEnv("tracing.headers")
 
 	// Set defaults
 	setDefaults()
@@ -139,6 +311,8 @@ func Load() (*Config, error) {
 		// Config file not found is okay, we can use env vars and defaults
 	}
 
+	bindOIDCEnv()
+
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -152,6 +326,31 @@ func Load() (*Config, error) {
 	return &config, nil
 }
 
+// bindOIDCEnv binds the environment variables of each enabled OIDC provider, such as
+// MUSIC_APP_OIDC_PROVIDERS_APPLE_CLIENT_IDS. Providers are keyed by name, so their variables
+// are only known once the enabled names are.
+func bindOIDCEnv() {
+	for _, name := range OIDCProviderNames(viper.GetStringSlice("oidc.enabled")) {
+		for _, key := range []string{"issuer", "client_ids", "discovery_url", "jwks_url", "require_nonce", "trust_email"} {
+			viper.BindEnv("oidc.providers." + name + "." + key)
+		}
+	}
+}
+
+// OIDCProviderNames normalizes a list of provider names, which may arrive as a single
+// comma separated value from the environment
+func OIDCProviderNames(enabled []string) []string {
+	var names []string
+	for _, entry := range enabled {
+		for _, name := range strings.Split(entry, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 func setDefaults() {
 	// App defaults
 	viper.SetDefault("app.name", "music-app-backend")
@@ -163,6 +362,7 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.idle_timeout", "120s")
+	viper.SetDefault("server.trusted_proxies", []string{})
 
 	// CORS defaults
 	viper.SetDefault("server.cors.allow_origins", []string{"*"})
@@ -180,6 +380,7 @@ func setDefaults() {
 	viper.SetDefault("database.max_open_conns", 25)
 	viper.SetDefault("database.max_idle_conns", 25)
 	viper.SetDefault("database.max_lifetime", "5m")
+	viper.SetDefault("database.auto_migrate", true)
 
 	// Redis defaults
 	viper.SetDefault("redis.addr", "localhost:6379")
@@ -194,28 +395,199 @@ func setDefaults() {
 	viper.SetDefault("auth.password_hash_memory", 65536)
 	viper.SetDefault("auth.password_hash_time", 3)
 	viper.SetDefault("auth.password_hash_threads", 2)
+	viper.SetDefault("auth.mfa_issuer", "Music App")
+	viper.SetDefault("auth.mfa_challenge_ttl", "5m")
+
+	// OIDC defaults
+	viper.SetDefault("oidc.enabled", []string{"google"})
+	viper.SetDefault("oidc.nonce_ttl", "10m")
 
 	// Providers defaults
 	viper.SetDefault("providers.enabled", []string{"itunes"})
 	viper.SetDefault("providers.default_timeout", "30s")
 	viper.SetDefault("providers.cache_ttl", "300s")
+	viper.SetDefault("providers.track_cache_ttl", "1h")
+	viper.SetDefault("providers.category_cache_ttl", "24h")
+	viper.SetDefault("providers.cache_stale_window", "60s")
 	viper.SetDefault("providers.rate_limit", 100)
+	viper.SetDefault("providers.breaker_failure_threshold", 5)
+	viper.SetDefault("providers.breaker_open_timeout", "30s")
+
+	// Downloads defaults
+	viper.SetDefault("downloads.storage_dir", "./data")
+	viper.SetDefault("downloads.workers", 2)
+	viper.SetDefault("downloads.poll_interval", "5s")
+	viper.SetDefault("downloads.lease", "2m")
+	viper.SetDefault("downloads.progress_interval", "2s")
+	viper.SetDefault("downloads.fetch_timeout", "5m")
+	viper.SetDefault("downloads.max_attempts", 5)
+	viper.SetDefault("downloads.retry_backoff", "30s")
+	viper.SetDefault("downloads.max_file_size", 52428800)
+	viper.SetDefault("downloads.signed_url_ttl", "15m")
+
+	// Jobs defaults
+	viper.SetDefault("jobs.enabled", true)
+	viper.SetDefault("jobs.token_cleanup_schedule", "@hourly")
+	viper.SetDefault("jobs.history_prune_schedule", "30 3 * * *")
+	viper.SetDefault("jobs.history_retention", "4320h")
+	viper.SetDefault("jobs.auth_event_prune_schedule", "45 3 * * *")
+	viper.SetDefault("jobs.auth_event_retention", "2160h")
+	viper.SetDefault("jobs.download_reap_schedule", "*/10 * * * *")
+	viper.SetDefault("jobs.stale_download_after", "1h")
+	viper.SetDefault("jobs.data_export_schedule", "@every 1m")
+	viper.SetDefault("jobs.account_purge_schedule", "@hourly")
+
+	// Account defaults
+	viper.SetDefault("account.deletion_grace_period", "720h")
+	viper.SetDefault("account.export_ttl", "168h")
+
+	// Mail defaults
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.from", "Music App <no-reply@localhost>")
+	viper.SetDefault("mail.smtp_port", 587)
+	viper.SetDefault("mail.password_reset_url", "http://localhost:3000/reset-password")
+	viper.SetDefault("mail.email_verification_url", "http://localhost:3000/verify-email")
+	viper.SetDefault("mail.account_unlock_url", "http://localhost:3000/unlock-account")
+	viper.SetDefault("mail.password_reset_ttl", "1h")
+	viper.SetDefault("mail.email_verification_ttl", "48h")
+
+	// Throttle defaults
+	viper.SetDefault("throttle.window", "15m")
+	viper.SetDefault("throttle.login_ip_limit", 50)
+	viper.SetDefault("throttle.delay_after", 3)
+	viper.SetDefault("throttle.base_delay", "1s")
+	viper.SetDefault("throttle.max_delay", "30s")
+	viper.SetDefault("throttle.lockout_threshold", 10)
+	viper.SetDefault("throttle.lockout_duration", "30m")
+	viper.SetDefault("throttle.refresh_ip_limit", 30)
+	viper.SetDefault("throttle.register_ip_limit", 10)
+	viper.SetDefault("throttle.register_window", "1h")
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.enabled", true)
+	setRateLimitDefaults("auth", 20, "1m", 10)
+	setRateLimitDefaults("users", 60, "1m", 20)
+	setRateLimitDefaults("music", 60, "1m", 20)
+	setRateLimitDefaults("playlists", 120, "1m", 40)
+	setRateLimitDefaults("library", 120, "1m", 40)
+	setRateLimitDefaults("admin", 120, "1m", 40)
+
+	// Metrics defaults
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("metrics.allowed_networks", []string{})
+
+	// Tracing defaults
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", false)
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+
+	// Health check defaults
+	viper.SetDefault("health.check_timeout", "2s")
+	viper.SetDefault("health.provider_timeout", "5s")
+	viper.SetDefault("health.provider_cache_ttl", "30s")
+}
+
+func setRateLimitDefaults(group string, requests int, period string, burst int) {
+	viper.SetDefault("rate_limit."+group+".requests", requests)
+	viper.SetDefault("rate_limit."+group+".period", period)
+	viper.SetDefault("rate_limit."+group+".burst", burst)
 }
 
 func validate(config *Config) error {
-	// Validate required auth secrets
-	if config.Auth.JWTAccessSecret == "" {
-		return fmt.Errorf("JWT access secret is required")
+	// HMAC signing methods sign access tokens with a shared secret; asymmetric methods need a
+	// private key instead. Refresh tokens are always signed with the refresh secret.
+	if strings.HasPrefix(config.Auth.JWTSigningMethod, "HS") {
+		if config.Auth.JWTAccessSecret == "" {
+			return fmt.Errorf("JWT access secret is required for the %s signing method", config.Auth.JWTSigningMethod)
+		}
+	} else if config.Auth.JWTPrivateKey == "" && config.Auth.JWTPrivateKeyFile == "" {
+		return fmt.Errorf("a JWT private key is required for the %s signing method", config.Auth.JWTSigningMethod)
 	}
 	if config.Auth.JWTRefreshSecret == "" {
 		return fmt.Errorf("JWT refresh secret is required")
 	}
 
+	// TOTP secrets are encrypted at rest with their own key everywhere but in development
+	if config.App.Environment != "development" && config.Auth.MFAEncryptionKey == "" {
+		return fmt.Errorf("an MFA encryption key is required in the %s environment", config.App.Environment)
+	}
+
+	// Production needs its own download URL signing secret rather than a derived one
+	if config.App.Environment == "production" && config.Downloads.URLSigningSecret == "" {
+		return fmt.Errorf("a download URL signing secret is required in production")
+	}
+
 	// Validate database password
 	if config.Database.Password == "" {
 		return fmt.Errorf("database password is required")
 	}
 
+	// Validate mail driver
+	switch config.Mail.Driver {
+	case "log":
+		// Logged messages carry live reset and verification links
+		if config.App.Environment == "production" {
+			return fmt.Errorf("the log mail driver cannot be used in production")
+		}
+	case "smtp":
+		if config.Mail.SMTPHost == "" {
+			return fmt.Errorf("SMTP host is required when the smtp mail driver is used")
+		}
+	default:
+		return fmt.Errorf("unknown mail driver %q", config.Mail.Driver)
+	}
+
+	// Validate OIDC providers; Google and Apple have well-known issuers
+	for _, name := range OIDCProviderNames(config.OIDC.Enabled) {
+		if len(name) > 32 {
+			return fmt.Errorf("OIDC provider name %q is longer than 32 characters", name)
+		}
+		if name != "google" && name != "apple" && config.OIDC.Providers[name].Issuer == "" {
+			return fmt.Errorf("issuer is required for OIDC provider %s", name)
+		}
+	}
+
+	// Validate metrics endpoint
+	if config.Metrics.Enabled && !strings.HasPrefix(config.Metrics.Path, "/") {
+		return fmt.Errorf("metrics path must start with /")
+	}
+	if config.Metrics.Enabled && config.App.Environment == "production" && config.Metrics.BearerToken == "" && len(config.Metrics.AllowedNetworks) == 0 {
+		return fmt.Errorf("metrics need a bearer token or allowed networks in production, or must be disabled")
+	}
+
+	// Validate rate limits, so a mistyped policy can't leave its route group unlimited
+	if config.RateLimit.Enabled {
+		policies := []struct {
+			group  string
+			policy RateLimitPolicy
+		}{
+			{"auth", config.RateLimit.Auth},
+			{"users", config.RateLimit.Users},
+			{"music", config.RateLimit.Music},
+			{"playlists", config.RateLimit.Playlists},
+			{"library", config.RateLimit.Library},
+			{"admin", config.RateLimit.Admin},
+		}
+		for _, p := range policies {
+			if p.policy.Requests <= 0 {
+				return fmt.Errorf("rate limit requests for %s must be positive", p.group)
+			}
+			if period, err := time.ParseDuration(p.policy.Period); err != nil || period <= 0 {
+				return fmt.Errorf("rate limit period for %s must be a positive duration, got %q", p.group, p.policy.Period)
+			}
+			if p.policy.Burst < 0 {
+				return fmt.Errorf("rate limit burst for %s cannot be negative", p.group)
+			}
+		}
+	}
+
+	// Validate tracing
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+
 	// Validate Google config if Google is enabled
 	for _, provider := range config.Providers.Enabled {
 		if provider == "google" && config.Google.ClientID == "" {
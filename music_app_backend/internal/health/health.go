@@ -0,0 +1,181 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultTimeout bounds checks registered without a timeout of their own
+const defaultTimeout = 2 * time.Second
+
+// Status is the outcome of a readiness run
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusDegraded    Status = "degraded"    // a non-critical check failed
+	StatusUnavailable Status = "unavailable" // a critical check failed
+)
+
+// Check is a single dependency check
+type Check struct {
+	Name string
+	// Critical checks make the service unready when they fail; others only degrade it
+	Critical bool
+	Timeout  time.Duration
+	// CacheFor reuses a result for this long, so frequent probes don't hammer external
+	// services. Zero runs the check every time.
+	CacheFor time.Duration
+	// Run does the check and returns a short description for verbose output, such as a
+	// schema version
+	Run func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name        string
+	Critical    bool
+	Healthy     bool
+	Info        string
+	Error       string
+	Latency     time.Duration
+	CheckedAt   time.Time
+	Cached      bool
+	LastError   string // most recent failure, even if the check has since recovered
+	LastErrorAt *time.Time
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Status Status
+	Checks []Result
+}
+
+// Checker runs registered checks concurrently, each with its own timeout
+type Checker struct {
+	mu      sync.Mutex
+	checks  []*Check
+	results map[string]*Result
+}
+
+// NewChecker creates a checker. Register checks, then call Run.
+func NewChecker() *Checker {
+	return &Checker{results: make(map[string]*Result)}
+}
+
+// Register adds a check. It must be called before Run.
+func (c *Checker) Register(check Check) error {
+	if check.Name == "" || check.Run == nil {
+		return fmt.Errorf("check needs a name and run function")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = defaultTimeout
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.checks {
+		if existing.Name == check.Name {
+			return fmt.Errorf("check %q is already registered", check.Name)
+		}
+	}
+	c.checks = append(c.checks, &check)
+	return nil
+}
+
+// Run runs every check and returns their results in registration order
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]*Check(nil), c.checks...)
+	c.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Healthy {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusUnavailable
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+// run returns a fresh enough cached result for check, or runs it
+func (c *Checker) run(ctx context.Context, check *Check) Result {
+	c.mu.Lock()
+	previous, ok := c.results[check.Name]
+	if ok && check.CacheFor > 0 && time.Since(previous.CheckedAt) < check.CacheFor {
+		cached := *previous
+		c.mu.Unlock()
+		cached.Cached = true
+		return cached
+	}
+	c.mu.Unlock()
+
+	start := time.Now()
+	info, err := runWithTimeout(ctx, check)
+	result := Result{
+		Name:      check.Name,
+		Critical:  check.Critical,
+		Healthy:   err == nil,
+		Info:      info,
+		Latency:   time.Since(start),
+		CheckedAt: start,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if previous, ok := c.results[check.Name]; ok {
+		result.LastError, result.LastErrorAt = previous.LastError, previous.LastErrorAt
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.LastError, result.LastErrorAt = result.Error, &start
+	}
+	c.results[check.Name] = &result
+	return result
+}
+
+// runWithTimeout runs check, giving up once its timeout passes even if it ignores ctx
+func runWithTimeout(ctx context.Context, check *Check) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	type outcome struct {
+		info string
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		info, err := check.Run(ctx)
+		done <- outcome{info, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.info, out.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("check timed out after %s", check.Timeout)
+		}
+		return "", ctx.Err()
+	}
+}
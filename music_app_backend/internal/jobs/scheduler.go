@@ -0,0 +1,236 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/tracing"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const lockKeyPrefix = "jobs:lock:"
+
+// Job is a unit of scheduled maintenance work.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Timeout  time.Duration // upper bound on a single run
+	// Run does the work and returns a short summary for the log, such as how many rows it
+	// touched.
+	Run func(ctx context.Context) (string, error)
+}
+
+// Stats describes a job's schedule and recent runs on this replica.
+type Stats struct {
+	Name         string
+	Running      bool
+	Runs         int64
+	Failures     int64
+	Skipped      int64 // runs taken by another replica
+	LastRunAt    *time.Time
+	LastDuration time.Duration
+	LastResult   string
+	LastError    string
+	NextRunAt    time.Time
+}
+
+// Scheduler runs jobs on their schedules. Every replica runs a scheduler, and for each
+// scheduled run the replicas race for a Redis lock keyed by job and run time, so each run
+// happens on exactly one replica.
+type Scheduler struct {
+	redis  *redis.Client
+	logger logger.Logger
+
+	mu    sync.Mutex
+	jobs  []*Job
+	stats map[string]*Stats
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler. Register jobs, then call Start.
+func NewScheduler(redisClient *redis.Client, logger logger.Logger) *Scheduler {
+	return &Scheduler{
+		redis:  redisClient,
+		logger: logger.With("component", "scheduler"),
+		stats:  make(map[string]*Stats),
+	}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("job needs a name, schedule and run function")
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = 10 * time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.stats[job.Name]; exists {
+		return fmt.Errorf("job %q is already registered", job.Name)
+	}
+	s.jobs = append(s.jobs, &job)
+	s.stats[job.Name] = &Stats{Name: job.Name}
+	return nil
+}
+
+// Start launches a goroutine per job. Jobs run until Stop is called or ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.mu.Lock()
+	jobs := append([]*Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		s.wg.Add(1)
+		go func(job *Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+
+	s.logger.Info("Scheduler started", "jobs", len(jobs))
+}
+
+// Stop cancels running jobs and waits for them to return, or for ctx to expire.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of every job's stats, sorted by name.
+func (s *Scheduler) Stats() []Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]Stats, 0, len(s.stats))
+	for _, st := range s.stats {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// loop waits for each scheduled run of job and executes it if this replica wins the lock.
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			s.logger.Warn("Job has no future runs, stopping", "job", job.Name)
+			return
+		}
+		s.update(job.Name, func(st *Stats) { st.NextRunAt = next })
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.execute(ctx, job, next)
+	}
+}
+
+// execute runs job for the run scheduled at runAt, unless another replica already has.
+func (s *Scheduler) execute(ctx context.Context, job *Job, runAt time.Time) {
+	log := s.logger.With("job", job.Name, "scheduled_at", runAt)
+
+	acquired, err := s.acquire(ctx, job, runAt)
+	if err != nil {
+		log.Error("failed to acquire job lock", "error", err)
+		return
+	}
+	if !acquired {
+		s.update(job.Name, func(st *Stats) { st.Skipped++ })
+		log.Debug("Job run taken by another replica")
+		return
+	}
+
+	start := time.Now()
+	s.update(job.Name, func(st *Stats) {
+		st.Running = true
+		st.LastRunAt = &start
+	})
+
+	runCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	runCtx, span := tracing.Tracer().Start(runCtx, "job "+job.Name, trace.WithNewRoot())
+	result, err := s.run(runCtx, job)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	cancel()
+	duration := time.Since(start)
+
+	s.update(job.Name, func(st *Stats) {
+		st.Running = false
+		st.Runs++
+		st.LastDuration = duration
+		st.LastResult = result
+		st.LastError = ""
+		if err != nil {
+			st.Failures++
+			st.LastError = err.Error()
+		}
+	})
+
+	if err != nil {
+		log.Error("Job failed", "error", err, "duration", duration.String())
+		return
+	}
+	log.Info("Job completed", "result", result, "duration", duration.String())
+}
+
+// run calls the job, converting a panic into an error so one bad job cannot stop the others.
+func (s *Scheduler) run(ctx context.Context, job *Job) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// acquire claims the run of job scheduled at runAt. The lock is never released early:
+// it expires after the job's timeout plus a margin for clock skew, so a replica whose clock
+// lags cannot repeat a run that has already finished.
+func (s *Scheduler) acquire(ctx context.Context, job *Job, runAt time.Time) (bool, error) {
+	key := fmt.Sprintf("%s%s:%d", lockKeyPrefix, job.Name, runAt.Unix())
+	return s.redis.SetNX(ctx, key, time.Now().UTC().Format(time.RFC3339), job.Timeout+time.Minute).Result()
+}
+
+func (s *Scheduler) update(name string, fn func(st *Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.stats[name])
+}
@@ -17,6 +17,34 @@ const (
 	StatePaused      DownloadState = "paused"
 )
 
+// downloadTransitions lists the states users may move a download to. Downloads only start,
+// complete and fail through the download worker.
+var downloadTransitions = map[DownloadState][]DownloadState{
+	StatePending:     {StatePaused},
+	StateDownloading: {StatePaused},
+	StatePaused:      {StatePending}, // resume
+	StateFailed:      {StatePending}, // retry
+}
+
+// IsValid reports whether s is a known download state.
+func (s DownloadState) IsValid() bool {
+	switch s {
+	case StatePending, StateDownloading, StateCompleted, StateFailed, StatePaused:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a download in state s may move to next.
+func (s DownloadState) CanTransitionTo(next DownloadState) bool {
+	for _, allowed := range downloadTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 // Favorite represents a user's favorite track.
 type Favorite struct {
 	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
@@ -42,7 +70,7 @@ type History struct {
 	Album           string    `gorm:"size:255"`
 	DurationMs      int
 	ArtworkURL      string `gorm:"size:1024"`
-	PlayedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	PlayedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP;index"`
 }
 
 // Download represents a user's downloaded track.
@@ -56,10 +84,16 @@ type Download struct {
 	Album           string        `gorm:"size:255"`
 	DurationMs      int
 	ArtworkURL      string        `gorm:"size:1024"`
-	State           DownloadState `gorm:"not null;size:20"`
+	State           DownloadState `gorm:"not null;size:20;index"`
 	LocalPath       *string       `gorm:"size:1024"`
 	FileSize        *int64
 	Quality         string `gorm:"not null;size:50"` // Changed to string
+	Progress        int           `gorm:"not null;default:0"` // percent complete, 0-100
+	FailureReason   *string       `gorm:"size:1024"`
+	RetryCount      int           `gorm:"not null;default:0"`
+	Attempts        int           `gorm:"not null;default:0"` // worker attempts since the last user retry
+	NextAttemptAt   *time.Time    // pending downloads are not claimed before this time
+	LeaseExpiresAt  *time.Time    // a downloading row whose lease has lapsed may be reclaimed
 	CreatedAt       time.Time     `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt       time.Time     `gorm:"default:CURRENT_TIMESTAMP"`
 }
@@ -2,8 +2,11 @@ package library
 
 import (
 	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository provides access to the library storage.
@@ -81,6 +84,25 @@ func (r *Repository) GetHistory(ctx context.Context, userID uuid.UUID, page, siz
 	return history, total, err
 }
 
+// PruneHistory deletes history entries played before cutoff, batchSize rows at a time so
+// that no single statement holds locks for long, and returns how many were deleted.
+func (r *Repository) PruneHistory(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	var deleted int64
+	for {
+		result := r.db.WithContext(ctx).Exec(
+			`DELETE FROM histories WHERE id IN (SELECT id FROM histories WHERE played_at < ? LIMIT ?)`,
+			cutoff, batchSize,
+		)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return deleted, nil
+		}
+	}
+}
+
 // --- Downloads ---
 
 // AddDownload adds a track to the user's download list.
@@ -115,12 +137,112 @@ func (r *Repository) GetDownloadByID(ctx context.Context, userID, downloadID uui
 	return &download, err
 }
 
+// FindDownloadByID retrieves a download by its ID regardless of owner.
+func (r *Repository) FindDownloadByID(ctx context.Context, downloadID uuid.UUID) (*Download, error) {
+	var download Download
+	err := r.db.WithContext(ctx).Where("id = ?", downloadID).First(&download).Error
+	return &download, err
+}
+
 // UpdateDownload updates a download's information (e.g., state, path, size).
 func (r *Repository) UpdateDownload(ctx context.Context, download *Download) error {
 	return r.db.WithContext(ctx).Save(download).Error
 }
 
+// UpdateDownloadState applies updates to a download only while it is still in the expected
+// state, so concurrent transitions cannot overwrite each other.
+func (r *Repository) UpdateDownloadState(ctx context.Context, downloadID uuid.UUID, from DownloadState, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&Download{}).
+		Where("id = ? AND state = ?", downloadID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidStateTransition
+	}
+	return nil
+}
+
+// ClaimDownload locks the next download ready for processing, marks it downloading and
+// leases it to the caller until the lease expires. Rows locked by other workers are
+// skipped. It returns gorm.ErrRecordNotFound when nothing is ready.
+func (r *Repository) ClaimDownload(ctx context.Context, lease time.Duration) (*Download, error) {
+	var download Download
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(state = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (state = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?))",
+				StatePending, now, StateDownloading, now).
+			Order("created_at ASC").
+			Take(&download).Error
+		if err != nil {
+			return err
+		}
+
+		leaseExpiresAt := now.Add(lease)
+		err = tx.Model(&download).UpdateColumns(map[string]interface{}{
+			"state":            StateDownloading,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"next_attempt_at":  nil,
+			"lease_expires_at": leaseExpiresAt,
+			"updated_at":       now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		download.State = StateDownloading
+		download.Attempts++
+		download.NextAttemptAt = nil
+		download.LeaseExpiresAt = &leaseExpiresAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &download, nil
+}
+
+// UpdateClaimedDownload applies updates to a download claimed by ClaimDownload. The update
+// only applies while the row is still downloading under the same attempt, so a worker whose
+// claim was paused, removed or reclaimed gets ErrInvalidStateTransition.
+func (r *Repository) UpdateClaimedDownload(ctx context.Context, download *Download, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&Download{}).
+		Where("id = ? AND state = ? AND attempts = ?", download.ID, StateDownloading, download.Attempts).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidStateTransition
+	}
+	return nil
+}
+
+// ReapStaleDownloads reclaims downloads whose worker lease expired before cutoff without
+// any worker claiming them again, usually because workers crashed mid-download. It marks
+// them failed and returns how many were reaped.
+func (r *Repository) ReapStaleDownloads(ctx context.Context, cutoff time.Time, reason string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&Download{}).
+		Where("state = ? AND updated_at < ?", StateDownloading, cutoff).
+		Where("lease_expires_at IS NULL OR lease_expires_at < ?", cutoff).
+		Updates(map[string]interface{}{
+			"state":            StateFailed,
+			"failure_reason":   reason,
+			"lease_expires_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
 // RemoveDownload removes a download entry.
 func (r *Repository) RemoveDownload(ctx context.Context, userID, downloadID uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, downloadID).Delete(&Download{}).Error
+	result := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, downloadID).Delete(&Download{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
\ No newline at end of file
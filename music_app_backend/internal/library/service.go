@@ -7,25 +7,34 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/blob"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 	"gorm.io/gorm"
 )
 
 var (
-	ErrFavoriteExists   = errors.New("track is already in favorites")
-	ErrFavoriteNotFound = errors.New("favorite not found")
-	ErrDownloadNotFound = errors.New("download not found")
+	ErrFavoriteExists    = errors.New("track is already in favorites")
+	ErrFavoriteNotFound  = errors.New("favorite not found")
+	ErrDownloadNotFound  = errors.New("download not found")
+	ErrInvalidDownloadID = errors.New("invalid download ID format")
+
+	ErrInvalidStateTransition = errors.New("download cannot move to the requested state")
+	ErrDownloadNotReady       = errors.New("download has not completed")
+	ErrInvalidFileURL         = errors.New("file URL is invalid or has expired")
 )
 
 // Service provides library business logic.
 type Service struct {
 	repo   *Repository
+	blobs  blob.Store
+	signer *blob.URLSigner
 	logger logger.Logger
 }
 
-// NewService creates a new library service.
-func NewService(repo *Repository, logger logger.Logger) *Service {
-	return &Service{repo: repo, logger: logger}
+// NewService creates a new library service. blobs holds the files of completed downloads
+// and signer issues the signed URLs used to stream them without a bearer token.
+func NewService(repo *Repository, blobs blob.Store, signer *blob.URLSigner, logger logger.Logger) *Service {
+	return &Service{repo: repo, blobs: blobs, signer: signer, logger: logger}
 }
 
 // --- Favorites ---
@@ -42,7 +51,7 @@ func (s *Service) AddFavorite(ctx context.Context, userIDStr string, data TrackD
 		return nil, ErrFavoriteExists
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		s.logger.Error("failed to check for existing favorite", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to check for existing favorite", "error", err, "userID", userID)
 		return nil, err
 	}
 
@@ -60,7 +69,7 @@ func (s *Service) AddFavorite(ctx context.Context, userIDStr string, data TrackD
 	}
 
 	if err := s.repo.AddFavorite(ctx, favorite); err != nil {
-		s.logger.Error("failed to add favorite", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to add favorite", "error", err, "userID", userID)
 		return nil, err
 	}
 
@@ -83,7 +92,7 @@ func (s *Service) GetFavorites(ctx context.Context, userIDStr string, page, size
 
 	favorites, total, err := s.repo.GetFavorites(ctx, userID, page, size)
 	if err != nil {
-		s.logger.Error("failed to get favorites", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to get favorites", "error", err, "userID", userID)
 		return nil, 0, err
 	}
 
@@ -105,7 +114,7 @@ func (s *Service) RemoveFavorite(ctx context.Context, userIDStr, favoriteIDStr s
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrFavoriteNotFound
 		}
-		s.logger.Error("failed to remove favorite", "error", err, "userID", userID, "favoriteID", favoriteID)
+		s.logger.WithContext(ctx).Error("failed to remove favorite", "error", err, "userID", userID, "favoriteID", favoriteID)
 		return err
 	}
 
@@ -135,7 +144,7 @@ func (s *Service) AddHistory(ctx context.Context, userIDStr string, data TrackDa
 	}
 
 	if err := s.repo.AddHistory(ctx, history); err != nil {
-		s.logger.Error("failed to add history", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to add history", "error", err, "userID", userID)
 		return nil, err
 	}
 
@@ -158,7 +167,7 @@ func (s *Service) GetUserHistory(ctx context.Context, userIDStr string, page, si
 
 	history, total, err := s.repo.GetHistory(ctx, userID, page, size)
 	if err != nil {
-		s.logger.Error("failed to get user history", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to get user history", "error", err, "userID", userID)
 		return nil, 0, err
 	}
 
@@ -189,7 +198,7 @@ func (s *Service) AddDownload(ctx context.Context, userIDStr string, data TrackD
 	}
 
 	if err := s.repo.AddDownload(ctx, download); err != nil {
-		s.logger.Error("failed to add download", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to add download", "error", err, "userID", userID)
 		return nil, err
 	}
 
@@ -212,31 +221,218 @@ func (s *Service) GetUserDownloads(ctx context.Context, userIDStr string, page,
 
 	downloads, total, err := s.repo.GetDownloads(ctx, userID, page, size, state)
 	if err != nil {
-		s.logger.Error("failed to get user downloads", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to get user downloads", "error", err, "userID", userID)
 		return nil, 0, err
 	}
 
 	return downloads, total, nil
 }
 
-// RemoveDownload removes a download entry.
+// RemoveDownload removes a download entry along with its downloaded file.
 func (s *Service) RemoveDownload(ctx context.Context, userIDStr, downloadIDStr string) error {
+	download, err := s.GetDownload(ctx, userIDStr, downloadIDStr)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveDownload(ctx, download.UserID, download.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDownloadNotFound
+		}
+		s.logger.WithContext(ctx).Error("failed to remove download", "error", err, "userID", download.UserID, "downloadID", download.ID)
+		return err
+	}
+
+	if download.LocalPath != nil {
+		if err := s.blobs.Delete(ctx, *download.LocalPath); err != nil {
+			// The row is already gone, so an orphaned file is only logged
+			s.logger.WithContext(ctx).Warn("failed to delete download file", "error", err, "downloadID", download.ID, "path", *download.LocalPath)
+		}
+	}
+
+	return nil
+}
+
+// GetDownload retrieves a single download owned by the user.
+func (s *Service) GetDownload(ctx context.Context, userIDStr, downloadIDStr string) (*Download, error) {
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		return errors.New("invalid user ID format")
+		return nil, errors.New("invalid user ID format")
 	}
 	downloadID, err := uuid.Parse(downloadIDStr)
 	if err != nil {
-		return errors.New("invalid download ID format")
+		return nil, ErrInvalidDownloadID
 	}
 
-	if err := s.repo.RemoveDownload(ctx, userID, downloadID); err != nil {
+	download, err := s.repo.GetDownloadByID(ctx, userID, downloadID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrDownloadNotFound
+			return nil, ErrDownloadNotFound
 		}
-		s.logger.Error("failed to remove download", "error", err, "userID", userID, "downloadID", downloadID)
-		return err
+		s.logger.WithContext(ctx).Error("failed to get download", "error", err, "userID", userID, "downloadID", downloadID)
+		return nil, err
 	}
 
-	return nil
+	return download, nil
+}
+
+// OpenDownloadFile opens the file of a completed download owned by the user. The caller
+// must close the returned object.
+func (s *Service) OpenDownloadFile(ctx context.Context, userIDStr, downloadIDStr string) (*Download, *blob.Object, error) {
+	download, err := s.GetDownload(ctx, userIDStr, downloadIDStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.openFile(ctx, download)
+}
+
+// SignDownloadFileURL issues a signature granting temporary access to a completed download's
+// file. It returns the Unix expiry time and the signature.
+func (s *Service) SignDownloadFileURL(ctx context.Context, userIDStr, downloadIDStr string) (int64, string, error) {
+	download, err := s.GetDownload(ctx, userIDStr, downloadIDStr)
+	if err != nil {
+		return 0, "", err
+	}
+	if download.State != StateCompleted {
+		return 0, "", ErrDownloadNotReady
+	}
+
+	expires, signature := s.signer.Sign(fileURLResource(download))
+	return expires, signature, nil
+}
+
+// OpenSignedDownloadFile opens a download's file using a signature from SignDownloadFileURL
+// in place of the owner's credentials. The caller must close the returned object.
+func (s *Service) OpenSignedDownloadFile(ctx context.Context, downloadIDStr string, expires int64, signature string) (*Download, *blob.Object, error) {
+	downloadID, err := uuid.Parse(downloadIDStr)
+	if err != nil {
+		return nil, nil, ErrInvalidFileURL
+	}
+
+	download, err := s.repo.FindDownloadByID(ctx, downloadID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidFileURL
+		}
+		s.logger.WithContext(ctx).Error("failed to get download", "error", err, "downloadID", downloadID)
+		return nil, nil, err
+	}
+
+	if err := s.signer.Verify(fileURLResource(download), expires, signature); err != nil {
+		return nil, nil, ErrInvalidFileURL
+	}
+
+	return s.openFile(ctx, download)
+}
+
+// openFile opens the stored file of a completed download.
+func (s *Service) openFile(ctx context.Context, download *Download) (*Download, *blob.Object, error) {
+	if download.State != StateCompleted || download.LocalPath == nil {
+		return nil, nil, ErrDownloadNotReady
+	}
+
+	object, err := s.blobs.Open(ctx, *download.LocalPath)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			s.logger.WithContext(ctx).Warn("download file is missing", "downloadID", download.ID, "path", *download.LocalPath)
+		} else {
+			s.logger.WithContext(ctx).Error("failed to open download file", "error", err, "downloadID", download.ID, "path", *download.LocalPath)
+		}
+		return nil, nil, err
+	}
+
+	return download, object, nil
+}
+
+// fileURLResource identifies a download's file, and its owner, in signed URLs.
+func fileURLResource(download *Download) string {
+	return "downloads/" + download.ID.String() + "/" + download.UserID.String()
+}
+
+// UpdateDownloadState applies a state change the user asked for: pausing a download, resuming
+// a paused one by returning it to pending, or retrying a failed one the same way. Only the
+// download worker starts, completes or fails downloads.
+func (s *Service) UpdateDownloadState(ctx context.Context, userIDStr, downloadIDStr string, state DownloadState) (*Download, error) {
+	download, err := s.GetDownload(ctx, userIDStr, downloadIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	switch {
+	case state == StatePending && download.State == StateFailed:
+		// A retry starts over
+		updates["progress"] = 0
+		updates["failure_reason"] = nil
+		updates["retry_count"] = gorm.Expr("retry_count + 1")
+		updates["attempts"] = 0
+		updates["next_attempt_at"] = nil
+	case state == StatePending:
+		// A resumed download is picked up by the worker right away
+		updates["next_attempt_at"] = nil
+	case state == StatePaused:
+		// Pausing drops the worker's lease; its claim on the download no longer applies
+		updates["lease_expires_at"] = nil
+	default:
+		return nil, ErrInvalidStateTransition
+	}
+
+	return s.transitionDownload(ctx, download, state, updates)
+}
+
+// RetryDownload puts a failed download back into the pending state, resetting its progress.
+func (s *Service) RetryDownload(ctx context.Context, userIDStr, downloadIDStr string) (*Download, error) {
+	download, err := s.GetDownload(ctx, userIDStr, downloadIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if download.State != StateFailed {
+		return nil, ErrInvalidStateTransition
+	}
+	return s.UpdateDownloadState(ctx, userIDStr, downloadIDStr, StatePending)
+}
+
+// transitionDownload validates and persists a state change, applying any extra column updates.
+func (s *Service) transitionDownload(ctx context.Context, download *Download, to DownloadState, updates map[string]interface{}) (*Download, error) {
+	if !download.State.CanTransitionTo(to) {
+		return nil, ErrInvalidStateTransition
+	}
+
+	updates["state"] = to
+	if err := s.repo.UpdateDownloadState(ctx, download.ID, download.State, updates); err != nil {
+		if !errors.Is(err, ErrInvalidStateTransition) {
+			s.logger.WithContext(ctx).Error("failed to update download state", "error", err, "downloadID", download.ID, "from", download.State, "to", to)
+		}
+		return nil, err
+	}
+
+	return s.repo.GetDownloadByID(ctx, download.UserID, download.ID)
+}
+
+// --- Maintenance ---
+
+// historyPruneBatchSize bounds how many history rows a single delete statement removes.
+const historyPruneBatchSize = 5000
+
+// PruneHistory deletes listening history older than retention and returns how many entries
+// were removed.
+func (s *Service) PruneHistory(ctx context.Context, retention time.Duration) (int64, error) {
+	deleted, err := s.repo.PruneHistory(ctx, time.Now().Add(-retention), historyPruneBatchSize)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("failed to prune history", "error", err, "deleted", deleted)
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+// ReapStaleDownloads fails downloads that have made no progress for staleAfter, so they can
+// be retried, and returns how many were reaped.
+func (s *Service) ReapStaleDownloads(ctx context.Context, staleAfter time.Duration) (int64, error) {
+	reaped, err := s.repo.ReapStaleDownloads(ctx, time.Now().Add(-staleAfter), "download stalled without progress")
+	if err != nil {
+		s.logger.WithContext(ctx).Error("failed to reap stale downloads", "error", err)
+		return 0, err
+	}
+	return reaped, nil
 }
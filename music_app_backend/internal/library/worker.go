@@ -0,0 +1,379 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/blob"
+	"github.com/mosesmmoisebidth/music_backend/internal/music"
+	"github.com/mosesmmoisebidth/music_backend/internal/tracing"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// maxRetryBackoff caps the delay between automatic retries of a failed download.
+const maxRetryBackoff = time.Hour
+
+var (
+	errNoPreview    = errors.New("track has no preview audio")
+	errFileTooLarge = errors.New("audio file exceeds the maximum download size")
+)
+
+// TrackFetcher looks up a provider's track. *music.MusicService satisfies it.
+type TrackFetcher interface {
+	GetTrack(ctx context.Context, provider, trackID string) (*music.Track, error)
+}
+
+// WorkerConfig controls the download worker pool.
+type WorkerConfig struct {
+	Workers          int           // number of concurrent downloads
+	PollInterval     time.Duration // how long an idle worker waits before looking for work again
+	Lease            time.Duration // how long a claim lasts without progress before it may be reclaimed
+	ProgressInterval time.Duration // minimum time between progress writes
+	FetchTimeout     time.Duration // upper bound on a single fetch
+	MaxAttempts      int           // attempts before a download is marked failed
+	RetryBackoff     time.Duration // delay before the first automatic retry, doubled per attempt
+	MaxFileSize      int64         // largest file accepted, in bytes
+}
+
+// DownloadWorker processes pending downloads in the background. Each worker claims one
+// download at a time, fetches the track's preview audio from its provider and streams
+// it into the blob store.
+type DownloadWorker struct {
+	repo   *Repository
+	tracks TrackFetcher
+	blobs  blob.Store
+	client *http.Client
+	config WorkerConfig
+	logger logger.Logger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDownloadWorker creates a download worker pool. Call Start to begin processing.
+func NewDownloadWorker(repo *Repository, tracks TrackFetcher, blobs blob.Store, config WorkerConfig, logger logger.Logger) *DownloadWorker {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+
+	return &DownloadWorker{
+		repo:   repo,
+		tracks: tracks,
+		blobs:  blobs,
+		client: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		config: config,
+		logger: logger.With("component", "download_worker"),
+	}
+}
+
+// Start launches the worker pool. Workers run until Stop is called or ctx is cancelled.
+func (w *DownloadWorker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < w.config.Workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.run(ctx)
+		}()
+	}
+
+	w.logger.Info("Download worker started", "workers", w.config.Workers)
+}
+
+// Stop cancels in-flight downloads and waits for every worker to exit, or for ctx to expire.
+// Cancelled downloads are released back to pending so they are picked up again later.
+func (w *DownloadWorker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.logger.Info("Download worker stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run claims and processes downloads until ctx is cancelled, sleeping when there is no work.
+func (w *DownloadWorker) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		download, err := w.repo.ClaimDownload(ctx, w.config.Lease)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) && ctx.Err() == nil {
+				w.logger.Error("failed to claim download", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.config.PollInterval):
+			}
+			continue
+		}
+
+		w.process(ctx, download)
+	}
+}
+
+// process fetches a claimed download and records the outcome.
+func (w *DownloadWorker) process(ctx context.Context, download *Download) {
+	ctx, span := tracing.Tracer().Start(ctx, "download", trace.WithNewRoot(), trace.WithAttributes(
+		attribute.String("download.id", download.ID.String()),
+		attribute.Int("download.attempt", download.Attempts),
+	))
+	defer span.End()
+	log := w.logger.WithContext(ctx).With("downloadID", download.ID, "attempt", download.Attempts)
+
+	key, size, err := w.fetch(ctx, download)
+	if err == nil {
+		err = w.complete(download, key, size)
+		if err == nil {
+			log.Info("Download completed", "size", size)
+			return
+		}
+		// The claim was lost before completion, so the file is no longer wanted
+		w.deleteBlob(download, key)
+	}
+
+	switch {
+	case errors.Is(err, ErrInvalidStateTransition):
+		log.Info("Download claim lost, abandoning", "reason", "paused, removed or reclaimed")
+	case ctx.Err() != nil:
+		w.release(download)
+		log.Info("Download interrupted by shutdown, released")
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.fail(download, err)
+	}
+}
+
+// fetch resolves the track's preview URL and streams it into the blob store.
+func (w *DownloadWorker) fetch(ctx context.Context, download *Download) (string, int64, error) {
+	track, err := w.tracks.GetTrack(ctx, download.Provider, download.ProviderTrackID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to look up track: %w", err)
+	}
+	if track.PreviewURL == "" {
+		return "", 0, errNoPreview
+	}
+
+	fetchCtx := ctx
+	if w.config.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, w.config.FetchTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(fetchCtx, http.MethodGet, track.PreviewURL, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid preview URL: %w", err)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to fetch preview: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("preview fetch returned status %d", resp.StatusCode)
+	}
+	if w.config.MaxFileSize > 0 && resp.ContentLength > w.config.MaxFileSize {
+		return "", 0, errFileTooLarge
+	}
+
+	key := downloadKey(download, track.PreviewURL)
+	body := &progressReader{
+		r:        resp.Body,
+		total:    resp.ContentLength,
+		limit:    w.config.MaxFileSize,
+		interval: w.config.ProgressInterval,
+		report: func(progress int) error {
+			return w.repo.UpdateClaimedDownload(ctx, download, map[string]interface{}{
+				"progress":         progress,
+				"lease_expires_at": time.Now().Add(w.config.Lease),
+			})
+		},
+	}
+
+	size, err := w.blobs.Put(fetchCtx, key, body)
+	if err != nil {
+		w.deleteBlob(download, key)
+		return "", 0, err
+	}
+
+	return key, size, nil
+}
+
+// complete marks a download finished and records where its file is stored.
+func (w *DownloadWorker) complete(download *Download, key string, size int64) error {
+	ctx, cancel := w.finishContext()
+	defer cancel()
+
+	return w.repo.UpdateClaimedDownload(ctx, download, map[string]interface{}{
+		"state":            StateCompleted,
+		"progress":         100,
+		"local_path":       key,
+		"file_size":        size,
+		"failure_reason":   nil,
+		"lease_expires_at": nil,
+	})
+}
+
+// fail schedules another attempt with exponential backoff, or marks the download failed
+// once its attempts are used up or the error cannot be fixed by retrying.
+func (w *DownloadWorker) fail(download *Download, cause error) {
+	ctx, cancel := w.finishContext()
+	defer cancel()
+
+	log := w.logger.With("downloadID", download.ID, "attempt", download.Attempts)
+	reason := cause.Error()
+
+	permanent := errors.Is(cause, errNoPreview) || errors.Is(cause, errFileTooLarge)
+	if !permanent && download.Attempts < w.config.MaxAttempts {
+		delay := retryBackoff(w.config.RetryBackoff, download.Attempts)
+		err := w.repo.UpdateClaimedDownload(ctx, download, map[string]interface{}{
+			"state":            StatePending,
+			"failure_reason":   reason,
+			"next_attempt_at":  time.Now().Add(delay),
+			"lease_expires_at": nil,
+		})
+		if err != nil {
+			if !errors.Is(err, ErrInvalidStateTransition) {
+				log.Error("failed to schedule download retry", "error", err)
+			}
+			return
+		}
+		log.Warn("Download attempt failed, retrying", "error", cause, "retryIn", delay)
+		return
+	}
+
+	err := w.repo.UpdateClaimedDownload(ctx, download, map[string]interface{}{
+		"state":            StateFailed,
+		"failure_reason":   reason,
+		"lease_expires_at": nil,
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInvalidStateTransition) {
+			log.Error("failed to mark download failed", "error", err)
+		}
+		return
+	}
+	log.Warn("Download failed", "error", cause)
+}
+
+// release returns an interrupted download to pending without counting the attempt.
+func (w *DownloadWorker) release(download *Download) {
+	ctx, cancel := w.finishContext()
+	defer cancel()
+
+	err := w.repo.UpdateClaimedDownload(ctx, download, map[string]interface{}{
+		"state":            StatePending,
+		"attempts":         download.Attempts - 1,
+		"lease_expires_at": nil,
+	})
+	if err != nil && !errors.Is(err, ErrInvalidStateTransition) {
+		w.logger.WithContext(ctx).Error("failed to release download", "error", err, "downloadID", download.ID)
+	}
+}
+
+// deleteBlob removes a file that will not be recorded against its download.
+func (w *DownloadWorker) deleteBlob(download *Download, key string) {
+	ctx, cancel := w.finishContext()
+	defer cancel()
+
+	if err := w.blobs.Delete(ctx, key); err != nil {
+		w.logger.WithContext(ctx).Warn("failed to delete download file", "error", err, "downloadID", download.ID, "path", key)
+	}
+}
+
+// finishContext returns a context for recording an outcome, which must succeed even
+// while the worker is shutting down.
+func (w *DownloadWorker) finishContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+// downloadKey builds the blob key for a download, keeping the preview's file extension.
+func downloadKey(download *Download, previewURL string) string {
+	ext := ".m4a"
+	if u, err := url.Parse(previewURL); err == nil && path.Ext(u.Path) != "" {
+		ext = path.Ext(u.Path)
+	}
+	return fmt.Sprintf("downloads/%s/%s%s", download.UserID, download.ID, ext)
+}
+
+// retryBackoff returns base doubled for every attempt after the first, capped at maxRetryBackoff.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// progressReader reports download progress at most once per interval and enforces a size limit.
+// A failed report aborts the read, which is how a worker notices it lost its claim.
+type progressReader struct {
+	r          io.Reader
+	total      int64 // expected size, or -1 when unknown
+	limit      int64
+	read       int64
+	interval   time.Duration
+	lastReport time.Time
+	report     func(progress int) error
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+
+	if p.limit > 0 && p.read > p.limit {
+		return n, errFileTooLarge
+	}
+
+	if time.Since(p.lastReport) >= p.interval {
+		p.lastReport = time.Now()
+
+		// Progress stays below 100 until the download is recorded as complete
+		progress := 0
+		if p.total > 0 {
+			progress = int(p.read * 99 / p.total)
+		}
+		if reportErr := p.report(progress); reportErr != nil {
+			return n, reportErr
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+)
+
+// LogMailer is a stand-in for development. It logs each message, including its body, and
+// when dir is set also writes it there as an .eml file that mail clients can open. Message
+// bodies carry live reset and verification links, so it must not be used in production.
+type LogMailer struct {
+	from   string
+	dir    string
+	logger logger.Logger
+}
+
+// NewLogMailer creates a log mailer. dir may be empty to only log messages.
+func NewLogMailer(from, dir string, logger logger.Logger) (*LogMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create mail outbox directory: %w", err)
+		}
+	}
+	return &LogMailer{from: from, dir: dir, logger: logger.With("component", "mailer")}, nil
+}
+
+// Send logs msg and writes it to the outbox directory, if one is configured.
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	data, err := render(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	fields := []interface{}{"to", msg.To, "subject", msg.Subject, "body", msg.Body}
+	if m.dir != "" {
+		name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString()[:8])
+		path := filepath.Join(m.dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write message: %w", err)
+		}
+		fields = append(fields, "file", path)
+	}
+
+	m.logger.Info("Email sent (log mailer)", fields...)
+	return nil
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	gormStartKey   = "metrics:start"
+	gormPluginName = "metrics"
+)
+
+// InstrumentGORM records the duration and failures of every query made through db
+func (m *Metrics) InstrumentGORM(db *gorm.DB) error {
+	return db.Use(&gormPlugin{metrics: m})
+}
+
+// gormPlugin times queries with callbacks around each of GORM's operations
+type gormPlugin struct {
+	metrics *Metrics
+}
+
+// Name returns the plugin name
+func (p *gormPlugin) Name() string {
+	return gormPluginName
+}
+
+// Initialize registers the timing callbacks
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	register := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, r := range register {
+		if err := r.before(gormPluginName+":before_"+r.operation, startQuery); err != nil {
+			return err
+		}
+		if err := r.after(gormPluginName+":after_"+r.operation, p.finishQuery(r.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startQuery notes when a query started
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+// finishQuery returns a callback recording a query of the given operation
+func (p *gormPlugin) finishQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.metrics.dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.metrics.dbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/music"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "music_app"
+
+// Metrics holds the application's Prometheus collectors. It records HTTP requests, music
+// provider calls and cache lookups, and database queries; see InstrumentGORM and
+// RegisterStorage for the latter and for connection pool stats.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	httpInFlight        prometheus.Gauge
+
+	providerCalls        *prometheus.CounterVec
+	providerCallDuration *prometheus.HistogramVec
+	providerErrors       *prometheus.CounterVec
+	cacheLookups         *prometheus.CounterVec
+
+	dbQueryDuration *prometheus.HistogramVec
+	dbQueryErrors   *prometheus.CounterVec
+}
+
+// New creates the application metrics, along with Go runtime and process metrics, in a
+// registry of their own
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency, by method, route template and status code.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests currently being handled.",
+		}),
+
+		providerCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "calls_total",
+			Help:      "Music provider calls, by provider, method and outcome (success, not_found, error, canceled, circuit_open or rate_limited).",
+		}, []string{"provider", "method", "outcome"}),
+		providerCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of music provider calls that reached the provider, by provider and method.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"provider", "method"}),
+		providerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "errors_total",
+			Help:      "Failed music provider calls, by provider, method and error code.",
+		}, []string{"provider", "method", "code"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "provider_cache",
+			Name:      "lookups_total",
+			Help:      "Provider response cache lookups, by provider, method and result (hit, stale or miss).",
+		}, []string{"provider", "method", "result"}),
+
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency, by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		dbQueryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_errors_total",
+			Help:      "Failed database queries, by operation and table. Lookups that found no row are not counted.",
+		}, []string{"operation", "table"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.httpInFlight,
+		m.providerCalls,
+		m.providerCallDuration,
+		m.providerErrors,
+		m.cacheLookups,
+		m.dbQueryDuration,
+		m.dbQueryErrors,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Register adds further collectors to the registry
+func (m *Metrics) Register(collectors ...prometheus.Collector) error {
+	for _, collector := range collectors {
+		if err := m.registry.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- HTTP ---
+
+// RequestStarted counts a request in flight. It returns a function that records the request
+// once it completes.
+func (m *Metrics) RequestStarted() func(method, route string, status int) {
+	start := time.Now()
+	m.httpInFlight.Inc()
+	return func(method, route string, status int) {
+		m.httpInFlight.Dec()
+		code := strconv.Itoa(status)
+		m.httpRequests.WithLabelValues(method, route, code).Inc()
+		m.httpRequestDuration.WithLabelValues(method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// --- Music Providers ---
+
+// ObserveProviderCall records a music provider call. Calls rejected by the provider's
+// circuit breaker or rate limiter are counted but don't affect latency.
+func (m *Metrics) ObserveProviderCall(provider, method string, duration time.Duration, err error) {
+	outcome := providerOutcome(err)
+	m.providerCalls.WithLabelValues(provider, method, outcome).Inc()
+
+	switch outcome {
+	case "circuit_open", "rate_limited":
+	default:
+		m.providerCallDuration.WithLabelValues(provider, method).Observe(duration.Seconds())
+	}
+
+	if err != nil && outcome != "not_found" {
+		code := "UNKNOWN"
+		var providerErr *music.ProviderError
+		if errors.As(err, &providerErr) && providerErr.Code != "" {
+			code = providerErr.Code
+		}
+		m.providerErrors.WithLabelValues(provider, method, code).Inc()
+	}
+}
+
+// ObserveCacheLookup records a provider response cache lookup
+func (m *Metrics) ObserveCacheLookup(provider, method string, result music.CacheResult) {
+	m.cacheLookups.WithLabelValues(provider, method, string(result)).Inc()
+}
+
+// providerOutcome classifies the result of a provider call
+func providerOutcome(err error) string {
+	var providerErr *music.ProviderError
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, music.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, music.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &providerErr) && (providerErr.Code == "NOT_FOUND" || providerErr.Code == "NOT_SUPPORTED"):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mosesmmoisebidth/music_backend/internal/config"
+	"github.com/mosesmmoisebidth/music_backend/internal/metrics"
+	"github.com/mosesmmoisebidth/music_backend/pkg/response"
+)
+
+// Metrics middleware records the count and latency of requests. Requests are labelled with
+// their route template, such as /api/v1/playlists/:playlistId, so IDs in paths don't create
+// a series per resource; requests matching no route share the label "unmatched". A nil
+// metrics disables recording.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	if m == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		done := m.RequestStarted()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		done(c.Request.Method, route, c.Writer.Status())
+	}
+}
+
+// MetricsAccess middleware restricts the metrics endpoint to scrapers from the allowed
+// networks and, when a bearer token is configured, to requests presenting it. Networks are
+// matched against the address of the connection itself, never a forwarded client IP, so a
+// trusted proxy in the allowed networks cannot relay outside requests.
+func MetricsAccess(cfg config.MetricsConfig) (gin.HandlerFunc, error) {
+	networks := make([]*net.IPNet, 0, len(cfg.AllowedNetworks))
+	for _, cidr := range cfg.AllowedNetworks {
+		if !strings.Contains(cidr, "/") {
+			if strings.Contains(cidr, ":") {
+				cidr += "/128"
+			} else {
+				cidr += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics allowed network %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return func(c *gin.Context) {
+		if len(networks) > 0 {
+			ip := net.ParseIP(c.RemoteIP())
+			allowed := false
+			for _, network := range networks {
+				if ip != nil && network.Contains(ip) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				response.Forbidden(c, "FORBIDDEN", "Metrics are not available from this address")
+				c.Abort()
+				return
+			}
+		}
+
+		if cfg.BearerToken != "" {
+			token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.BearerToken)) != 1 {
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				response.Unauthorized(c, "INVALID_TOKEN", "A valid metrics bearer token is required")
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}, nil
+}
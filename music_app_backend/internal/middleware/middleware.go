@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -31,8 +32,9 @@ func Logger(logger logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		requestID := c.GetString("request_id")
+		log := logger.WithContext(c.Request.Context())
 
-		logger.With("request_id", requestID, "method", c.Request.Method, "path", c.Request.URL.Path).
+		log.With("request_id", requestID, "method", c.Request.Method, "path", c.Request.URL.Path).
 			Info("Request started")
 
 		c.Next()
@@ -62,11 +64,11 @@ func Logger(logger logger.Logger) gin.HandlerFunc {
 
 		switch logLevel {
 		case "warn":
-			logger.With(logFields).Warn("Request completed with warning")
+			log.With(logFields).Warn("Request completed with warning")
 		case "error":
-			logger.With(logFields).Error("Request completed with error")
+			log.With(logFields).Error("Request completed with error")
 		default:
-			logger.With(logFields).Info("Request completed")
+			log.With(logFields).Info("Request completed")
 		}
 	}
 }
@@ -77,7 +79,7 @@ func Recovery(logger logger.Logger) gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				requestID := c.GetString("request_id")
-				logger.With("request_id", requestID, "error", err).
+				logger.WithContext(c.Request.Context()).With("request_id", requestID, "error", err).
 					Error("Panic recovered")
 
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -105,8 +107,8 @@ func CORS(config config.CORSConfig) gin.HandlerFunc {
 	})
 }
 
-// JWTAuth middleware validates JWT tokens
-func JWTAuth(jwtService *auth.JWTService) gin.HandlerFunc {
+// JWTAuth middleware validates JWT tokens and rejects tokens whose session has been revoked
+func JWTAuth(jwtService *auth.JWTService, sessions *auth.SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -147,6 +149,28 @@ func JWTAuth(jwtService *auth.JWTService) gin.HandlerFunc {
 			return
 		}
 
+		if err := sessions.Validate(c.Request.Context(), claims); err != nil {
+			if errors.Is(err, auth.ErrSessionRevoked) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": gin.H{
+						"code":    "SESSION_REVOKED",
+						"message": "Session has been revoked, please sign in again",
+					},
+					"data": nil,
+				})
+			} else {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error": gin.H{
+						"code":    "SESSION_CHECK_FAILED",
+						"message": "Unable to verify session",
+					},
+					"data": nil,
+				})
+			}
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID.String())
 		c.Set("user_email", claims.Email)
 		c.Set("user_roles", claims.Roles)
@@ -156,8 +180,22 @@ func JWTAuth(jwtService *auth.JWTService) gin.HandlerFunc {
 	}
 }
 
-// OptionalAuth middleware validates JWT tokens but doesn't require them
-func OptionalAuth(jwtService *auth.JWTService) gin.HandlerFunc {
+// JWTAuthUnlessSigned applies JWTAuth unless the request carries a URL signature, which the
+// handler verifies instead. This lets clients that cannot set headers use signed URLs.
+func JWTAuthUnlessSigned(jwtService *auth.JWTService, sessions *auth.SessionStore) gin.HandlerFunc {
+	jwtAuth := JWTAuth(jwtService, sessions)
+	return func(c *gin.Context) {
+		if c.Query("signature") != "" {
+			c.Next()
+			return
+		}
+		jwtAuth(c)
+	}
+}
+
+// OptionalAuth middleware validates JWT tokens but doesn't require them. Requests with an
+// invalid or revoked token proceed anonymously.
+func OptionalAuth(jwtService *auth.JWTService, sessions *auth.SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -177,6 +215,11 @@ func OptionalAuth(jwtService *auth.JWTService) gin.HandlerFunc {
 			return
 		}
 
+		if err := sessions.Validate(c.Request.Context(), claims); err != nil {
+			c.Next()
+			return
+		}
+
 		c.Set("user_id", claims.UserID.String())
 		c.Set("user_email", claims.Email)
 		c.Set("user_roles", claims.Roles)
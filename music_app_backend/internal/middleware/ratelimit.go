@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"github.com/mosesmmoisebidth/music_backend/pkg/response"
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+// gcraScript implements the generic cell rate algorithm. The key holds the theoretical
+// arrival time (TAT) in milliseconds: the time at which the client's allowance is fully
+// used. A request is allowed if, after adding one emission interval, the TAT is no more than
+// the burst tolerance ahead of now. Time comes from Redis so replicas with skewed clocks agree.
+//
+// It returns whether the request was allowed, how long to wait before retrying, and how long
+// until the allowance is full again, both in milliseconds. Times stay in milliseconds because
+// Lua turns numbers with more than 14 significant digits into imprecise strings.
+var gcraScript = redis.NewScript(`
+local key = KEYS[1]
+local emission = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local tat = tonumber(redis.call('GET', key) or '0')
+if tat < now then
+	tat = now
+end
+local new_tat = tat + emission
+local allow_at = new_tat - tolerance
+if allow_at > now then
+	return {0, allow_at - now, tat - now}
+end
+redis.call('SET', key, new_tat, 'PX', new_tat - now)
+return {1, 0, new_tat - now}
+`)
+
+// RateLimiter enforces request rate limits with the generic cell rate algorithm, which
+// spreads a policy's requests evenly over its period while allowing bursts up to the
+// policy's burst size. State is kept in Redis, so limits hold across replicas.
+type RateLimiter struct {
+	redis  *redis.Client
+	logger logger.Logger
+}
+
+// NewRateLimiter creates a rate limiter
+func NewRateLimiter(client *redis.Client, logger logger.Logger) *RateLimiter {
+	return &RateLimiter{redis: client, logger: logger.With("component", "rate_limiter")}
+}
+
+// rateLimitResult is the outcome of counting a request against a policy
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+	resetAfter time.Duration
+}
+
+// allow counts a request for key, where requests are let through every emission interval
+// on average and up to burst at once
+func (l *RateLimiter) allow(ctx context.Context, key string, interval time.Duration, burst int) (rateLimitResult, error) {
+	emission := interval.Milliseconds()
+	if emission < 1 {
+		emission = 1
+	}
+	tolerance := emission * int64(burst)
+
+	values, err := gcraScript.Run(ctx, l.redis, []string{key}, emission, tolerance).Int64Slice()
+	if err != nil {
+		return rateLimitResult{}, fmt.Errorf("failed to apply rate limit: %w", err)
+	}
+
+	result := rateLimitResult{
+		allowed:    values[0] == 1,
+		retryAfter: time.Duration(values[1]) * time.Millisecond,
+		resetAfter: time.Duration(values[2]) * time.Millisecond,
+	}
+	if result.allowed {
+		result.remaining = int((tolerance - values[2]) / emission)
+	}
+	return result, nil
+}
+
+// RateLimitPolicy allows Requests per Period, spread evenly, with bursts of up to Burst
+// requests
+type RateLimitPolicy struct {
+	Requests int
+	Period   time.Duration
+	Burst    int // defaults to Requests
+}
+
+// RateLimit middleware limits requests under a named policy. Authenticated requests are
+// counted per user and anonymous ones per client IP, so it must run after any authentication
+// middleware; client IPs come from X-Forwarded-For only for trusted proxies. Responses carry
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, all
+// describing the burst allowance and the time it takes to refill, and rejected requests get a
+// 429 with Retry-After. If Redis is unavailable requests are let through. A nil limiter or a
+// policy without requests disables limiting.
+func RateLimit(limiter *RateLimiter, name string, policy RateLimitPolicy) gin.HandlerFunc {
+	if limiter == nil || policy.Requests <= 0 || policy.Period <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	burst := policy.Burst
+	if burst <= 0 {
+		burst = policy.Requests
+	}
+	interval := policy.Period / time.Duration(policy.Requests)
+	window := interval * time.Duration(burst)
+	policyHeader := fmt.Sprintf("%d;w=%d", burst, int64(math.Ceil(window.Seconds())))
+
+	return func(c *gin.Context) {
+		subject := "ip:" + c.ClientIP()
+		if userID, exists := c.Get("user_id"); exists {
+			subject = "user:" + userID.(string)
+		}
+
+		result, err := limiter.allow(c.Request.Context(), rateLimitKeyPrefix+name+":"+subject, interval, burst)
+		if err != nil {
+			limiter.logger.Error("Rate limit check failed, allowing request", "error", err, "policy", name)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		c.Header("RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(result.resetAfter.Seconds())), 10))
+		c.Header("RateLimit-Policy", policyHeader)
+
+		if !result.allowed {
+			response.TooManyRequests(c, "RATE_LIMITED", "Too many requests, please slow down", result.retryAfter)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,8 @@
+-- Extensions are left in place because other schemas may depend on them.
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS downloads;
+DROP TABLE IF EXISTS histories;
+DROP TABLE IF EXISTS favorites;
+DROP TABLE IF EXISTS playlist_tracks;
+DROP TABLE IF EXISTS playlists;
+DROP TABLE IF EXISTS users;
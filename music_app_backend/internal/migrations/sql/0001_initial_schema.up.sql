@@ -0,0 +1,126 @@
+-- Baseline schema. Statements are idempotent so databases previously managed by
+-- GORM AutoMigrate can adopt versioned migrations without changes.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+CREATE TABLE IF NOT EXISTS users (
+    id              uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email           varchar(255),
+    password        varchar(255),
+    display_name    varchar(255),
+    photo_url       varchar(1024),
+    roles           text[],
+    is_active       boolean DEFAULT true,
+    google_id       varchar(255),
+    last_login_at   timestamptz,
+    preferences     jsonb,
+    favorite_genres text[],
+    created_at      timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at      timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id ON users (google_id);
+
+CREATE TABLE IF NOT EXISTS playlists (
+    id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id     uuid NOT NULL,
+    title       varchar(100) NOT NULL,
+    description varchar(500),
+    cover_url   varchar(1024),
+    is_public   boolean DEFAULT false,
+    share_code  varchar(10),
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_playlists_user_id ON playlists (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_playlists_share_code ON playlists (share_code);
+
+CREATE TABLE IF NOT EXISTS playlist_tracks (
+    id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    playlist_id       uuid NOT NULL,
+    provider          varchar(20) NOT NULL,
+    provider_track_id varchar(100) NOT NULL,
+    title             varchar(255) NOT NULL,
+    artist            varchar(255) NOT NULL,
+    album             varchar(255),
+    duration_ms       bigint,
+    artwork_url       varchar(1024),
+    track_number      bigint,
+    position          bigint NOT NULL,
+    added_at          timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_playlist_tracks_playlist_id ON playlist_tracks (playlist_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_playlists_tracks') THEN
+        ALTER TABLE playlist_tracks
+            ADD CONSTRAINT fk_playlists_tracks FOREIGN KEY (playlist_id)
+            REFERENCES playlists (id) ON DELETE CASCADE;
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS favorites (
+    id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id           uuid NOT NULL,
+    provider          varchar(20) NOT NULL,
+    provider_track_id varchar(100) NOT NULL,
+    title             varchar(255) NOT NULL,
+    artist            varchar(255) NOT NULL,
+    album             varchar(255),
+    duration_ms       bigint,
+    artwork_url       varchar(1024),
+    added_at          timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_favorites_user_id ON favorites (user_id);
+
+CREATE TABLE IF NOT EXISTS histories (
+    id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id           uuid NOT NULL,
+    provider          varchar(20) NOT NULL,
+    provider_track_id varchar(100) NOT NULL,
+    title             varchar(255) NOT NULL,
+    artist            varchar(255) NOT NULL,
+    album             varchar(255),
+    duration_ms       bigint,
+    artwork_url       varchar(1024),
+    played_at         timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_histories_user_id ON histories (user_id);
+
+CREATE TABLE IF NOT EXISTS downloads (
+    id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id           uuid NOT NULL,
+    provider          varchar(20) NOT NULL,
+    provider_track_id varchar(100) NOT NULL,
+    title             varchar(255) NOT NULL,
+    artist            varchar(255) NOT NULL,
+    album             varchar(255),
+    duration_ms       bigint,
+    artwork_url       varchar(1024),
+    state             varchar(20) NOT NULL,
+    local_path        varchar(1024),
+    file_size         bigint,
+    quality           varchar(50) NOT NULL,
+    created_at        timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at        timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_downloads_user_id ON downloads (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     uuid NOT NULL,
+    token_id    varchar(255) NOT NULL,
+    issued_at   timestamptz,
+    expires_at  timestamptz,
+    revoked     boolean DEFAULT false,
+    replaced_by varchar(255),
+    user_agent  varchar(500),
+    ip          varchar(45),
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_id ON refresh_tokens (token_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_downloads_state;
+ALTER TABLE downloads
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS retry_count,
+    DROP COLUMN IF EXISTS failure_reason,
+    DROP COLUMN IF EXISTS progress;
+
+ALTER TABLE playlists DROP COLUMN IF EXISTS version;
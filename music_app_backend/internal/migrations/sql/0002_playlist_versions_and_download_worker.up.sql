@@ -0,0 +1,19 @@
+ALTER TABLE playlists ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+-- Compact track positions to 1..n per playlist, as reordering and removal now expect
+UPDATE playlist_tracks pt
+SET position = ranked.rn
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY playlist_id ORDER BY position, added_at, id) AS rn
+    FROM playlist_tracks
+) ranked
+WHERE pt.id = ranked.id AND pt.position <> ranked.rn;
+
+ALTER TABLE downloads
+    ADD COLUMN IF NOT EXISTS progress bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS failure_reason varchar(1024),
+    ADD COLUMN IF NOT EXISTS retry_count bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS attempts bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz,
+    ADD COLUMN IF NOT EXISTS lease_expires_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_downloads_state ON downloads (state);
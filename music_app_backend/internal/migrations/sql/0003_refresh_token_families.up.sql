@@ -0,0 +1,6 @@
+-- Refresh tokens rotated from the same sign-in share a family, so that reuse of a rotated
+-- token can revoke every descendant. Existing tokens each start their own family.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id uuid;
+UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_histories_played_at;
@@ -0,0 +1,2 @@
+-- History retention pruning deletes by played_at
+CREATE INDEX IF NOT EXISTS idx_histories_played_at ON histories (played_at);
@@ -0,0 +1,18 @@
+-- Email verification state. Google accounts were created from verified Google emails.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+UPDATE users SET email_verified_at = created_at
+WHERE google_id IS NOT NULL AND email_verified_at IS NULL;
+
+-- Single-use tokens behind password reset and email verification links. Only a SHA-256
+-- hash of each token is stored.
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    uuid NOT NULL,
+    purpose    varchar(32) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_one_time_tokens_token_hash ON one_time_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user_id ON one_time_tokens (user_id);
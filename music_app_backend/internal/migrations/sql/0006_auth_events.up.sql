@@ -0,0 +1,14 @@
+-- Audit log of security-relevant authentication events such as failed and throttled sign-ins
+CREATE TABLE IF NOT EXISTS auth_events (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    event      varchar(64) NOT NULL,
+    user_id    uuid,
+    email      varchar(255),
+    ip         varchar(45),
+    user_agent varchar(500),
+    detail     varchar(255),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_auth_events_user_id ON auth_events (user_id);
+CREATE INDEX IF NOT EXISTS idx_auth_events_email ON auth_events (email);
+CREATE INDEX IF NOT EXISTS idx_auth_events_created_at ON auth_events (created_at);
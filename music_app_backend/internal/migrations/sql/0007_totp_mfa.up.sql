@@ -0,0 +1,20 @@
+-- TOTP authenticators. Secrets are encrypted with AES-GCM; a factor is only enforced at
+-- sign-in once confirmed_at is set.
+CREATE TABLE IF NOT EXISTS totp_factors (
+    user_id          uuid PRIMARY KEY,
+    secret_encrypted varchar(255) NOT NULL,
+    confirmed_at     timestamptz,
+    last_used_step   bigint NOT NULL DEFAULT 0,
+    created_at       timestamptz,
+    updated_at       timestamptz
+);
+
+-- Single-use recovery codes for users who lose their authenticator, hashed with Argon2
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    uuid NOT NULL,
+    code_hash  varchar(255) NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS google_id varchar(255);
+-- Only one Google account per user fits in users.google_id; keep the earliest linked
+UPDATE users SET google_id = i.subject
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, subject FROM user_identities
+    WHERE provider = 'google'
+    ORDER BY user_id, created_at
+) i
+WHERE users.id = i.user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id ON users (google_id);
+
+DROP TABLE IF EXISTS user_identities;
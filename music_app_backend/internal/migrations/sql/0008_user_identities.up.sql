@@ -0,0 +1,21 @@
+-- External login identities, so a user can sign in with several providers as well as a
+-- password. They replace users.google_id.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      uuid NOT NULL,
+    provider     varchar(32) NOT NULL,
+    subject      varchar(255) NOT NULL,
+    email        varchar(255),
+    last_used_at timestamptz,
+    created_at   timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+INSERT INTO user_identities (user_id, provider, subject, email, last_used_at, created_at)
+SELECT id, 'google', google_id, email, last_login_at, created_at FROM users
+WHERE google_id IS NOT NULL
+ON CONFLICT (provider, subject) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_users_google_id;
+ALTER TABLE users DROP COLUMN IF EXISTS google_id;
@@ -0,0 +1,22 @@
+-- Accounts scheduled for deletion. They can be restored by signing in until the grace
+-- period ends, after which everything the user owns is removed.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Personal data exports, generated in the background and kept until expires_at
+CREATE TABLE IF NOT EXISTS data_exports (
+    id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id          uuid NOT NULL,
+    state            varchar(20) NOT NULL,
+    object_key       varchar(1024),
+    file_size        bigint,
+    failure_reason   varchar(1024),
+    attempts         integer NOT NULL DEFAULT 0,
+    lease_expires_at timestamptz,
+    completed_at     timestamptz,
+    expires_at       timestamptz,
+    created_at       timestamptz,
+    updated_at       timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_state ON data_exports (state);
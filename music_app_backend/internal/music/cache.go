@@ -0,0 +1,235 @@
+package music
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const cacheKeyPrefix = "music:cache:v1"
+
+// cacheEntry is the envelope stored in Redis for every cached provider response
+type cacheEntry struct {
+	Payload    json.RawMessage `json:"payload"`
+	FreshUntil time.Time       `json:"fresh_until"`
+}
+
+// trackPage bundles paginated track results so they can be cached as a single value
+type trackPage struct {
+	Tracks   []Track   `json:"tracks"`
+	PageInfo *PageInfo `json:"page_info"`
+}
+
+// playlistPage bundles paginated playlist results so they can be cached as a single value
+type playlistPage struct {
+	Playlists []PlaylistSummary `json:"playlists"`
+	PageInfo  *PageInfo         `json:"page_info"`
+}
+
+// CachedProvider decorates a MusicProvider with a Redis-backed response cache.
+// Entries are served fresh for their TTL, then served stale for CacheStaleWindow
+// while a single background refresh repopulates them. Concurrent misses for the
+// same key share one upstream call.
+type CachedProvider struct {
+	provider MusicProvider
+	redis    *redis.Client
+	config   *ProviderConfig
+	observer Observer
+	group    singleflight.Group
+}
+
+// NewCachedProvider wraps a provider with a Redis-backed response cache
+func NewCachedProvider(provider MusicProvider, redisClient *redis.Client, config *ProviderConfig) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		redis:    redisClient,
+		config:   config,
+		observer: observerOf(config),
+	}
+}
+
+// GetName returns the name of the wrapped provider
+func (c *CachedProvider) GetName() string {
+	return c.provider.GetName()
+}
+
+// SearchTracks returns cached search results or fetches them from the wrapped provider
+func (c *CachedProvider) SearchTracks(ctx context.Context, query string, page, size int, filters *SearchFilters) ([]Track, *PageInfo, error) {
+	result, err := cachedCall(ctx, c, "search", c.config.CacheTTL, func(ctx context.Context) (trackPage, error) {
+		tracks, pageInfo, err := c.provider.SearchTracks(ctx, query, page, size, filters)
+		return trackPage{Tracks: tracks, PageInfo: pageInfo}, err
+	}, normalizeQuery(query), page, size, filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Tracks, result.PageInfo, nil
+}
+
+// GetTrack returns a cached track or fetches it from the wrapped provider
+func (c *CachedProvider) GetTrack(ctx context.Context, trackID string) (*Track, error) {
+	return cachedCall(ctx, c, "track", c.config.TrackCacheTTL, func(ctx context.Context) (*Track, error) {
+		return c.provider.GetTrack(ctx, trackID)
+	}, trackID)
+}
+
+// GetTopCharts returns cached top charts or fetches them from the wrapped provider
+func (c *CachedProvider) GetTopCharts(ctx context.Context, country string, page, size int) ([]Track, *PageInfo, error) {
+	result, err := cachedCall(ctx, c, "top_charts", c.config.CacheTTL, func(ctx context.Context) (trackPage, error) {
+		tracks, pageInfo, err := c.provider.GetTopCharts(ctx, country, page, size)
+		return trackPage{Tracks: tracks, PageInfo: pageInfo}, err
+	}, strings.ToUpper(country), page, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Tracks, result.PageInfo, nil
+}
+
+// GetCategories returns cached categories or fetches them from the wrapped provider
+func (c *CachedProvider) GetCategories(ctx context.Context) ([]Category, error) {
+	return cachedCall(ctx, c, "categories", c.config.CategoryCacheTTL, func(ctx context.Context) ([]Category, error) {
+		return c.provider.GetCategories(ctx)
+	})
+}
+
+// GetPlaylistsByCategory returns cached category playlists or fetches them from the wrapped provider
+func (c *CachedProvider) GetPlaylistsByCategory(ctx context.Context, categoryID string, page, size int) ([]PlaylistSummary, *PageInfo, error) {
+	result, err := cachedCall(ctx, c, "category_playlists", c.config.CacheTTL, func(ctx context.Context) (playlistPage, error) {
+		playlists, pageInfo, err := c.provider.GetPlaylistsByCategory(ctx, categoryID, page, size)
+		return playlistPage{Playlists: playlists, PageInfo: pageInfo}, err
+	}, categoryID, page, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Playlists, result.PageInfo, nil
+}
+
+// GetPlaylistTracks returns cached playlist tracks or fetches them from the wrapped provider
+func (c *CachedProvider) GetPlaylistTracks(ctx context.Context, playlistID string, page, size int) ([]Track, *PageInfo, error) {
+	result, err := cachedCall(ctx, c, "playlist_tracks", c.config.CacheTTL, func(ctx context.Context) (trackPage, error) {
+		tracks, pageInfo, err := c.provider.GetPlaylistTracks(ctx, playlistID, page, size)
+		return trackPage{Tracks: tracks, PageInfo: pageInfo}, err
+	}, playlistID, page, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Tracks, result.PageInfo, nil
+}
+
+// IsHealthy always checks the wrapped provider directly
+func (c *CachedProvider) IsHealthy(ctx context.Context) error {
+	return c.provider.IsHealthy(ctx)
+}
+
+// cachedCall serves a method result from Redis, falling back to fetch on a miss.
+// A TTL of zero disables caching for the method.
+func cachedCall[T any](ctx context.Context, c *CachedProvider, method string, ttl time.Duration, fetch func(context.Context) (T, error), args ...interface{}) (T, error) {
+	if ttl <= 0 || c.redis == nil {
+		return fetch(ctx)
+	}
+
+	key := c.cacheKey(method, args...)
+	load := func(ctx context.Context) (interface{}, error) {
+		value, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(ctx, key, ttl, value)
+		return value, nil
+	}
+
+	if entry, ok := c.load(ctx, key); ok {
+		var value T
+		if err := json.Unmarshal(entry.Payload, &value); err == nil {
+			if time.Now().After(entry.FreshUntil) {
+				c.observer.ObserveCacheLookup(c.GetName(), method, CacheStale)
+				c.revalidate(key, load)
+			} else {
+				c.observer.ObserveCacheLookup(c.GetName(), method, CacheHit)
+			}
+			return value, nil
+		}
+	}
+	c.observer.ObserveCacheLookup(c.GetName(), method, CacheMiss)
+
+	// Detach from the caller's cancellation so one aborted request doesn't fail
+	// every other request waiting on the same shared fetch
+	shared, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.fetchTimeout())
+	defer cancel()
+
+	result, err, _ := c.group.Do(key, func() (interface{}, error) {
+		return load(shared)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result.(T), nil
+}
+
+// revalidate refreshes a stale entry in the background, at most once per key at a time
+func (c *CachedProvider) revalidate(key string, load func(context.Context) (interface{}, error)) {
+	c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.fetchTimeout())
+		defer cancel()
+		return load(ctx)
+	})
+}
+
+// load reads a cache entry, treating any Redis error as a miss
+func (c *CachedProvider) load(ctx context.Context, key string) (*cacheEntry, bool) {
+	data, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// store writes a cache entry that stays fresh for ttl and is kept for a further stale window
+func (c *CachedProvider) store(ctx context.Context, key string, ttl time.Duration, value interface{}) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		Payload:    payload,
+		FreshUntil: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return
+	}
+
+	// Cache write failures are not fatal, the next request simply misses again
+	_ = c.redis.Set(ctx, key, data, ttl+c.config.CacheStaleWindow).Err()
+}
+
+// cacheKey builds a key from the provider, method and a hash of the call arguments
+func (c *CachedProvider) cacheKey(method string, args ...interface{}) string {
+	encoded, _ := json.Marshal(args)
+	sum := sha256.Sum256(encoded)
+	return strings.Join([]string{cacheKeyPrefix, c.provider.GetName(), method, hex.EncodeToString(sum[:16])}, ":")
+}
+
+// fetchTimeout bounds upstream calls made on behalf of several callers
+func (c *CachedProvider) fetchTimeout() time.Duration {
+	if c.config.Timeout > 0 {
+		return c.config.Timeout
+	}
+	return 30 * time.Second
+}
+
+// normalizeQuery folds case and whitespace so equivalent searches share a cache entry
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
@@ -0,0 +1,216 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+// BrowseCategory is a category merged across providers. Provider categories are mapped onto
+// a shared taxonomy, so "Hip-Hop", "hiphop" and "Rap" are one category with the ID hip-hop.
+type BrowseCategory struct {
+	ID      string           `json:"id"`
+	Name    string           `json:"name"`
+	IconURL string           `json:"icon_url,omitempty"`
+	Sources []CategorySource `json:"sources"`
+}
+
+// CategorySource identifies one provider's copy of a category
+type CategorySource struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+}
+
+// taxonomyEntry is a normalized category and the provider names that map onto it
+type taxonomyEntry struct {
+	id      string
+	name    string
+	aliases []string
+}
+
+// categoryTaxonomy lists the normalized categories in browse order. Provider categories
+// matching none of them are kept under their own name, after these.
+var categoryTaxonomy = []taxonomyEntry{
+	{id: "pop", name: "Pop"},
+	{id: "hip-hop", name: "Hip-Hop", aliases: []string{"Hip Hop", "Rap"}},
+	{id: "rock", name: "Rock"},
+	{id: "rnb", name: "R&B", aliases: []string{"RnB", "Rhythm and Blues", "R&B/Soul", "Soul"}},
+	{id: "electronic", name: "Electronic", aliases: []string{"Dance", "Dance/Electronic", "Electronic/Dance", "EDM"}},
+	{id: "latin", name: "Latin", aliases: []string{"Latino"}},
+	{id: "country", name: "Country"},
+	{id: "indie", name: "Indie", aliases: []string{"Alternative", "Indie/Alternative"}},
+	{id: "k-pop", name: "K-Pop", aliases: []string{"Korean Pop"}},
+	{id: "metal", name: "Metal", aliases: []string{"Heavy Metal"}},
+	{id: "jazz", name: "Jazz"},
+	{id: "classical", name: "Classical"},
+	{id: "blues", name: "Blues"},
+	{id: "reggae", name: "Reggae"},
+	{id: "afro", name: "Afro", aliases: []string{"Afrobeats", "Afropop", "African"}},
+	{id: "folk", name: "Folk", aliases: []string{"Folk & Acoustic", "Acoustic"}},
+	{id: "soundtracks", name: "Soundtracks", aliases: []string{"Soundtrack", "Movies", "Film & TV"}},
+	{id: "chill", name: "Chill", aliases: []string{"Relax", "Lounge"}},
+	{id: "workout", name: "Workout", aliases: []string{"Fitness"}},
+	{id: "mood", name: "Mood"},
+	{id: "party", name: "Party"},
+	{id: "focus", name: "Focus", aliases: []string{"Study"}},
+	{id: "sleep", name: "Sleep"},
+}
+
+// taxonomyIndex maps a category key to its position in categoryTaxonomy
+var taxonomyIndex = func() map[string]int {
+	index := make(map[string]int)
+	for i, entry := range categoryTaxonomy {
+		for _, name := range append([]string{entry.id, entry.name}, entry.aliases...) {
+			index[categoryKey(name)] = i
+		}
+	}
+	return index
+}()
+
+// categoryKey folds case and punctuation so equivalent category names compare equal
+func categoryKey(name string) string {
+	return nonAlnumRe.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// categorySlug builds an ID for a category outside the taxonomy
+func categorySlug(name string) string {
+	return strings.Trim(nonAlnumRe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// GetBrowseCategories gets the categories of every available provider, merged onto the
+// shared taxonomy. Providers that fail are left out; an error is returned only if all fail.
+func (m *MusicService) GetBrowseCategories(ctx context.Context) ([]BrowseCategory, error) {
+	providers := m.registry.availableProviders()
+	if len(providers) == 0 {
+		return nil, ErrCircuitOpen
+	}
+
+	type categoriesResult struct {
+		provider   string
+		categories []Category
+		err        error
+	}
+	resultChan := make(chan categoriesResult, len(providers))
+	for _, provider := range providers {
+		go func(p MusicProvider) {
+			categories, err := p.GetCategories(ctx)
+			resultChan <- categoriesResult{provider: p.GetName(), categories: categories, err: err}
+		}(provider)
+	}
+
+	results := make([]categoriesResult, 0, len(providers))
+	var errs []error
+	for range providers {
+		res := <-resultChan
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		results = append(results, res)
+	}
+	if len(results) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Merge in provider name order so icons and names don't change between requests
+	sort.Slice(results, func(i, j int) bool { return results[i].provider < results[j].provider })
+
+	merged := make(map[string]*BrowseCategory)
+	var extra []string // IDs of categories outside the taxonomy, in first-seen order
+	for _, res := range results {
+		for _, category := range res.categories {
+			id, name := categorySlug(category.Name), category.Name
+			i, known := taxonomyIndex[categoryKey(category.Name)]
+			if !known {
+				i, known = taxonomyIndex[categoryKey(category.ID)]
+			}
+			if known {
+				id, name = categoryTaxonomy[i].id, categoryTaxonomy[i].name
+			}
+			if id == "" {
+				continue
+			}
+
+			browse, exists := merged[id]
+			if !exists {
+				browse = &BrowseCategory{ID: id, Name: name}
+				merged[id] = browse
+				if !known {
+					extra = append(extra, id)
+				}
+			}
+			if browse.IconURL == "" {
+				browse.IconURL = category.IconURL
+			}
+			browse.Sources = append(browse.Sources, CategorySource{Provider: res.provider, ID: category.ID, Name: category.Name})
+		}
+	}
+
+	categories := make([]BrowseCategory, 0, len(merged))
+	for _, entry := range categoryTaxonomy {
+		if browse, ok := merged[entry.id]; ok {
+			categories = append(categories, *browse)
+		}
+	}
+	for _, id := range extra {
+		categories = append(categories, *merged[id])
+	}
+	return categories, nil
+}
+
+// GetBrowseCategoryPlaylists gets the playlists of a merged category from the first of its
+// providers that has playlists, or from provider when one is given. A category no provider
+// has playlists for returns an empty page.
+func (m *MusicService) GetBrowseCategoryPlaylists(ctx context.Context, categoryID, provider string, page, size int) ([]PlaylistSummary, *PageInfo, error) {
+	categories, err := m.GetBrowseCategories(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var category *BrowseCategory
+	for i := range categories {
+		if categories[i].ID == categoryID {
+			category = &categories[i]
+			break
+		}
+	}
+	if category == nil || (provider != "" && !category.hasSource(provider)) {
+		return nil, nil, ErrCategoryNotFound
+	}
+
+	var lastErr error
+	for _, source := range category.Sources {
+		if provider != "" && source.Provider != provider {
+			continue
+		}
+
+		playlists, pageInfo, err := m.GetPlaylistsByCategory(ctx, source.Provider, source.ID, page, size)
+		if err == nil {
+			return playlists, pageInfo, nil
+		}
+
+		var providerErr *ProviderError
+		if errors.As(err, &providerErr) && providerErr.Code == "NOT_SUPPORTED" {
+			continue
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, nil, lastErr
+	}
+	return []PlaylistSummary{}, &PageInfo{Page: page, Size: size}, nil
+}
+
+// hasSource reports whether provider offers the category
+func (c *BrowseCategory) hasSource(provider string) bool {
+	for _, source := range c.Sources {
+		if source.Provider == provider {
+			return true
+		}
+	}
+	return false
+}
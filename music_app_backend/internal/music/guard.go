@@ -0,0 +1,328 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrCircuitOpen = errors.New("provider circuit is open")
+	ErrRateLimited = errors.New("provider rate limit exceeded")
+)
+
+// CircuitState represents the state of a provider circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreaker stops calls to a provider after repeated failures. Once the
+// open timeout elapses a single probe call is let through; its outcome decides
+// whether the circuit closes again or re-opens.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	state       CircuitState
+	failures    int
+	threshold   int
+	openTimeout time.Duration
+	openedAt    time.Time
+	probing     bool
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if openTimeout <= 0 {
+		openTimeout = 30 * time.Second
+	}
+
+	return &CircuitBreaker{
+		state:       CircuitClosed,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+	}
+}
+
+// Allow reports whether a call may proceed
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the circuit and resets the failure count
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// RecordFailure counts a failed call and opens the circuit when the threshold is reached
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
+
+// Release frees a half-open probe slot without deciding the circuit's state,
+// used when a call ended for reasons unrelated to provider health
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// State returns the current circuit state
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.openTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// tokenBucket is a token-bucket rate limiter allowing a number of calls per minute
+type tokenBucket struct {
+	mu         sync.Mutex
+	capacity   float64
+	tokens     float64
+	refillRate float64 // tokens per second
+	last       time.Time
+}
+
+// newTokenBucket creates a limiter for perMinute calls, or nil for no limit
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	return &tokenBucket{
+		capacity:   float64(perMinute),
+		tokens:     float64(perMinute),
+		refillRate: float64(perMinute) / 60,
+		last:       time.Now(),
+	}
+}
+
+// Allow takes a token if one is available
+func (t *tokenBucket) Allow() bool {
+	if t == nil {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.refillRate
+	if t.tokens > t.capacity {
+		t.tokens = t.capacity
+	}
+	t.last = now
+
+	if t.tokens < 1 {
+		return false
+	}
+	t.tokens--
+	return true
+}
+
+// guardedProvider enforces a rate limit and circuit breaker around a provider
+type guardedProvider struct {
+	provider MusicProvider
+	breaker  *CircuitBreaker
+	limiter  *tokenBucket
+	observer Observer
+}
+
+func newGuardedProvider(provider MusicProvider, config *ProviderConfig) *guardedProvider {
+	return &guardedProvider{
+		provider: provider,
+		breaker:  NewCircuitBreaker(config.BreakerFailureThreshold, config.BreakerOpenTimeout),
+		limiter:  newTokenBucket(config.RateLimit),
+		observer: observerOf(config),
+	}
+}
+
+// GetName returns the name of the wrapped provider
+func (g *guardedProvider) GetName() string {
+	return g.provider.GetName()
+}
+
+// SearchTracks searches for tracks through the guard
+func (g *guardedProvider) SearchTracks(ctx context.Context, query string, page, size int, filters *SearchFilters) ([]Track, *PageInfo, error) {
+	var tracks []Track
+	var pageInfo *PageInfo
+	err := g.call(ctx, "search", func(ctx context.Context) error {
+		var err error
+		tracks, pageInfo, err = g.provider.SearchTracks(ctx, query, page, size, filters)
+		return err
+	})
+	return tracks, pageInfo, err
+}
+
+// GetTrack gets a track through the guard
+func (g *guardedProvider) GetTrack(ctx context.Context, trackID string) (*Track, error) {
+	var track *Track
+	err := g.call(ctx, "track", func(ctx context.Context) error {
+		var err error
+		track, err = g.provider.GetTrack(ctx, trackID)
+		return err
+	})
+	return track, err
+}
+
+// GetTopCharts gets top charts through the guard
+func (g *guardedProvider) GetTopCharts(ctx context.Context, country string, page, size int) ([]Track, *PageInfo, error) {
+	var tracks []Track
+	var pageInfo *PageInfo
+	err := g.call(ctx, "top_charts", func(ctx context.Context) error {
+		var err error
+		tracks, pageInfo, err = g.provider.GetTopCharts(ctx, country, page, size)
+		return err
+	})
+	return tracks, pageInfo, err
+}
+
+// GetCategories gets categories through the guard
+func (g *guardedProvider) GetCategories(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	err := g.call(ctx, "categories", func(ctx context.Context) error {
+		var err error
+		categories, err = g.provider.GetCategories(ctx)
+		return err
+	})
+	return categories, err
+}
+
+// GetPlaylistsByCategory gets category playlists through the guard
+func (g *guardedProvider) GetPlaylistsByCategory(ctx context.Context, categoryID string, page, size int) ([]PlaylistSummary, *PageInfo, error) {
+	var playlists []PlaylistSummary
+	var pageInfo *PageInfo
+	err := g.call(ctx, "category_playlists", func(ctx context.Context) error {
+		var err error
+		playlists, pageInfo, err = g.provider.GetPlaylistsByCategory(ctx, categoryID, page, size)
+		return err
+	})
+	return playlists, pageInfo, err
+}
+
+// GetPlaylistTracks gets playlist tracks through the guard
+func (g *guardedProvider) GetPlaylistTracks(ctx context.Context, playlistID string, page, size int) ([]Track, *PageInfo, error) {
+	var tracks []Track
+	var pageInfo *PageInfo
+	err := g.call(ctx, "playlist_tracks", func(ctx context.Context) error {
+		var err error
+		tracks, pageInfo, err = g.provider.GetPlaylistTracks(ctx, playlistID, page, size)
+		return err
+	})
+	return tracks, pageInfo, err
+}
+
+// IsHealthy reports an open circuit without probing the provider. Health checks
+// bypass the rate limiter and don't affect the breaker.
+func (g *guardedProvider) IsHealthy(ctx context.Context) error {
+	if g.breaker.State() == CircuitOpen {
+		return NewProviderError(g.GetName(), "Circuit breaker is open", "CIRCUIT_OPEN", ErrCircuitOpen)
+	}
+	return g.provider.IsHealthy(ctx)
+}
+
+// call runs fn if the breaker and limiter allow it and records the outcome
+func (g *guardedProvider) call(ctx context.Context, method string, fn func(context.Context) error) error {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "provider "+g.GetName()+" "+method, trace.WithAttributes(
+		attribute.String("music.provider", g.GetName()),
+		attribute.String("music.method", method),
+	))
+	defer span.End()
+
+	if !g.breaker.Allow() {
+		err := NewProviderError(g.GetName(), "Circuit breaker is open", "CIRCUIT_OPEN", ErrCircuitOpen)
+		g.observer.ObserveProviderCall(g.GetName(), method, time.Since(start), err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	if !g.limiter.Allow() {
+		g.breaker.Release()
+		err := NewProviderError(g.GetName(), "Rate limit exceeded", "RATE_LIMITED", ErrRateLimited)
+		g.observer.ObserveProviderCall(g.GetName(), method, time.Since(start), err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	err := fn(ctx)
+	g.observer.ObserveProviderCall(g.GetName(), method, time.Since(start), err)
+	if err != nil {
+		span.RecordError(err)
+		if isProviderFailure(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	switch {
+	case err == nil, !isProviderFailure(err):
+		g.breaker.RecordSuccess()
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the provider
+		g.breaker.Release()
+	default:
+		g.breaker.RecordFailure()
+	}
+
+	return err
+}
+
+// isProviderFailure reports whether an error indicates the provider itself is unhealthy.
+// Lookups that simply found nothing, or asked for something unsupported, don't count.
+func isProviderFailure(err error) bool {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.Code {
+		case "NOT_FOUND", "NOT_SUPPORTED":
+			return false
+		}
+	}
+	return true
+}
@@ -67,6 +67,7 @@ type iTunesTrackData struct {
 // NewITunesProvider creates a new iTunes provider
 func NewITunesProvider(config *ProviderConfig) *ITunesProvider {
 	client := resty.New()
+	client.SetTransport(tracedTransport())
 	client.SetTimeout(config.Timeout)
 	client.SetHeader("User-Agent", config.UserAgent)
 
@@ -202,6 +203,11 @@ func (i *ITunesProvider) GetPlaylistsByCategory(ctx context.Context, categoryID
 	return nil, nil, NewProviderError(i.GetName(), "Playlists not supported", "NOT_SUPPORTED", nil)
 }
 
+// GetPlaylistTracks gets the tracks of a playlist (iTunes doesn't support this)
+func (i *ITunesProvider) GetPlaylistTracks(ctx context.Context, playlistID string, page, size int) ([]Track, *PageInfo, error) {
+	return nil, nil, NewProviderError(i.GetName(), "Playlists not supported", "NOT_SUPPORTED", nil)
+}
+
 // IsHealthy checks if the provider is healthy
 func (i *ITunesProvider) IsHealthy(ctx context.Context) error {
 	resp, err := i.client.R().
@@ -0,0 +1,461 @@
+package music
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// rrfK dampens reciprocal rank fusion so top positions don't dominate completely
+	rrfK = 60
+	// durationToleranceMs is how far apart two durations may be and still be the same recording
+	durationToleranceMs = 3000
+	// maxMergedPageSize is the largest page every provider can serve in one call
+	maxMergedPageSize = 50
+	// maxCursorSeen bounds how many already-returned tracks a cursor remembers
+	maxCursorSeen = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid search cursor")
+	ErrMergedPage    = errors.New("searches across all providers are paged by cursor, not page number")
+)
+
+var (
+	bracketedRe = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+	suffixRe    = regexp.MustCompile(`\s+-\s+.*$`)
+	featuringRe = regexp.MustCompile(`(\s+|\s*[\(\[])(feat\.?|ft\.?|featuring)\s+.*$`)
+	nonAlnumRe  = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// searchCursor records how far a merged search has read into each provider
+type searchCursor struct {
+	Query   string         `json:"q"`
+	Offsets map[string]int `json:"o"`
+	Seen    []string       `json:"s,omitempty"`
+	Page    int            `json:"p,omitempty"` // number of the page the cursor resumes at
+}
+
+// mergeCandidate is one provider result considered for the merged page
+type mergeCandidate struct {
+	track    Track
+	provider string
+	rank     int // position in the provider's full result list
+	cluster  int
+	keys     []string
+}
+
+// providerWindow holds the results fetched from one provider for this page
+type providerWindow struct {
+	provider   string
+	offset     int
+	candidates []*mergeCandidate
+	full       bool
+	err        error
+}
+
+// searchMerged searches every available provider and merges the results into a
+// single ranked, de-duplicated page. The returned PageInfo carries a cursor for
+// the following page.
+func (m *MusicService) searchMerged(ctx context.Context, query, cursorStr string, size int, filters *SearchFilters) ([]Track, *PageInfo, error) {
+	if size <= 0 {
+		size = 20
+	}
+	if size > maxMergedPageSize {
+		size = maxMergedPageSize
+	}
+
+	queryHash := hashSearch(query, filters)
+	cursor, err := decodeSearchCursor(cursorStr, queryHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	providers := m.registry.availableProviders()
+	windows := make([]*providerWindow, len(providers))
+	done := make(chan struct{}, len(providers))
+
+	for i, provider := range providers {
+		go func(i int, p MusicProvider) {
+			defer func() { done <- struct{}{} }()
+			windows[i] = fetchWindow(ctx, p, query, cursor.Offsets[p.GetName()], size, filters)
+		}(i, provider)
+	}
+	for range providers {
+		<-done
+	}
+
+	// Keep a stable provider order so merges are reproducible
+	sort.Slice(windows, func(i, j int) bool { return windows[i].provider < windows[j].provider })
+
+	var firstErr error
+	succeeded := 0
+	var candidates []*mergeCandidate
+	for _, w := range windows {
+		if w.err != nil {
+			if firstErr == nil {
+				firstErr = w.err
+			}
+			continue
+		}
+		succeeded++
+		candidates = append(candidates, w.candidates...)
+	}
+	if succeeded == 0 && firstErr != nil {
+		return nil, nil, firstErr
+	}
+
+	clusters := clusterCandidates(candidates)
+	scores := make(map[int]float64, len(clusters))
+	for _, c := range candidates {
+		scores[c.cluster] += 1.0 / float64(rrfK+c.rank+1)
+	}
+
+	seen := make(map[string]bool, len(cursor.Seen))
+	for _, key := range cursor.Seen {
+		seen[key] = true
+	}
+	emittedClusters := make(map[int]bool)
+	heads := make([]int, len(windows))
+	tracks := make([]Track, 0, size)
+	var newlySeen []string
+
+	isEmitted := func(c *mergeCandidate) bool {
+		if emittedClusters[c.cluster] {
+			return true
+		}
+		for _, key := range c.keys {
+			if seen[key] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for len(tracks) < size {
+		best := -1
+		for i, w := range windows {
+			// Skip past anything already returned on this or an earlier page
+			for heads[i] < len(w.candidates) && isEmitted(w.candidates[heads[i]]) {
+				heads[i]++
+			}
+			if heads[i] >= len(w.candidates) {
+				continue
+			}
+			if best == -1 || outranks(w.candidates[heads[i]], windows[best].candidates[heads[best]], scores) {
+				best = i
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		head := windows[best].candidates[heads[best]]
+		heads[best]++
+		emittedClusters[head.cluster] = true
+		tracks = append(tracks, mergeCluster(clusters[head.cluster]))
+
+		// Remember duplicates that sit beyond what this page consumed so the next
+		// page doesn't return them again
+		for _, member := range clusters[head.cluster] {
+			if member != head {
+				newlySeen = append(newlySeen, member.keys...)
+			}
+		}
+	}
+
+	next := &searchCursor{Query: queryHash, Offsets: make(map[string]int, len(windows)), Page: cursor.Page + 1}
+	for provider, offset := range cursor.Offsets {
+		next.Offsets[provider] = offset
+	}
+	hasNext := false
+	var maxTotal int64
+	for i, w := range windows {
+		if w.err != nil {
+			continue
+		}
+		next.Offsets[w.provider] = w.offset + heads[i]
+		if heads[i] < len(w.candidates) || w.full {
+			hasNext = true
+		}
+		if total := int64(w.offset + len(w.candidates)); total > maxTotal {
+			maxTotal = total
+		}
+	}
+	next.Seen = append(cursor.Seen, newlySeen...)
+	if len(next.Seen) > maxCursorSeen {
+		next.Seen = next.Seen[len(next.Seen)-maxCursorSeen:]
+	}
+
+	pageInfo := &PageInfo{
+		Page:    cursor.Page,
+		Size:    size,
+		Total:   maxTotal,
+		HasNext: hasNext,
+		HasPrev: cursorStr != "",
+	}
+	if hasNext {
+		pageInfo.NextCursor = encodeSearchCursor(next)
+	}
+
+	return tracks, pageInfo, nil
+}
+
+// fetchWindow reads up to size results from a provider starting at offset. The
+// provider API is page based, so an unaligned offset may need two page fetches.
+func fetchWindow(ctx context.Context, p MusicProvider, query string, offset, size int, filters *SearchFilters) *providerWindow {
+	window := &providerWindow{provider: p.GetName(), offset: offset}
+
+	page := offset/size + 1
+	skip := offset % size
+
+	tracks, _, err := p.SearchTracks(ctx, query, page, size, filters)
+	if err != nil {
+		window.err = err
+		return window
+	}
+	if skip > 0 && len(tracks) == size {
+		if more, _, err := p.SearchTracks(ctx, query, page+1, size, filters); err == nil {
+			tracks = append(tracks, more...)
+		}
+	}
+
+	if skip >= len(tracks) {
+		return window
+	}
+	tracks = tracks[skip:]
+	if len(tracks) > size {
+		tracks = tracks[:size]
+	}
+
+	window.full = len(tracks) == size
+	window.candidates = make([]*mergeCandidate, len(tracks))
+	for i, track := range tracks {
+		window.candidates[i] = &mergeCandidate{
+			track:    track,
+			provider: window.provider,
+			rank:     offset + i,
+			keys:     dedupKeys(track),
+		}
+	}
+
+	return window
+}
+
+// clusterCandidates groups candidates that are the same recording, assigning
+// each a cluster index, and returns the members of every cluster
+func clusterCandidates(candidates []*mergeCandidate) [][]*mergeCandidate {
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	byISRC := make(map[string]int)
+	byTitle := make(map[string][]int)
+	for i, c := range candidates {
+		if isrc := strings.ToUpper(c.track.ISRC); isrc != "" {
+			if j, exists := byISRC[isrc]; exists {
+				union(j, i)
+			} else {
+				byISRC[isrc] = i
+			}
+		}
+
+		key := titleArtistKey(c.track)
+		if key == "" {
+			continue
+		}
+		for _, j := range byTitle[key] {
+			if durationsMatch(candidates[j].track.Duration, c.track.Duration) {
+				union(j, i)
+			}
+		}
+		byTitle[key] = append(byTitle[key], i)
+	}
+
+	index := make(map[int]int)
+	var clusters [][]*mergeCandidate
+	for i, c := range candidates {
+		root := find(i)
+		idx, exists := index[root]
+		if !exists {
+			idx = len(clusters)
+			index[root] = idx
+			clusters = append(clusters, nil)
+		}
+		c.cluster = idx
+		clusters[idx] = append(clusters[idx], c)
+	}
+
+	return clusters
+}
+
+// mergeCluster collapses duplicate candidates into one track listing every source.
+// The best ranked member provides the base metadata and gaps are filled from the rest.
+func mergeCluster(members []*mergeCandidate) Track {
+	ordered := make([]*mergeCandidate, len(members))
+	copy(ordered, members)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].rank != ordered[j].rank {
+			return ordered[i].rank < ordered[j].rank
+		}
+		return ordered[i].provider < ordered[j].provider
+	})
+
+	merged := ordered[0].track
+	merged.Sources = nil
+	for _, member := range ordered {
+		t := member.track
+		if merged.PreviewURL == "" {
+			merged.PreviewURL = t.PreviewURL
+		}
+		if merged.ISRC == "" {
+			merged.ISRC = t.ISRC
+		}
+		if merged.Genre == "" {
+			merged.Genre = t.Genre
+		}
+		if merged.ArtworkURL == "" {
+			merged.ArtworkURL = t.ArtworkURL
+		}
+		if t.Popularity > merged.Popularity {
+			merged.Popularity = t.Popularity
+		}
+		merged.Explicit = merged.Explicit || t.Explicit
+		merged.Sources = append(merged.Sources, TrackSource{
+			Provider:    t.Provider,
+			ID:          t.ID,
+			ExternalURL: t.ExternalURL,
+			PreviewURL:  t.PreviewURL,
+		})
+	}
+
+	return merged
+}
+
+// outranks reports whether candidate a should be emitted before b
+func outranks(a, b *mergeCandidate, scores map[int]float64) bool {
+	if sa, sb := scores[a.cluster], scores[b.cluster]; sa != sb {
+		return sa > sb
+	}
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	return a.provider < b.provider
+}
+
+// dedupKeys returns the keys a cursor uses to recognise a track on later pages
+func dedupKeys(track Track) []string {
+	var keys []string
+	if track.ISRC != "" {
+		keys = append(keys, "isrc:"+strings.ToUpper(track.ISRC))
+	}
+	if key := titleArtistKey(track); key != "" {
+		keys = append(keys, "ta:"+key)
+	}
+	return keys
+}
+
+// titleArtistKey builds a normalized title and artist key
+func titleArtistKey(track Track) string {
+	title := normalizeTitle(track.Title)
+	artist := normalizeArtist(track.Artist)
+	if title == "" || artist == "" {
+		return ""
+	}
+	return title + "|" + artist
+}
+
+// normalizeTitle drops bracketed qualifiers and version suffixes such as "(feat. X)" or "- Remastered"
+func normalizeTitle(title string) string {
+	title = strings.ToLower(title)
+	title = bracketedRe.ReplaceAllString(title, "")
+	title = suffixRe.ReplaceAllString(title, "")
+	return strings.TrimSpace(nonAlnumRe.ReplaceAllString(title, " "))
+}
+
+// normalizeArtist drops featured artists from a credit. Commas and ampersands are kept, as
+// they are part of names such as "Earth, Wind & Fire".
+func normalizeArtist(artist string) string {
+	artist = strings.ToLower(artist)
+	artist = featuringRe.ReplaceAllString(artist, "")
+	return strings.TrimSpace(nonAlnumRe.ReplaceAllString(artist, " "))
+}
+
+// durationsMatch treats unknown durations as matching anything
+func durationsMatch(a, b int64) bool {
+	if a == 0 || b == 0 {
+		return true
+	}
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= durationToleranceMs
+}
+
+// hashSearch identifies a query and its filters so a cursor can't be replayed against a different search
+func hashSearch(query string, filters *SearchFilters) string {
+	encoded, _ := json.Marshal([]interface{}{normalizeQuery(query), filters})
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodeSearchCursor serializes a cursor into an opaque URL-safe string
+func encodeSearchCursor(cursor *searchCursor) string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeSearchCursor parses a cursor, returning a fresh one when cursorStr is empty
+func decodeSearchCursor(cursorStr, queryHash string) (*searchCursor, error) {
+	if cursorStr == "" {
+		return &searchCursor{Query: queryHash, Offsets: map[string]int{}, Page: 1}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursorStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor searchCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Query != queryHash {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Offsets == nil {
+		cursor.Offsets = map[string]int{}
+	}
+	if cursor.Page < 2 {
+		cursor.Page = 2
+	}
+	for _, offset := range cursor.Offsets {
+		if offset < 0 {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return &cursor, nil
+}
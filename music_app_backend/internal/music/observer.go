@@ -0,0 +1,38 @@
+package music
+
+import "time"
+
+// CacheResult is the outcome of looking up a provider response in the cache
+type CacheResult string
+
+const (
+	CacheHit   CacheResult = "hit"
+	CacheStale CacheResult = "stale" // served while a background refresh runs
+	CacheMiss  CacheResult = "miss"
+)
+
+// Observer is notified of provider calls and cache lookups, for example to record metrics.
+// Methods are named as in cache keys: search, track, top_charts, categories,
+// category_playlists and playlist_tracks.
+type Observer interface {
+	// ObserveProviderCall is called after every call through a provider's guard, including
+	// calls it rejected with ErrCircuitOpen or ErrRateLimited without reaching the provider
+	ObserveProviderCall(provider, method string, duration time.Duration, err error)
+
+	// ObserveCacheLookup is called for every lookup of a cached provider response
+	ObserveCacheLookup(provider, method string, result CacheResult)
+}
+
+// nopObserver is used when no observer is configured
+type nopObserver struct{}
+
+func (nopObserver) ObserveProviderCall(string, string, time.Duration, error) {}
+func (nopObserver) ObserveCacheLookup(string, string, CacheResult)           {}
+
+// observerOf returns the observer set in config, or one that ignores everything
+func observerOf(config *ProviderConfig) Observer {
+	if config.Observer != nil {
+		return config.Observer
+	}
+	return nopObserver{}
+}
@@ -2,25 +2,38 @@ package music
 
 import (
 	"context"
+	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Track represents a music track from any provider
 type Track struct {
-	ID            string  `json:"id"`
-	Title         string  `json:"title"`
-	Artist        string  `json:"artist"`
-	Album         string  `json:"album"`
-	Duration      int64   `json:"duration_ms"`
-	ArtworkURL    string  `json:"artwork_url"`
-	PreviewURL    string  `json:"preview_url,omitempty"`
-	TrackNumber   int     `json:"track_number,omitempty"`
-	ReleaseDate   string  `json:"release_date,omitempty"`
-	Genre         string  `json:"genre,omitempty"`
-	Provider      string  `json:"provider"`
-	ExternalURL   string  `json:"external_url,omitempty"`
-	Explicit      bool    `json:"explicit"`
-	Popularity    int     `json:"popularity,omitempty"`
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Artist      string        `json:"artist"`
+	Album       string        `json:"album"`
+	Duration    int64         `json:"duration_ms"`
+	ArtworkURL  string        `json:"artwork_url"`
+	PreviewURL  string        `json:"preview_url,omitempty"`
+	TrackNumber int           `json:"track_number,omitempty"`
+	ReleaseDate string        `json:"release_date,omitempty"`
+	Genre       string        `json:"genre,omitempty"`
+	Provider    string        `json:"provider"`
+	ExternalURL string        `json:"external_url,omitempty"`
+	Explicit    bool          `json:"explicit"`
+	Popularity  int           `json:"popularity,omitempty"`
+	ISRC        string        `json:"isrc,omitempty"`
+	Sources     []TrackSource `json:"sources,omitempty"` // every provider offering this track in merged results
+}
+
+// TrackSource identifies one provider's copy of a track
+type TrackSource struct {
+	Provider    string `json:"provider"`
+	ID          string `json:"id"`
+	ExternalURL string `json:"external_url,omitempty"`
+	PreviewURL  string `json:"preview_url,omitempty"`
 }
 
 // PlaylistSummary represents a playlist summary from any provider
@@ -45,12 +58,13 @@ type Category struct {
 
 // PageInfo represents pagination information
 type PageInfo struct {
-	Page       int   `json:"page"`
-	Size       int   `json:"size"`
-	Total      int64 `json:"total"`
-	HasNext    bool  `json:"has_next"`
-	HasPrev    bool  `json:"has_prev"`
-	TotalPages int   `json:"total_pages"`
+	Page       int    `json:"page"`
+	Size       int    `json:"size"`
+	Total      int64  `json:"total"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"` // set by merged multi-provider searches
 }
 
 // SearchFilters represents search filter options
@@ -83,16 +97,33 @@ type MusicProvider interface {
 	// GetPlaylistsByCategory gets playlists for a specific category
 	GetPlaylistsByCategory(ctx context.Context, categoryID string, page, size int) ([]PlaylistSummary, *PageInfo, error)
 	
+	// GetPlaylistTracks gets the tracks of a playlist, such as one returned by GetPlaylistsByCategory
+	GetPlaylistTracks(ctx context.Context, playlistID string, page, size int) ([]Track, *PageInfo, error)
+	
 	// IsHealthy checks if the provider is healthy and accessible
 	IsHealthy(ctx context.Context) error
 }
 
 // ProviderConfig holds common configuration for music providers
 type ProviderConfig struct {
-	Timeout   time.Duration
-	RateLimit int
-	CacheTTL  time.Duration
-	UserAgent string
+	Timeout                 time.Duration
+	RateLimit               int           // calls per minute per provider, 0 disables limiting
+	CacheTTL                time.Duration // search, top charts, category playlists and playlist tracks
+	TrackCacheTTL           time.Duration
+	CategoryCacheTTL        time.Duration
+	CacheStaleWindow        time.Duration // how long expired entries may still be served while refreshing
+	BreakerFailureThreshold int           // consecutive failures before a provider's circuit opens
+	BreakerOpenTimeout      time.Duration // how long a circuit stays open before a probe is allowed
+	UserAgent               string
+	Observer                Observer // notified of provider calls and cache lookups, may be nil
+}
+
+// tracedTransport sends provider requests through the default transport, recording a client
+// span for each and propagating the trace context
+func tracedTransport() http.RoundTripper {
+	return otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Host
+	}))
 }
 
 // ProviderError represents an error from a music provider
@@ -2,26 +2,48 @@ package music
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
-	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
-// ProviderRegistry manages multiple music providers
+var ErrProviderNotFound = errors.New("provider not found")
+
+// ProviderDecorator wraps a provider with additional behaviour, such as caching
+type ProviderDecorator func(MusicProvider) MusicProvider
+
+// ProviderRegistry manages multiple music providers. Every registered provider
+// is guarded by its own rate limiter and circuit breaker.
 type ProviderRegistry struct {
 	providers   map[string]MusicProvider
+	breakers    map[string]*CircuitBreaker
+	decorators  []ProviderDecorator
 	enabledOnly []string
+	config      *ProviderConfig
 	mu          sync.RWMutex
 }
 
 // NewProviderRegistry creates a new provider registry
-func NewProviderRegistry(enabledProviders []string) *ProviderRegistry {
+func NewProviderRegistry(enabledProviders []string, config *ProviderConfig) *ProviderRegistry {
 	return &ProviderRegistry{
 		providers:   make(map[string]MusicProvider),
+		breakers:    make(map[string]*CircuitBreaker),
 		enabledOnly: enabledProviders,
+		config:      config,
 	}
 }
 
+// Use adds a decorator applied outside the rate limiter and circuit breaker of
+// providers registered afterwards
+func (r *ProviderRegistry) Use(decorator ProviderDecorator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.decorators = append(r.decorators, decorator)
+}
+
 // Register adds a provider to the registry
 func (r *ProviderRegistry) Register(provider MusicProvider) error {
 	r.mu.Lock()
@@ -42,7 +64,15 @@ func (r *ProviderRegistry) Register(provider MusicProvider) error {
 		return fmt.Errorf("provider %s is not enabled", name)
 	}
 
-	r.providers[name] = provider
+	guarded := newGuardedProvider(provider, r.config)
+
+	var wrapped MusicProvider = guarded
+	for _, decorate := range r.decorators {
+		wrapped = decorate(wrapped)
+	}
+
+	r.providers[name] = wrapped
+	r.breakers[name] = guarded.breaker
 	return nil
 }
 
@@ -53,7 +83,7 @@ func (r *ProviderRegistry) GetProvider(name string) (MusicProvider, error) {
 
 	provider, exists := r.providers[name]
 	if !exists {
-		return nil, fmt.Errorf("provider %s not found", name)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
 	}
 
 	return provider, nil
@@ -85,9 +115,38 @@ func (r *ProviderRegistry) GetProviderNames() []string {
 	return names
 }
 
-// SearchAllProviders searches across all enabled providers
+// CircuitStates returns the circuit breaker state of every registered provider
+func (r *ProviderRegistry) CircuitStates() map[string]CircuitState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make(map[string]CircuitState, len(r.breakers))
+	for name, breaker := range r.breakers {
+		states[name] = breaker.State()
+	}
+
+	return states
+}
+
+// availableProviders returns enabled providers whose circuit is not open
+func (r *ProviderRegistry) availableProviders() []MusicProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make([]MusicProvider, 0, len(r.providers))
+	for name, provider := range r.providers {
+		if breaker, exists := r.breakers[name]; exists && breaker.State() == CircuitOpen {
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+// SearchAllProviders searches across all enabled providers, skipping those with an open circuit
 func (r *ProviderRegistry) SearchAllProviders(ctx context.Context, query string, page, size int, filters *SearchFilters) (map[string][]Track, map[string]*PageInfo, []error) {
-	providers := r.GetEnabledProviders()
+	providers := r.availableProviders()
 	results := make(map[string][]Track)
 	pageInfos := make(map[string]*PageInfo)
 	errors := make([]error, 0)
@@ -129,7 +188,8 @@ func (r *ProviderRegistry) SearchAllProviders(ctx context.Context, query string,
 	return results, pageInfos, errors
 }
 
-// HealthCheckAll checks health of all providers
+// HealthCheckAll checks health of all providers. Providers with an open circuit
+// report ErrCircuitOpen without being probed.
 func (r *ProviderRegistry) HealthCheckAll(ctx context.Context) map[string]error {
 	providers := r.GetEnabledProviders()
 	results := make(map[string]error)
@@ -166,22 +226,27 @@ func (r *ProviderRegistry) HealthCheckAll(ctx context.Context) map[string]error
 type MusicService struct {
 	registry *ProviderRegistry
 	config   *ProviderConfig
+	cache    *redis.Client
 }
 
-// NewMusicService creates a new music service
-func NewMusicService(enabledProviders []string, timeout time.Duration, cacheTTL time.Duration, spotifyClientID, spotifyClientSecret string) *MusicService {
-	config := &ProviderConfig{
-		Timeout:   timeout,
-		RateLimit: 100,
-		CacheTTL:  cacheTTL,
-		UserAgent: "music-app-backend/1.0",
+// NewMusicService creates a new music service. When cache is non-nil, provider
+// responses are cached in Redis using the TTLs from config.
+func NewMusicService(enabledProviders []string, config *ProviderConfig, cache *redis.Client, spotifyClientID, spotifyClientSecret string) *MusicService {
+	if config.UserAgent == "" {
+		config.UserAgent = "music-app-backend/1.0"
 	}
 
-	registry := NewProviderRegistry(enabledProviders)
+	registry := NewProviderRegistry(enabledProviders, config)
 
 	service := &MusicService{
 		registry: registry,
 		config:   config,
+		cache:    cache,
+	}
+
+	// Cache outside the guard so cache hits don't consume rate limit tokens
+	if cache != nil {
+		registry.Use(service.withCache)
 	}
 
 	// Initialize enabled providers
@@ -212,8 +277,15 @@ func (m *MusicService) initializeProviders(enabledProviders []string, spotifyCli
 	}
 }
 
-// SearchTracks searches for tracks using a specific provider or all providers
-func (m *MusicService) SearchTracks(ctx context.Context, provider, query string, page, size int, filters *SearchFilters) ([]Track, *PageInfo, error) {
+// withCache wraps a provider with the response cache
+func (m *MusicService) withCache(provider MusicProvider) MusicProvider {
+	return NewCachedProvider(provider, m.cache, m.config)
+}
+
+// SearchTracks searches for tracks using a specific provider or all providers.
+// Searches across all providers are merged, de-duplicated and paged by cursor
+// rather than page number, so they return ErrMergedPage for any page but the first.
+func (m *MusicService) SearchTracks(ctx context.Context, provider, query, cursor string, page, size int, filters *SearchFilters) ([]Track, *PageInfo, error) {
 	if provider != "" {
 		// Search using specific provider
 		p, err := m.registry.GetProvider(provider)
@@ -223,38 +295,10 @@ func (m *MusicService) SearchTracks(ctx context.Context, provider, query string,
 		return p.SearchTracks(ctx, query, page, size, filters)
 	}
 
-	// Search using all providers and combine results
-	allResults, allPageInfos, errors := m.registry.SearchAllProviders(ctx, query, page, size, filters)
-	
-	if len(allResults) == 0 {
-		if len(errors) > 0 {
-			return nil, nil, errors[0]
-		}
-		return []Track{}, &PageInfo{Page: page, Size: size}, nil
-	}
-
-	// Combine results from all providers
-	var combinedTracks []Track
-	var totalResults int64
-
-	for providerName, tracks := range allResults {
-		combinedTracks = append(combinedTracks, tracks...)
-		if pageInfo, exists := allPageInfos[providerName]; exists {
-			totalResults += pageInfo.Total
-		}
-	}
-
-	// Create combined page info
-	pageInfo := &PageInfo{
-		Page:       page,
-		Size:       size,
-		Total:      totalResults,
-		HasNext:    len(combinedTracks) == size,
-		HasPrev:    page > 1,
-		TotalPages: int(totalResults+int64(size)-1) / size,
+	if page > 1 {
+		return nil, nil, ErrMergedPage
 	}
-
-	return combinedTracks, pageInfo, nil
+	return m.searchMerged(ctx, query, cursor, size, filters)
 }
 
 // GetTrack gets a specific track from a provider
@@ -299,12 +343,35 @@ func (m *MusicService) GetPlaylistsByCategory(ctx context.Context, provider, cat
 	return p.GetPlaylistsByCategory(ctx, categoryID, page, size)
 }
 
+// GetPlaylistTracks gets the tracks of a playlist from a provider
+func (m *MusicService) GetPlaylistTracks(ctx context.Context, provider, playlistID string, page, size int) ([]Track, *PageInfo, error) {
+	p, err := m.registry.GetProvider(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.GetPlaylistTracks(ctx, playlistID, page, size)
+}
+
 // HealthCheck checks the health of all providers
 func (m *MusicService) HealthCheck(ctx context.Context) map[string]error {
 	return m.registry.HealthCheckAll(ctx)
 }
 
+// CheckProvider checks the health of a single provider
+func (m *MusicService) CheckProvider(ctx context.Context, provider string) error {
+	p, err := m.registry.GetProvider(provider)
+	if err != nil {
+		return err
+	}
+	return p.IsHealthy(ctx)
+}
+
 // GetProviderNames returns names of all enabled providers
 func (m *MusicService) GetProviderNames() []string {
 	return m.registry.GetProviderNames()
 }
+
+// CircuitStates returns the circuit breaker state of every provider
+func (m *MusicService) CircuitStates() map[string]CircuitState {
+	return m.registry.CircuitStates()
+}
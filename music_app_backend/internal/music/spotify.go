@@ -11,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/tracing"
 )
 
 // SpotifyProvider implements the MusicProvider interface for Spotify Web API
@@ -35,6 +37,7 @@ type SpotifyTrack struct {
 	PreviewURL   *string             `json:"preview_url"`
 	TrackNumber  int                 `json:"track_number"`
 	ExternalUrls SpotifyExternalUrls `json:"external_urls"`
+	ExternalIDs  SpotifyExternalIDs  `json:"external_ids"`
 }
 
 type SpotifyArtist struct {
@@ -60,6 +63,10 @@ type SpotifyExternalUrls struct {
 	Spotify string `json:"spotify"`
 }
 
+type SpotifyExternalIDs struct {
+	ISRC string `json:"isrc"`
+}
+
 type SpotifySearchResponse struct {
 	Tracks SpotifyTracksResponse `json:"tracks"`
 }
@@ -73,6 +80,19 @@ type SpotifyTracksResponse struct {
 	Next     *string        `json:"next"`
 }
 
+// SpotifyPlaylistTracksResponse is a page of playlist items. Track is null for items that
+// are no longer available.
+type SpotifyPlaylistTracksResponse struct {
+	Items []struct {
+		Track *SpotifyTrack `json:"track"`
+	} `json:"items"`
+	Total    int     `json:"total"`
+	Limit    int     `json:"limit"`
+	Offset   int     `json:"offset"`
+	Previous *string `json:"previous"`
+	Next     *string `json:"next"`
+}
+
 type SpotifyCategory struct {
 	ID    string         `json:"id"`
 	Name  string         `json:"name"`
@@ -120,7 +140,7 @@ type SpotifyTokenResponse struct {
 func NewSpotifyProvider(config *ProviderConfig, clientID, clientSecret string) *SpotifyProvider {
 	return &SpotifyProvider{
 		config:       config,
-		httpClient:   &http.Client{Timeout: config.Timeout},
+		httpClient:   &http.Client{Timeout: config.Timeout, Transport: tracedTransport()},
 		clientID:     clientID,
 		clientSecret: clientSecret,
 	}
@@ -137,6 +157,9 @@ func (s *SpotifyProvider) ensureToken(ctx context.Context) error {
 		return nil
 	}
 
+	ctx, span := tracing.Tracer().Start(ctx, "spotify.token")
+	defer span.End()
+
 	// Get client credentials token
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
@@ -297,15 +320,12 @@ func (s *SpotifyProvider) GetTopCharts(ctx context.Context, country string, page
 		return nil, nil, NewProviderError("spotify", fmt.Sprintf("API error: %d %s", resp.StatusCode, string(body)), "API_ERROR", nil)
 	}
 
-	var tracksResp SpotifyTracksResponse
+	var tracksResp SpotifyPlaylistTracksResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tracksResp); err != nil {
 		return nil, nil, NewProviderError("spotify", "Failed to decode response", "DECODE_ERROR", err)
 	}
 
-	tracks := make([]Track, len(tracksResp.Items))
-	for i, spotifyTrack := range tracksResp.Items {
-		tracks[i] = s.convertTrack(spotifyTrack)
-	}
+	tracks := s.convertPlaylistTracks(tracksResp)
 
 	pageInfo := &PageInfo{
 		Page:       page,
@@ -422,6 +442,52 @@ func (s *SpotifyProvider) GetPlaylistsByCategory(ctx context.Context, categoryID
 	return playlists, pageInfo, nil
 }
 
+// GetPlaylistTracks gets the tracks of a playlist
+func (s *SpotifyProvider) GetPlaylistTracks(ctx context.Context, playlistID string, page, size int) ([]Track, *PageInfo, error) {
+	if size > 50 {
+		size = 50
+	}
+
+	offset := (page - 1) * size
+
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(size))
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("market", "US")
+
+	endpoint := "/playlists/" + url.PathEscape(playlistID) + "/tracks?" + params.Encode()
+	resp, err := s.makeRequest(ctx, endpoint)
+	if err != nil {
+		return nil, nil, NewProviderError("spotify", "Get playlist tracks failed", "PLAYLIST_TRACKS_ERROR", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, NewProviderError("spotify", "Playlist not found", "NOT_FOUND", nil)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, nil, NewProviderError("spotify", fmt.Sprintf("API error: %d %s", resp.StatusCode, string(body)), "API_ERROR", nil)
+	}
+
+	var tracksResp SpotifyPlaylistTracksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tracksResp); err != nil {
+		return nil, nil, NewProviderError("spotify", "Failed to decode response", "DECODE_ERROR", err)
+	}
+
+	pageInfo := &PageInfo{
+		Page:       page,
+		Size:       size,
+		Total:      int64(tracksResp.Total),
+		HasNext:    tracksResp.Next != nil,
+		HasPrev:    tracksResp.Previous != nil,
+		TotalPages: (tracksResp.Total + size - 1) / size,
+	}
+
+	return s.convertPlaylistTracks(tracksResp), pageInfo, nil
+}
+
 // IsHealthy checks if the provider is healthy
 func (s *SpotifyProvider) IsHealthy(ctx context.Context) error {
 	if err := s.ensureToken(ctx); err != nil {
@@ -442,6 +508,18 @@ func (s *SpotifyProvider) IsHealthy(ctx context.Context) error {
 	return nil
 }
 
+// convertPlaylistTracks converts the available tracks of a playlist page
+func (s *SpotifyProvider) convertPlaylistTracks(tracksResp SpotifyPlaylistTracksResponse) []Track {
+	tracks := make([]Track, 0, len(tracksResp.Items))
+	for _, item := range tracksResp.Items {
+		if item.Track == nil || item.Track.ID == "" {
+			continue
+		}
+		tracks = append(tracks, s.convertTrack(*item.Track))
+	}
+	return tracks
+}
+
 // convertTrack converts a Spotify track to our Track format
 func (s *SpotifyProvider) convertTrack(spotifyTrack SpotifyTrack) Track {
 	artistNames := make([]string, len(spotifyTrack.Artists))
@@ -473,5 +551,6 @@ func (s *SpotifyProvider) convertTrack(spotifyTrack SpotifyTrack) Track {
 		ExternalURL: spotifyTrack.ExternalUrls.Spotify,
 		Explicit:    spotifyTrack.Explicit,
 		Popularity:  spotifyTrack.Popularity,
+		ISRC:        spotifyTrack.ExternalIDs.ISRC,
 	}
 }
@@ -23,6 +23,7 @@ type Playlist struct {
 	CoverURL    *string   `gorm:"size:1024"`
 	IsPublic    bool      `gorm:"default:false"`
 	ShareCode   *string   `gorm:"uniqueIndex;size:10"`
+	Version     int       `gorm:"not null;default:1"` // incremented on every change to the track list
 	Tracks      []PlaylistTrack `gorm:"foreignKey:PlaylistID;constraint:OnDelete:CASCADE;"`
 	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
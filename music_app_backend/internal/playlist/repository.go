@@ -2,9 +2,12 @@ package playlist
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/storage"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository provides access to the playlist storage.
@@ -22,10 +25,12 @@ func (r *Repository) Create(ctx context.Context, playlist *Playlist) error {
 	return r.db.WithContext(ctx).Create(playlist).Error
 }
 
-// GetByID retrieves a playlist by its ID, preloading its tracks.
+// GetByID retrieves a playlist by its ID, preloading its tracks in position order.
 func (r *Repository) GetByID(ctx context.Context, playlistID uuid.UUID) (*Playlist, error) {
 	var playlist Playlist
-	err := r.db.WithContext(ctx).Preload("Tracks").First(&playlist, playlistID).Error
+	err := r.db.WithContext(ctx).
+		Preload("Tracks", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		First(&playlist, playlistID).Error
 	return &playlist, err
 }
 
@@ -46,9 +51,58 @@ func (r *Repository) GetUserPlaylists(ctx context.Context, userID uuid.UUID, pag
 	return playlists, total, err
 }
 
-// Update updates a playlist's details.
-func (r *Repository) Update(ctx context.Context, playlist *Playlist) error {
-	return r.db.WithContext(ctx).Save(playlist).Error
+// ListPublicPlaylists retrieves a paginated list of public playlists, optionally filtered by title.
+func (r *Repository) ListPublicPlaylists(ctx context.Context, query string, page, size int) ([]Playlist, int64, error) {
+	var playlists []Playlist
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&Playlist{}).Where("is_public = ?", true)
+	if query != "" {
+		db = db.Where(`title ILIKE ? ESCAPE '\'`, "%"+storage.EscapeLike(query)+"%")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Order("created_at DESC").Limit(size).Offset(offset).Find(&playlists).Error
+
+	return playlists, total, err
+}
+
+// Unpublish makes a playlist private and invalidates its share code.
+func (r *Repository) Unpublish(ctx context.Context, playlistID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&Playlist{}).
+		Where("id = ?", playlistID).
+		Updates(map[string]interface{}{
+			"is_public":  false,
+			"share_code": nil,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Update applies updates to a playlist's details. The version is left alone, as it only
+// tracks changes to the track list.
+func (r *Repository) Update(ctx context.Context, playlistID uuid.UUID, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	result := r.db.WithContext(ctx).Model(&Playlist{}).
+		Where("id = ?", playlistID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 // Delete removes a playlist.
@@ -57,9 +111,22 @@ func (r *Repository) Delete(ctx context.Context, playlistID uuid.UUID) error {
 	return r.db.WithContext(ctx).Select("Tracks").Delete(&Playlist{ID: playlistID}).Error
 }
 
-// AddTrack adds a track to a playlist.
+// AddTrack appends a track to the end of a playlist, assigning its position.
 func (r *Repository) AddTrack(ctx context.Context, track *PlaylistTrack) error {
-	return r.db.WithContext(ctx).Create(track).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Bumping the version first locks the playlist row, serializing concurrent appends
+		if err := bumpVersion(tx, track.PlaylistID, nil); err != nil {
+			return err
+		}
+
+		var maxPos int
+		if err := tx.Model(&PlaylistTrack{}).Where("playlist_id = ?", track.PlaylistID).Select("COALESCE(MAX(position), 0)").Row().Scan(&maxPos); err != nil {
+			return err
+		}
+
+		track.Position = maxPos + 1
+		return tx.Create(track).Error
+	})
 }
 
 // GetTrack retrieves a specific track from a playlist.
@@ -69,9 +136,32 @@ func (r *Repository) GetTrack(ctx context.Context, playlistID, trackID uuid.UUID
 	return &track, err
 }
 
-// RemoveTrack removes a track from a playlist.
+// RemoveTrack removes a track from a playlist and shifts later tracks up so positions stay contiguous.
 func (r *Repository) RemoveTrack(ctx context.Context, playlistID, trackID uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("playlist_id = ? AND id = ?", playlistID, trackID).Delete(&PlaylistTrack{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, playlistID, nil); err != nil {
+			return err
+		}
+
+		var track PlaylistTrack
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("playlist_id = ? AND id = ?", playlistID, trackID).
+			First(&track).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrTrackNotFound
+			}
+			return err
+		}
+
+		if err := tx.Delete(&track).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&PlaylistTrack{}).
+			Where("playlist_id = ? AND position > ?", playlistID, track.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error
+	})
 }
 
 // GetMaxPosition returns the highest position number for tracks in a playlist.
@@ -81,7 +171,48 @@ func (r *Repository) GetMaxPosition(ctx context.Context, playlistID uuid.UUID) (
 	return maxPos, err
 }
 
-// UpdateTrackPositions updates the positions of multiple tracks in a playlist.
-func (r *Repository) UpdateTrackPositions(ctx context.Context, tracks []PlaylistTrack) error {
-	return r.db.WithContext(ctx).Save(&tracks).Error
+// UpdateTrackPositions rewrites track positions in a single transaction. It fails with
+// ErrVersionConflict if the playlist's version no longer matches expectedVersion.
+func (r *Repository) UpdateTrackPositions(ctx context.Context, playlistID uuid.UUID, expectedVersion int, positions []TrackPosition) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, playlistID, &expectedVersion); err != nil {
+			return err
+		}
+
+		for _, p := range positions {
+			if err := tx.Model(&PlaylistTrack{}).
+				Where("playlist_id = ? AND id = ?", playlistID, p.TrackID).
+				UpdateColumn("position", p.Position).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// bumpVersion increments a playlist's version inside tx, locking its row until the
+// transaction ends. When expectedVersion is set the update only applies if it matches.
+func bumpVersion(tx *gorm.DB, playlistID uuid.UUID, expectedVersion *int) error {
+	query := tx.Model(&Playlist{}).Where("id = ?", playlistID)
+	if expectedVersion != nil {
+		query = query.Where("version = ?", *expectedVersion)
+	}
+
+	result := query.UpdateColumns(map[string]interface{}{
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		if expectedVersion != nil {
+			return ErrVersionConflict
+		}
+		return ErrPlaylistNotFound
+	}
+
+	return nil
 }
\ No newline at end of file
@@ -1,4 +1,3 @@
-
 package playlist
 
 import (
@@ -12,9 +11,11 @@ import (
 )
 
 var (
-	ErrPlaylistNotFound = errors.New("playlist not found")
-	ErrTrackNotFound    = errors.New("track not found in playlist")
-	ErrNotPlaylistOwner = errors.New("user is not the owner of the playlist")
+	ErrPlaylistNotFound  = errors.New("playlist not found")
+	ErrTrackNotFound     = errors.New("track not found in playlist")
+	ErrNotPlaylistOwner  = errors.New("user is not the owner of the playlist")
+	ErrVersionConflict   = errors.New("playlist was modified by another request")
+	ErrInvalidTrackOrder = errors.New("track order must list every track in the playlist exactly once")
 )
 
 // Service provides playlist business logic.
@@ -41,10 +42,11 @@ func (s *Service) CreatePlaylist(ctx context.Context, userIDStr, title, descript
 		Title:       title,
 		Description: description,
 		IsPublic:    false, // Default to private
+		Version:     1,
 	}
 
 	if err := s.repo.Create(ctx, playlist); err != nil {
-		s.logger.Error("failed to create playlist", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to create playlist", "error", err, "userID", userID)
 		return nil, err
 	}
 
@@ -63,7 +65,7 @@ func (s *Service) GetPlaylist(ctx context.Context, playlistIDStr, userIDStr stri
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrPlaylistNotFound
 		}
-		s.logger.Error("failed to get playlist by id", "error", err, "playlistID", playlistID)
+		s.logger.WithContext(ctx).Error("failed to get playlist by id", "error", err, "playlistID", playlistID)
 		return nil, err
 	}
 
@@ -95,7 +97,7 @@ func (s *Service) GetUserPlaylists(ctx context.Context, userIDStr string, page,
 
 	playlists, total, err := s.repo.GetUserPlaylists(ctx, userID, page, size)
 	if err != nil {
-		s.logger.Error("failed to get user playlists", "error", err, "userID", userID)
+		s.logger.WithContext(ctx).Error("failed to get user playlists", "error", err, "userID", userID)
 		return nil, 0, err
 	}
 
@@ -109,18 +111,25 @@ func (s *Service) UpdatePlaylist(ctx context.Context, playlistIDStr, userIDStr s
 		return nil, err
 	}
 
+	updates := map[string]interface{}{}
 	if title != nil {
 		playlist.Title = *title
+		updates["title"] = *title
 	}
 	if description != nil {
 		playlist.Description = *description
+		updates["description"] = *description
 	}
 	if isPublic != nil {
 		playlist.IsPublic = *isPublic
+		updates["is_public"] = *isPublic
+	}
+	if len(updates) == 0 {
+		return playlist, nil
 	}
 
-	if err := s.repo.Update(ctx, playlist); err != nil {
-		s.logger.Error("failed to update playlist", "error", err, "playlistID", playlist.ID)
+	if err := s.repo.Update(ctx, playlist.ID, updates); err != nil {
+		s.logger.WithContext(ctx).Error("failed to update playlist", "error", err, "playlistID", playlist.ID)
 		return nil, err
 	}
 
@@ -135,7 +144,7 @@ func (s *Service) DeletePlaylist(ctx context.Context, playlistIDStr, userIDStr s
 	}
 
 	if err := s.repo.Delete(ctx, playlist.ID); err != nil {
-		s.logger.Error("failed to delete playlist", "error", err, "playlistID", playlist.ID)
+		s.logger.WithContext(ctx).Error("failed to delete playlist", "error", err, "playlistID", playlist.ID)
 		return err
 	}
 
@@ -149,12 +158,6 @@ func (s *Service) AddTrackToPlaylist(ctx context.Context, playlistIDStr, userIDS
 		return nil, err
 	}
 
-	maxPos, err := s.repo.GetMaxPosition(ctx, playlist.ID)
-	if err != nil {
-		s.logger.Error("failed to get max position for track", "error", err, "playlistID", playlist.ID)
-		return nil, err
-	}
-
 	newTrack := &PlaylistTrack{
 		ID:              uuid.New(),
 		PlaylistID:      playlist.ID,
@@ -165,12 +168,11 @@ func (s *Service) AddTrackToPlaylist(ctx context.Context, playlistIDStr, userIDS
 		Album:           data.Album,
 		DurationMs:      data.DurationMs,
 		ArtworkURL:      data.ArtworkURL,
-		Position:        maxPos + 1,
 		AddedAt:         time.Now(),
 	}
 
 	if err := s.repo.AddTrack(ctx, newTrack); err != nil {
-		s.logger.Error("failed to add track to playlist", "error", err, "playlistID", playlist.ID)
+		s.logger.WithContext(ctx).Error("failed to add track to playlist", "error", err, "playlistID", playlist.ID)
 		return nil, err
 	}
 
@@ -190,25 +192,159 @@ func (s *Service) RemoveTrackFromPlaylist(ctx context.Context, playlistIDStr, us
 	}
 
 	if err := s.repo.RemoveTrack(ctx, playlist.ID, trackID); err != nil {
-		s.logger.Error("failed to remove track from playlist", "error", err, "playlistID", playlist.ID, "trackID", trackID)
+		if errors.Is(err, ErrTrackNotFound) {
+			return nil, err
+		}
+		s.logger.WithContext(ctx).Error("failed to remove track from playlist", "error", err, "playlistID", playlist.ID, "trackID", trackID)
 		return nil, err
 	}
 
 	return s.repo.GetByID(ctx, playlist.ID)
 }
 
-// ReorderPlaylistTracks reorders tracks within a playlist.
-func (s *Service) ReorderPlaylistTracks(ctx context.Context, playlistIDStr, userIDStr string, trackPositions []TrackPosition) error {
-	_, err := s.getAndVerifyOwner(ctx, playlistIDStr, userIDStr)
+// ReorderPlaylistTracks replaces the order of a playlist's tracks. trackIDs must list
+// every track in the playlist exactly once, and version must match the playlist's
+// current version so that reorders based on a stale view are rejected.
+func (s *Service) ReorderPlaylistTracks(ctx context.Context, playlistIDStr, userIDStr string, trackIDStrs []string, version int) (*Playlist, error) {
+	playlist, err := s.getAndVerifyOwner(ctx, playlistIDStr, userIDStr)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if playlist.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	if len(trackIDStrs) != len(playlist.Tracks) {
+		return nil, ErrInvalidTrackOrder
+	}
+
+	existing := make(map[uuid.UUID]bool, len(playlist.Tracks))
+	for _, track := range playlist.Tracks {
+		existing[track.ID] = true
+	}
+
+	order := make([]uuid.UUID, 0, len(trackIDStrs))
+	for _, idStr := range trackIDStrs {
+		trackID, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, errors.New("invalid track ID format")
+		}
+		if !existing[trackID] {
+			return nil, ErrInvalidTrackOrder
+		}
+		// Each track may only appear once
+		delete(existing, trackID)
+		order = append(order, trackID)
+	}
+
+	return s.applyTrackOrder(ctx, playlist, order)
+}
+
+// MoveTrack moves a single track to a new 1-based position, shifting the tracks in
+// between. Positions past the end of the playlist move the track to the end.
+func (s *Service) MoveTrack(ctx context.Context, playlistIDStr, userIDStr, trackIDStr string, position, version int) (*Playlist, error) {
+	playlist, err := s.getAndVerifyOwner(ctx, playlistIDStr, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	trackID, err := uuid.Parse(trackIDStr)
+	if err != nil {
+		return nil, errors.New("invalid track ID format")
+	}
+
+	if playlist.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	order := make([]uuid.UUID, 0, len(playlist.Tracks))
+	found := false
+	for _, track := range playlist.Tracks {
+		if track.ID == trackID {
+			found = true
+			continue
+		}
+		order = append(order, track.ID)
+	}
+	if !found {
+		return nil, ErrTrackNotFound
+	}
+
+	index := position - 1
+	if index < 0 {
+		index = 0
+	}
+	if index > len(order) {
+		index = len(order)
+	}
+
+	order = append(order, uuid.Nil)
+	copy(order[index+1:], order[index:])
+	order[index] = trackID
+
+	return s.applyTrackOrder(ctx, playlist, order)
+}
+
+// applyTrackOrder persists order as contiguous positions starting at 1 and returns the updated playlist.
+func (s *Service) applyTrackOrder(ctx context.Context, playlist *Playlist, order []uuid.UUID) (*Playlist, error) {
+	positions := make([]TrackPosition, len(order))
+	for i, trackID := range order {
+		positions[i] = TrackPosition{TrackID: trackID, Position: i + 1}
+	}
+
+	if err := s.repo.UpdateTrackPositions(ctx, playlist.ID, playlist.Version, positions); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			return nil, err
+		}
+		s.logger.WithContext(ctx).Error("failed to update track positions", "error", err, "playlistID", playlist.ID)
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, playlist.ID)
+}
+
+// ListPublicPlaylists retrieves public playlists for moderation, optionally filtered by title.
+func (s *Service) ListPublicPlaylists(ctx context.Context, query string, page, size int) ([]Playlist, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 20
+	}
+
+	playlists, total, err := s.repo.ListPublicPlaylists(ctx, query, page, size)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("failed to list public playlists", "error", err)
+		return nil, 0, err
 	}
 
-	// This is a complex operation and would require a transaction and careful updates.
-	// The simplified version is removed to avoid introducing buggy behavior.
-	// A full implementation would fetch all tracks, reorder them in memory, and then update all positions in a single transaction.
-	s.logger.Warn("ReorderPlaylistTracks is not fully implemented and is a complex operation.")
-	return errors.New("reordering tracks is not implemented yet")
+	return playlists, total, nil
+}
+
+// UnpublishPlaylist makes any playlist private and revokes its share link, regardless of
+// ownership. It is intended for moderators.
+func (s *Service) UnpublishPlaylist(ctx context.Context, playlistIDStr string) (*Playlist, error) {
+	playlistID, err := uuid.Parse(playlistIDStr)
+	if err != nil {
+		return nil, errors.New("invalid playlist ID format")
+	}
+
+	if err := s.repo.Unpublish(ctx, playlistID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlaylistNotFound
+		}
+		s.logger.WithContext(ctx).Error("failed to unpublish playlist", "error", err, "playlistID", playlistID)
+		return nil, err
+	}
+
+	playlist, err := s.repo.GetByID(ctx, playlistID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("failed to get playlist by id", "error", err, "playlistID", playlistID)
+		return nil, err
+	}
+
+	return playlist, nil
 }
 
 // getAndVerifyOwner is a helper function to get a playlist and check if the user is the owner.
@@ -228,7 +364,7 @@ func (s *Service) getAndVerifyOwner(ctx context.Context, playlistIDStr, userIDSt
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrPlaylistNotFound
 		}
-		s.logger.Error("failed to get playlist by id for ownership check", "error", err, "playlistID", playlistID)
+		s.logger.WithContext(ctx).Error("failed to get playlist by id for ownership check", "error", err, "playlistID", playlistID)
 		return nil, err
 	}
 
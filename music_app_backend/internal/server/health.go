@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mosesmmoisebidth/music_backend/internal/health"
+	"github.com/mosesmmoisebidth/music_backend/internal/migrations"
+	"github.com/mosesmmoisebidth/music_backend/pkg/response"
+)
+
+// newHealthChecker builds the readiness checks. The database, Redis and schema version are
+// critical; music providers only degrade search, so their failures don't make the service
+// unready.
+func (s *Server) newHealthChecker() (*health.Checker, error) {
+	cfg := s.config.Health
+	checkTimeout := parseDuration(cfg.CheckTimeout, 2*time.Second)
+
+	sqlDB, err := s.storage.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	migrator, err := migrations.New(sqlDB, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []health.Check{
+		{
+			Name:     "database",
+			Critical: true,
+			Timeout:  checkTimeout,
+			Run: func(ctx context.Context) (string, error) {
+				return "", s.storage.PingDatabase(ctx)
+			},
+		},
+		{
+			Name:     "redis",
+			Critical: true,
+			Timeout:  checkTimeout,
+			Run: func(ctx context.Context) (string, error) {
+				return "", s.storage.PingRedis(ctx)
+			},
+		},
+		{
+			Name:     "migrations",
+			Critical: true,
+			Timeout:  checkTimeout,
+			Run: func(ctx context.Context) (string, error) {
+				version, err := migrator.Version(ctx)
+				if err != nil {
+					return "", fmt.Errorf("failed to read schema version: %w", err)
+				}
+				if version < migrator.Latest() {
+					return "", fmt.Errorf("schema is at version %d, expected %d", version, migrator.Latest())
+				}
+				return "version " + strconv.FormatInt(version, 10), nil
+			},
+		},
+	}
+
+	providerTimeout := parseDuration(cfg.ProviderTimeout, 5*time.Second)
+	providerCacheTTL := parseDuration(cfg.ProviderCacheTTL, 30*time.Second)
+	for _, name := range s.musicService.GetProviderNames() {
+		checks = append(checks, health.Check{
+			Name:     "provider:" + name,
+			Timeout:  providerTimeout,
+			CacheFor: providerCacheTTL,
+			Run: func(ctx context.Context) (string, error) {
+				return "circuit " + string(s.musicService.CircuitStates()[name]), s.musicService.CheckProvider(ctx, name)
+			},
+		})
+	}
+
+	checker := health.NewChecker()
+	for _, check := range checks {
+		if err := checker.Register(check); err != nil {
+			return nil, err
+		}
+	}
+	return checker, nil
+}
+
+// liveness handles liveness probes. It checks no dependencies, so an outage of the database
+// or a provider doesn't get the process restarted.
+func (s *Server) liveness(c *gin.Context) {
+	response.Success(c, gin.H{
+		"status":    "ok",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// verboseReadinessAccess restricts /readyz?verbose=true, whose check errors describe the
+// infrastructure, to the scrapers allowed to read metrics. Plain probes stay open. Production
+// without a metrics token or allowed networks has no one to allow, so it refuses verbose mode.
+func (s *Server) verboseReadinessAccess(metricsAccess gin.HandlerFunc) gin.HandlerFunc {
+	unprotected := s.config.Metrics.BearerToken == "" && len(s.config.Metrics.AllowedNetworks) == 0
+	return func(c *gin.Context) {
+		if verbose, _ := strconv.ParseBool(c.Query("verbose")); !verbose {
+			c.Next()
+			return
+		}
+		if unprotected && s.config.App.Environment == "production" {
+			response.Forbidden(c, "FORBIDDEN", "Verbose readiness requires a metrics bearer token or allowed networks")
+			c.Abort()
+			return
+		}
+		metricsAccess(c)
+	}
+}
+
+// readiness handles readiness probes, answering 503 while the database, Redis or schema is
+// not usable. Add ?verbose=true for every check's latency and last error, which is subject to
+// the metrics access rules.
+func (s *Server) readiness(c *gin.Context) {
+	report := s.health.Run(c.Request.Context())
+
+	data := gin.H{
+		"status":    report.Status,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	failed := []string{}
+	for _, result := range report.Checks {
+		if !result.Healthy {
+			failed = append(failed, result.Name)
+		}
+	}
+	if len(failed) > 0 {
+		data["failed"] = failed
+	}
+
+	if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose {
+		checks := make([]gin.H, 0, len(report.Checks))
+		for _, result := range report.Checks {
+			check := gin.H{
+				"name":       result.Name,
+				"healthy":    result.Healthy,
+				"critical":   result.Critical,
+				"latency_ms": float64(result.Latency.Microseconds()) / 1000,
+				"checked_at": result.CheckedAt.UTC().Format(time.RFC3339),
+				"cached":     result.Cached,
+			}
+			if result.Info != "" {
+				check["info"] = result.Info
+			}
+			if result.Error != "" {
+				check["error"] = result.Error
+			}
+			if result.LastErrorAt != nil {
+				check["last_error"] = result.LastError
+				check["last_error_at"] = result.LastErrorAt.UTC().Format(time.RFC3339)
+			}
+			checks = append(checks, check)
+		}
+		data["checks"] = checks
+	}
+
+	if report.Status == health.StatusUnavailable {
+		s.logger.WithContext(c.Request.Context()).Warn("Readiness check failed", "checks", failed)
+		response.ServiceUnavailable(c, "NOT_READY", "Service is not ready", data)
+		return
+	}
+	response.Success(c, data)
+}
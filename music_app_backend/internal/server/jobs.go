@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/account"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/jobs"
+	"github.com/mosesmmoisebidth/music_backend/internal/library"
+)
+
+// newScheduler builds the maintenance job scheduler from the jobs configuration
+func (s *Server) newScheduler(authService *auth.AuthService, oneTimeTokens *auth.OneTimeTokenService, auditLog *auth.AuditLog, libraryService *library.Service, accountService *account.Service) (*jobs.Scheduler, error) {
+	cfg := s.config.Jobs
+	scheduler := jobs.NewScheduler(s.storage.Redis, s.logger)
+
+	historyRetention := parseDuration(cfg.HistoryRetention, 180*24*time.Hour)
+	staleDownloadAfter := parseDuration(cfg.StaleDownloadAfter, time.Hour)
+	authEventRetention := parseDuration(cfg.AuthEventRetention, 90*24*time.Hour)
+
+	definitions := []struct {
+		name     string
+		schedule string
+		timeout  time.Duration
+		run      func(ctx context.Context) (string, error)
+	}{
+		{
+			name:     "expired_token_cleanup",
+			schedule: cfg.TokenCleanupSchedule,
+			timeout:  5 * time.Minute,
+			run: func(ctx context.Context) (string, error) {
+				removed, err := authService.CleanupExpiredTokens(ctx)
+				if err != nil {
+					return "", err
+				}
+				removedOneTime, err := oneTimeTokens.CleanupExpiredTokens(ctx)
+				return fmt.Sprintf("removed %d expired refresh tokens and %d expired one-time tokens", removed, removedOneTime), err
+			},
+		},
+		{
+			name:     "history_pruning",
+			schedule: cfg.HistoryPruneSchedule,
+			timeout:  30 * time.Minute,
+			run: func(ctx context.Context) (string, error) {
+				deleted, err := libraryService.PruneHistory(ctx, historyRetention)
+				return fmt.Sprintf("deleted %d history entries older than %s", deleted, historyRetention), err
+			},
+		},
+		{
+			name:     "auth_event_pruning",
+			schedule: cfg.AuthEventPruneSchedule,
+			timeout:  30 * time.Minute,
+			run: func(ctx context.Context) (string, error) {
+				deleted, err := auditLog.Prune(ctx, authEventRetention)
+				return fmt.Sprintf("deleted %d auth events older than %s", deleted, authEventRetention), err
+			},
+		},
+		{
+			name:     "stale_download_reaping",
+			schedule: cfg.DownloadReapSchedule,
+			timeout:  5 * time.Minute,
+			run: func(ctx context.Context) (string, error) {
+				reaped, err := libraryService.ReapStaleDownloads(ctx, staleDownloadAfter)
+				return fmt.Sprintf("failed %d stalled downloads", reaped), err
+			},
+		},
+		{
+			name:     "data_export_generation",
+			schedule: cfg.DataExportSchedule,
+			timeout:  30 * time.Minute,
+			run: func(ctx context.Context) (string, error) {
+				completed, err := accountService.GenerateExports(ctx)
+				return fmt.Sprintf("generated %d data exports", completed), err
+			},
+		},
+		{
+			name:     "account_purge",
+			schedule: cfg.AccountPurgeSchedule,
+			timeout:  30 * time.Minute,
+			run: func(ctx context.Context) (string, error) {
+				purged, err := accountService.PurgeDeletedAccounts(ctx)
+				if err != nil {
+					return "", err
+				}
+				removed, err := accountService.RemoveExpiredExports(ctx)
+				return fmt.Sprintf("purged %d deleted accounts and removed %d expired data exports", purged, removed), err
+			},
+		},
+	}
+
+	for _, d := range definitions {
+		schedule, err := jobs.ParseSchedule(d.schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", d.name, err)
+		}
+		if err := scheduler.Register(jobs.Job{Name: d.name, Schedule: schedule, Timeout: d.timeout, Run: d.run}); err != nil {
+			return nil, err
+		}
+	}
+
+	return scheduler, nil
+}
@@ -2,30 +2,53 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"time"
+
 	"github.com/gin-gonic/gin"
 	_ "github.com/mosesmmoisebidth/music_backend/docs" // This is required for swag to find docs
+	"github.com/mosesmmoisebidth/music_backend/internal/account"
 	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/blob"
+	"github.com/mosesmmoisebidth/music_backend/internal/buildinfo"
 	"github.com/mosesmmoisebidth/music_backend/internal/config"
+	"github.com/mosesmmoisebidth/music_backend/internal/health"
+	"github.com/mosesmmoisebidth/music_backend/internal/jobs"
 	"github.com/mosesmmoisebidth/music_backend/internal/library"
+	"github.com/mosesmmoisebidth/music_backend/internal/mail"
+	"github.com/mosesmmoisebidth/music_backend/internal/metrics"
 	"github.com/mosesmmoisebidth/music_backend/internal/middleware"
 	"github.com/mosesmmoisebidth/music_backend/internal/music"
 	"github.com/mosesmmoisebidth/music_backend/internal/playlist"
 	"github.com/mosesmmoisebidth/music_backend/internal/storage"
+	"github.com/mosesmmoisebidth/music_backend/internal/tracing"
 	httpTransport "github.com/mosesmmoisebidth/music_backend/internal/transport/http"
 	"github.com/mosesmmoisebidth/music_backend/internal/user"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 	"github.com/mosesmmoisebidth/music_backend/pkg/response"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang.org/x/crypto/hkdf"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	router  *gin.Engine
-	config  *config.Config
-	storage *storage.Storage
-	logger  logger.Logger
+	router         *gin.Engine
+	config         *config.Config
+	storage        *storage.Storage
+	logger         logger.Logger
+	metrics        *metrics.Metrics // nil when metrics are disabled
+	musicService   *music.MusicService
+	blobs          blob.Store
+	downloadWorker *library.DownloadWorker
+	scheduler      *jobs.Scheduler
+	health         *health.Checker
 }
 
 // New creates a new server instance
@@ -34,22 +57,52 @@ func New(cfg *config.Config, storage *storage.Storage, logger logger.Logger) (*S
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	blobs, err := blob.NewLocalStore(cfg.Downloads.StorageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize download storage: %w", err)
+	}
+
 	server := &Server{
 		config:  cfg,
 		storage: storage,
 		logger:  logger,
+		blobs:   blobs,
+	}
+
+	if cfg.Metrics.Enabled {
+		server.metrics = metrics.New()
+		if err := server.metrics.RegisterStorage(storage); err != nil {
+			return nil, fmt.Errorf("failed to initialize metrics: %w", err)
+		}
 	}
 
-	server.setupRouter()
+	if cfg.Tracing.Enabled {
+		if err := tracing.InstrumentGORM(storage.DB); err != nil {
+			return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+		}
+		tracing.InstrumentRedis(storage.Redis)
+	}
+
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 	return server, nil
 }
 
 // setupRouter configures the Gin router
-func (s *Server) setupRouter() {
+func (s *Server) setupRouter() error {
 	router := gin.New()
 
+	// Client IPs drive throttling and rate limits, so X-Forwarded-For is only believed from
+	// known proxies
+	if err := router.SetTrustedProxies(s.config.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	// Global middleware
 	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Metrics(s.metrics))
 	router.Use(middleware.Logger(s.logger))
 	router.Use(middleware.Recovery(s.logger))
 	router.Use(middleware.CORS(s.config.Server.CORS))
@@ -57,8 +110,19 @@ func (s *Server) setupRouter() {
 
 	// Health check and version endpoints
 	router.GET("/healthz", s.healthCheck)
+	router.GET("/livez", s.liveness)
+	metricsAccess, err := middleware.MetricsAccess(s.config.Metrics)
+	if err != nil {
+		return err
+	}
+	router.GET("/readyz", s.verboseReadinessAccess(metricsAccess), s.readiness)
 	router.GET("/version", s.versionInfo)
 
+	// Prometheus metrics
+	if s.metrics != nil {
+		router.GET(s.config.Metrics.Path, metricsAccess, gin.WrapH(s.metrics.Handler()))
+	}
+
 	// Swagger documentation route
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -72,71 +136,203 @@ func (s *Server) setupRouter() {
 	// Repositories
 	userRepo := user.NewRepository(s.storage.DB)
 	refreshTokenRepo := auth.NewRefreshTokenRepository(s.storage.DB)
+	oneTimeTokenRepo := auth.NewOneTimeTokenRepository(s.storage.DB)
+	authEventRepo := auth.NewAuthEventRepository(s.storage.DB)
+	mfaRepo := auth.NewMFARepository(s.storage.DB)
 	playlistRepo := playlist.NewRepository(s.storage.DB)
 	libraryRepo := library.NewRepository(s.storage.DB)
+	accountRepo := account.NewRepository(s.storage.DB)
 
 	// Services
-	jwtService := auth.NewJWTService(
-		s.config.Auth.JWTAccessSecret,
-		s.config.Auth.JWTRefreshSecret,
-		s.config.Auth.AccessTokenTTL,
-		s.config.Auth.RefreshTokenTTL,
-		s.config.Auth.JWTSigningMethod,
-	)
-	googleService := auth.NewGoogleService(s.config.Google.ClientID)
+	jwtConfig, err := s.jwtConfig()
+	if err != nil {
+		return err
+	}
+	jwtService, err := auth.NewJWTService(jwtConfig)
+	if err != nil {
+		return fmt.Errorf("failed to configure JWT signing: %w", err)
+	}
+	identityProviders, err := s.identityProviders()
+	if err != nil {
+		return err
+	}
+
+	sessionStore := auth.NewSessionStore(s.storage.Redis, s.config.Auth.AccessTokenTTL)
+	nonceStore := auth.NewNonceStore(s.storage.Redis, parseDuration(s.config.OIDC.NonceTTL, 10*time.Minute))
 
 	userService := user.NewService(userRepo, passwordHasher, s.logger)
-	authService := auth.NewAuthService(jwtService, googleService, refreshTokenRepo, s.logger)
+	authService := auth.NewAuthService(jwtService, identityProviders, refreshTokenRepo, sessionStore, nonceStore, userService, s.logger)
+	oneTimeTokenService := auth.NewOneTimeTokenService(oneTimeTokenRepo)
+	auditLog := auth.NewAuditLog(authEventRepo, s.logger)
+	lockoutDuration := parseDuration(s.config.Throttle.LockoutDuration, 30*time.Minute)
+	throttle := auth.NewThrottle(s.storage.Redis, auth.ThrottleConfig{
+		Window:           parseDuration(s.config.Throttle.Window, 15*time.Minute),
+		LoginIPLimit:     s.config.Throttle.LoginIPLimit,
+		DelayAfter:       s.config.Throttle.DelayAfter,
+		BaseDelay:        parseDuration(s.config.Throttle.BaseDelay, time.Second),
+		MaxDelay:         parseDuration(s.config.Throttle.MaxDelay, 30*time.Second),
+		LockoutThreshold: s.config.Throttle.LockoutThreshold,
+		LockoutDuration:  lockoutDuration,
+		RefreshIPLimit:   s.config.Throttle.RefreshIPLimit,
+		RegisterIPLimit:  s.config.Throttle.RegisterIPLimit,
+		RegisterWindow:   parseDuration(s.config.Throttle.RegisterWindow, time.Hour),
+	}, auditLog)
+	mfaEncryptionKey := s.config.Auth.MFAEncryptionKey
+	if mfaEncryptionKey == "" {
+		mfaEncryptionKey = derivedKey(s.config.Auth.JWTRefreshSecret, "music-app MFA secret encryption")
+	}
+	mfaService, err := auth.NewMFAService(mfaRepo, passwordHasher, s.storage.Redis, auth.MFAConfig{
+		Issuer:        s.config.Auth.MFAIssuer,
+		EncryptionKey: mfaEncryptionKey,
+		ChallengeTTL:  s.config.Auth.MFAChallengeTTL,
+	}, auditLog, s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to configure two-factor authentication: %w", err)
+	}
+	mailer, err := s.newMailer()
+	if err != nil {
+		return fmt.Errorf("failed to configure mailer: %w", err)
+	}
+	credentialsService := user.NewCredentialsService(userRepo, passwordHasher, oneTimeTokenService, authService, throttle, mailer, user.CredentialsConfig{
+		PasswordResetURL:     s.config.Mail.PasswordResetURL,
+		EmailVerificationURL: s.config.Mail.EmailVerificationURL,
+		AccountUnlockURL:     s.config.Mail.AccountUnlockURL,
+		PasswordResetTTL:     parseDuration(s.config.Mail.PasswordResetTTL, time.Hour),
+		EmailVerificationTTL: parseDuration(s.config.Mail.EmailVerificationTTL, 48*time.Hour),
+		AccountUnlockTTL:     lockoutDuration,
+	}, s.logger)
 	playlistService := playlist.NewService(playlistRepo, s.logger)
-	libraryService := library.NewService(libraryRepo, s.logger)
-	musicService := music.NewMusicService(
-		s.config.Providers.Enabled,
-		30*time.Second, // timeout
-		5*time.Minute,  // cache TTL
-		s.config.Spotify.ClientID,
-		s.config.Spotify.ClientSecret,
-	)
+	urlSigningSecret := s.config.Downloads.URLSigningSecret
+	if urlSigningSecret == "" {
+		urlSigningSecret = derivedKey(s.config.Auth.JWTRefreshSecret, "music-app download URL signing")
+	}
+	urlSigner := blob.NewURLSigner(urlSigningSecret, parseDuration(s.config.Downloads.SignedURLTTL, 15*time.Minute))
+	libraryService := library.NewService(libraryRepo, s.blobs, urlSigner, s.logger)
+	accountService := account.NewService(accountRepo, s.blobs, account.Config{
+		DeletionGracePeriod: parseDuration(s.config.Account.DeletionGracePeriod, 30*24*time.Hour),
+		ExportTTL:           parseDuration(s.config.Account.ExportTTL, 7*24*time.Hour),
+	}, s.logger)
+	providerConfig := &music.ProviderConfig{
+		Timeout:                 parseDuration(s.config.Providers.DefaultTimeout, 30*time.Second),
+		RateLimit:               s.config.Providers.RateLimit,
+		CacheTTL:                parseDuration(s.config.Providers.CacheTTL, 5*time.Minute),
+		TrackCacheTTL:           parseDuration(s.config.Providers.TrackCacheTTL, time.Hour),
+		CategoryCacheTTL:        parseDuration(s.config.Providers.CategoryCacheTTL, 24*time.Hour),
+		CacheStaleWindow:        parseDuration(s.config.Providers.CacheStaleWindow, time.Minute),
+		BreakerFailureThreshold: s.config.Providers.BreakerFailureThreshold,
+		BreakerOpenTimeout:      parseDuration(s.config.Providers.BreakerOpenTimeout, 30*time.Second),
+	}
+	if s.metrics != nil {
+		providerConfig.Observer = s.metrics
+	}
+	musicService := music.NewMusicService(s.config.Providers.Enabled, providerConfig, s.storage.Redis, s.config.Spotify.ClientID, s.config.Spotify.ClientSecret)
+	s.musicService = musicService
+
+	checker, err := s.newHealthChecker()
+	if err != nil {
+		return fmt.Errorf("failed to initialize health checks: %w", err)
+	}
+	s.health = checker
+
+	if s.config.Downloads.Workers > 0 {
+		s.downloadWorker = library.NewDownloadWorker(libraryRepo, musicService, s.blobs, library.WorkerConfig{
+			Workers:          s.config.Downloads.Workers,
+			PollInterval:     parseDuration(s.config.Downloads.PollInterval, 5*time.Second),
+			Lease:            parseDuration(s.config.Downloads.Lease, 2*time.Minute),
+			ProgressInterval: parseDuration(s.config.Downloads.ProgressInterval, 2*time.Second),
+			FetchTimeout:     parseDuration(s.config.Downloads.FetchTimeout, 5*time.Minute),
+			MaxAttempts:      s.config.Downloads.MaxAttempts,
+			RetryBackoff:     parseDuration(s.config.Downloads.RetryBackoff, 30*time.Second),
+			MaxFileSize:      s.config.Downloads.MaxFileSize,
+		}, s.logger)
+	}
+
+	if s.config.Jobs.Enabled {
+		scheduler, err := s.newScheduler(authService, oneTimeTokenService, auditLog, libraryService, accountService)
+		if err != nil {
+			return fmt.Errorf("failed to configure scheduled jobs: %w", err)
+		}
+		s.scheduler = scheduler
+	}
 
 	// --- Initialize Handlers ---
-	authHandlers := httpTransport.NewAuthHandlers(userService, authService, s.logger)
-	userHandlers := httpTransport.NewUserHandlers(userService, s.logger)
+	authHandlers := httpTransport.NewAuthHandlers(userService, authService, credentialsService, mfaService, throttle, s.logger)
+	userHandlers := httpTransport.NewUserHandlers(userService, authService, credentialsService, s.logger)
+	mfaHandlers := httpTransport.NewMFAHandlers(mfaService, userService, s.logger)
+	accountHandlers := httpTransport.NewAccountHandlers(accountService, userService, authService, s.logger)
 	playlistHandlers := httpTransport.NewPlaylistHandlers(playlistService, s.logger)
 	libraryHandlers := httpTransport.NewLibraryHandlers(libraryService, s.logger)
 	musicHandlers := httpTransport.NewMusicHandlers(musicService, s.logger)
+	adminHandlers := httpTransport.NewAdminHandlers(userService, authService, playlistService, s.scheduler, s.logger)
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandlers.JWKS)
 
 	// --- API Routes ---
 	api := router.Group("/api/v1")
 	api.Use(middleware.ContentType())
 
+	var rateLimiter *middleware.RateLimiter
+	if s.config.RateLimit.Enabled {
+		rateLimiter = middleware.NewRateLimiter(s.storage.Redis, s.logger)
+	}
+	limits := s.config.RateLimit
+
 	// Authentication routes (public)
-	authGroup := api.Group("/auth")
+	authGroup := api.Group("/auth", middleware.RateLimit(rateLimiter, "auth", rateLimitPolicy(limits.Auth)))
 	{
 		authGroup.POST("/register", authHandlers.Register)
 		authGroup.POST("/login", authHandlers.Login)
 		authGroup.POST("/google", authHandlers.GoogleSignIn)
+		authGroup.GET("/oidc/nonce", authHandlers.IssueNonce)
+		authGroup.POST("/oidc/:provider", authHandlers.OIDCSignIn)
 		authGroup.POST("/refresh", authHandlers.RefreshToken)
 		authGroup.POST("/logout", authHandlers.Logout)
+		authGroup.POST("/password/forgot", authHandlers.ForgotPassword)
+		authGroup.POST("/password/reset", authHandlers.ResetPassword)
+		authGroup.POST("/email/verify", authHandlers.VerifyEmail)
+		authGroup.POST("/unlock", authHandlers.UnlockAccount)
+		authGroup.POST("/mfa/verify", authHandlers.VerifyMFA)
 	}
 
-	jwtAuth := middleware.JWTAuth(jwtService)
+	jwtAuth := middleware.JWTAuth(jwtService, sessionStore)
 
 	// User routes
-	userGroup := api.Group("/users", jwtAuth)
+	userGroup := api.Group("/users", jwtAuth, middleware.RateLimit(rateLimiter, "users", rateLimitPolicy(limits.Users)))
 	{
 		userGroup.GET("/me", userHandlers.GetCurrentUser)
 		userGroup.PATCH("/me", userHandlers.UpdateCurrentUser)
+		userGroup.DELETE("/me", accountHandlers.DeleteAccount)
+		userGroup.PUT("/me/password", userHandlers.ChangePassword)
+		userGroup.POST("/me/email/verification", userHandlers.SendEmailVerification)
+		userGroup.GET("/me/sessions", userHandlers.GetSessions)
+		userGroup.DELETE("/me/sessions/others", userHandlers.RevokeOtherSessions)
+		userGroup.DELETE("/me/sessions/:sessionId", userHandlers.RevokeSession)
+		userGroup.GET("/me/identities", userHandlers.GetLoginMethods)
+		userGroup.POST("/me/identities/:provider", userHandlers.LinkIdentity)
+		userGroup.DELETE("/me/identities/:identityId", userHandlers.UnlinkIdentity)
+		userGroup.GET("/me/export", accountHandlers.GetExport)
+		userGroup.GET("/me/export/file", accountHandlers.GetExportFile)
+		userGroup.GET("/me/mfa", mfaHandlers.GetStatus)
+		userGroup.POST("/me/mfa/totp", mfaHandlers.BeginTOTPEnrollment)
+		userGroup.POST("/me/mfa/totp/verify", mfaHandlers.ConfirmTOTPEnrollment)
+		userGroup.DELETE("/me/mfa/totp", mfaHandlers.DisableTOTP)
+		userGroup.POST("/me/mfa/recovery-codes", mfaHandlers.RegenerateRecoveryCodes)
 	}
 
 	// Music routes
-	musicGroup := api.Group("/music", middleware.OptionalAuth(jwtService))
+	musicGroup := api.Group("/music", middleware.OptionalAuth(jwtService, sessionStore), middleware.RateLimit(rateLimiter, "music", rateLimitPolicy(limits.Music)))
 	{
 		musicGroup.GET("/search", musicHandlers.SearchTracks)
 		musicGroup.GET("/tracks/:trackId", musicHandlers.GetTrack)
 		musicGroup.GET("/top-charts", musicHandlers.GetTopCharts)
+		musicGroup.GET("/categories", musicHandlers.GetCategories)
+		musicGroup.GET("/categories/:categoryId/playlists", musicHandlers.GetCategoryPlaylists)
+		musicGroup.GET("/playlists/:provider/:playlistId", musicHandlers.GetPlaylistTracks)
 	}
 
 	// Playlist routes
-	playlistGroup := api.Group("/playlists", jwtAuth)
+	playlistGroup := api.Group("/playlists", jwtAuth, middleware.RateLimit(rateLimiter, "playlists", rateLimitPolicy(limits.Playlists)))
 	{
 		playlistGroup.GET("", playlistHandlers.GetPlaylists)
 		playlistGroup.POST("", playlistHandlers.CreatePlaylist)
@@ -144,20 +340,48 @@ func (s *Server) setupRouter() {
 		playlistGroup.PATCH("/:playlistId", playlistHandlers.UpdatePlaylist)
 		playlistGroup.DELETE("/:playlistId", playlistHandlers.DeletePlaylist)
 		playlistGroup.POST("/:playlistId/tracks", playlistHandlers.AddTrackToPlaylist)
+		playlistGroup.PATCH("/:playlistId/tracks/order", playlistHandlers.ReorderPlaylistTracks)
 		playlistGroup.DELETE("/:playlistId/tracks/:trackId", playlistHandlers.RemoveTrackFromPlaylist)
+		playlistGroup.PATCH("/:playlistId/tracks/:trackId/position", playlistHandlers.MoveTrack)
 	}
 
 	// Library routes
-	libraryGroup := api.Group("", jwtAuth)
+	libraryRateLimit := middleware.RateLimit(rateLimiter, "library", rateLimitPolicy(limits.Library))
+	libraryGroup := api.Group("", jwtAuth, libraryRateLimit)
 	{
 		libraryGroup.GET("/favorites", libraryHandlers.GetFavorites)
 		libraryGroup.POST("/favorites", libraryHandlers.AddFavorite)
 		libraryGroup.DELETE("/favorites/:favoriteId", libraryHandlers.RemoveFavorite)
 		libraryGroup.GET("/history", libraryHandlers.GetHistory)
 		libraryGroup.POST("/history", libraryHandlers.AddHistory)
+		libraryGroup.GET("/downloads", libraryHandlers.GetDownloads)
+		libraryGroup.POST("/downloads", libraryHandlers.AddDownload)
+		libraryGroup.GET("/downloads/:downloadId", libraryHandlers.GetDownload)
+		libraryGroup.DELETE("/downloads/:downloadId", libraryHandlers.RemoveDownload)
+		libraryGroup.PATCH("/downloads/:downloadId/state", libraryHandlers.UpdateDownloadState)
+		libraryGroup.POST("/downloads/:downloadId/retry", libraryHandlers.RetryDownload)
+		libraryGroup.GET("/downloads/:downloadId/file-url", libraryHandlers.GetDownloadFileURL)
+	}
+
+	// Downloaded files accept either a bearer token or a signed URL
+	api.GET("/downloads/:downloadId/file", middleware.JWTAuthUnlessSigned(jwtService, sessionStore), libraryRateLimit, libraryHandlers.GetDownloadFile)
+
+	// Admin routes (user and content moderation)
+	adminGroup := api.Group("/admin", jwtAuth, middleware.RequireRole("admin"), middleware.RateLimit(rateLimiter, "admin", rateLimitPolicy(limits.Admin)))
+	{
+		adminGroup.GET("/users", adminHandlers.ListUsers)
+		adminGroup.GET("/users/:userId", adminHandlers.GetUser)
+		adminGroup.PATCH("/users/:userId/status", adminHandlers.UpdateUserStatus)
+		adminGroup.DELETE("/users/:userId/sessions", adminHandlers.RevokeUserSessions)
+		adminGroup.POST("/users/:userId/roles", adminHandlers.GrantUserRole)
+		adminGroup.DELETE("/users/:userId/roles/:role", adminHandlers.RevokeUserRole)
+		adminGroup.GET("/playlists", adminHandlers.ListPublicPlaylists)
+		adminGroup.POST("/playlists/:playlistId/unpublish", adminHandlers.UnpublishPlaylist)
+		adminGroup.GET("/jobs", adminHandlers.GetJobs)
 	}
 
 	s.router = router
+	return nil
 }
 
 // Router returns the configured Gin router
@@ -165,14 +389,47 @@ func (s *Server) Router() *gin.Engine {
 	return s.router
 }
 
+// StartBackgroundWorkers starts in-process background processing: the download worker and
+// the maintenance job scheduler
+func (s *Server) StartBackgroundWorkers(ctx context.Context) {
+	if s.downloadWorker != nil {
+		s.downloadWorker.Start(ctx)
+	}
+	if s.scheduler != nil {
+		s.scheduler.Start(ctx)
+	}
+}
+
+// StopBackgroundWorkers stops background processing, waiting until ctx expires for in-flight work to wind down
+func (s *Server) StopBackgroundWorkers(ctx context.Context) error {
+	var errs []error
+	if s.downloadWorker != nil {
+		if err := s.downloadWorker.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("download worker: %w", err))
+		}
+	}
+	if s.scheduler != nil {
+		if err := s.scheduler.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("scheduler: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // healthCheck handles health check requests
 func (s *Server) healthCheck(c *gin.Context) {
 	if err := s.storage.Health(); err != nil {
-		s.logger.Error("Health check failed", "error", err)
+		s.logger.WithContext(c.Request.Context()).Error("Health check failed", "error", err)
 		response.InternalError(c, "UNHEALTHY", "Service is unhealthy")
 		return
 	}
 
+	// Provider outages degrade search but don't make the service unhealthy
+	providers := gin.H{}
+	for name, state := range s.musicService.CircuitStates() {
+		providers[name] = gin.H{"circuit": state}
+	}
+
 	healthData := gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
@@ -180,6 +437,7 @@ func (s *Server) healthCheck(c *gin.Context) {
 			"database": "healthy",
 			"redis":    "healthy",
 		},
+		"providers": providers,
 	}
 
 	response.Success(c, healthData)
@@ -187,9 +445,122 @@ func (s *Server) healthCheck(c *gin.Context) {
 
 // versionInfo handles version information requests
 func (s *Server) versionInfo(c *gin.Context) {
+	build := buildinfo.Get()
 	versionData := gin.H{
-		"version":     "1.2.0",
+		"version":     build.Version,
+		"commit":      build.Commit,
+		"build_time":  build.BuildTime,
+		"modified":    build.Modified,
+		"go_version":  build.GoVersion,
 		"environment": s.config.App.Environment,
 	}
 	response.Success(c, versionData)
 }
+
+// jwtConfig builds the token signing configuration, reading any PEM key files
+func (s *Server) jwtConfig() (auth.JWTConfig, error) {
+	cfg := s.config.Auth
+	jwtConfig := auth.JWTConfig{
+		SigningMethod:          cfg.JWTSigningMethod,
+		AccessSecret:           cfg.JWTAccessSecret,
+		RefreshSecret:          cfg.JWTRefreshSecret,
+		PreviousAccessSecrets:  cfg.JWTPreviousAccessSecrets,
+		PreviousRefreshSecrets: cfg.JWTPreviousRefreshSecrets,
+		PrivateKeyPEM:          []byte(cfg.JWTPrivateKey),
+		KeyID:                  cfg.JWTKeyID,
+		AccessTokenTTL:         cfg.AccessTokenTTL,
+		RefreshTokenTTL:        cfg.RefreshTokenTTL,
+	}
+
+	if len(jwtConfig.PrivateKeyPEM) == 0 && cfg.JWTPrivateKeyFile != "" {
+		data, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return jwtConfig, fmt.Errorf("failed to read JWT private key: %w", err)
+		}
+		jwtConfig.PrivateKeyPEM = data
+	}
+	for _, path := range cfg.JWTPreviousKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return jwtConfig, fmt.Errorf("failed to read previous JWT key: %w", err)
+		}
+		jwtConfig.PreviousKeysPEM = append(jwtConfig.PreviousKeysPEM, data)
+	}
+	return jwtConfig, nil
+}
+
+// identityProviders builds the enabled OpenID Connect login providers. Providers without
+// client IDs are skipped, so Google sign-in stays off until a client ID is configured.
+func (s *Server) identityProviders() ([]auth.IdentityProvider, error) {
+	var providers []auth.IdentityProvider
+	for _, name := range config.OIDCProviderNames(s.config.OIDC.Enabled) {
+		cfg := s.config.OIDC.Providers[name]
+		if name == user.ProviderGoogle && len(cfg.ClientIDs) == 0 && s.config.Google.ClientID != "" {
+			cfg.ClientIDs = []string{s.config.Google.ClientID}
+		}
+		if len(cfg.ClientIDs) == 0 {
+			s.logger.Warn("OIDC provider has no client IDs; sign-in with it is disabled", "provider", name)
+			continue
+		}
+
+		provider, err := auth.NewOIDCProvider(auth.OIDCConfig{
+			Name:         name,
+			Issuer:       cfg.Issuer,
+			ClientIDs:    cfg.ClientIDs,
+			DiscoveryURL: cfg.DiscoveryURL,
+			JWKSURL:      cfg.JWKSURL,
+			RequireNonce: cfg.RequireNonce,
+			TrustEmail:   cfg.TrustEmail,
+		})
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// newMailer builds the mailer selected by the mail configuration
+func (s *Server) newMailer() (mail.Mailer, error) {
+	cfg := s.config.Mail
+	if cfg.Driver == "smtp" {
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}), nil
+	}
+	return mail.NewLogMailer(cfg.From, cfg.OutboxDir, s.logger)
+}
+
+// rateLimitPolicy converts a configured rate limit policy for the rate limit middleware. The
+// period has already been checked by config validation.
+func rateLimitPolicy(policy config.RateLimitPolicy) middleware.RateLimitPolicy {
+	period, _ := time.ParseDuration(policy.Period)
+	return middleware.RateLimitPolicy{
+		Requests: policy.Requests,
+		Period:   period,
+		Burst:    policy.Burst,
+	}
+}
+
+// derivedKey derives a key for one purpose from secret with HKDF, for development setups that
+// don't configure a separate key. Each purpose gets unrelated key material, so no key doubles
+// as another.
+func derivedKey(secret, purpose string) string {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(purpose)), key); err != nil {
+		panic(err) // HKDF-SHA256 only fails past 8160 bytes
+	}
+	return hex.EncodeToString(key)
+}
+
+// parseDuration parses a duration string, falling back to the given default when it is empty or invalid
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+	return fallback
+}
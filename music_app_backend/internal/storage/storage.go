@@ -3,13 +3,10 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/mosesmmoisebidth/music_backend/internal/auth"
 	"github.com/mosesmmoisebidth/music_backend/internal/config"
-	"github.com/mosesmmoisebidth/music_backend/internal/library"
-	"github.com/mosesmmoisebidth/music_backend/internal/playlist"
-	"github.com/mosesmmoisebidth/music_backend/internal/user"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -94,17 +91,10 @@ func initRedis(config config.RedisConfig) (*redis.Client, error) {
 	return client, nil
 }
 
-// AutoMigrate runs database migrations for all models
-func (s *Storage) AutoMigrate() error {
-	return s.DB.AutoMigrate(
-		&user.User{},
-		&playlist.Playlist{},
-		&playlist.PlaylistTrack{},
-		&library.Favorite{},
-		&library.History{},
-		&library.Download{},
-		&auth.RefreshToken{},
-	)
+// NewDatabase opens a PostgreSQL connection on its own, for tooling such as migrations
+// that does not need Redis
+func NewDatabase(dbConfig config.DatabaseConfig) (*gorm.DB, error) {
+	return initDB(dbConfig)
 }
 
 // Close closes all database connections
@@ -126,18 +116,35 @@ func (s *Storage) Close() error {
 
 // Health checks the health of all storage connections
 func (s *Storage) Health() error {
-	// Check PostgreSQL health
-	if sqlDB, err := s.DB.DB(); err != nil {
+	ctx := context.Background()
+	if err := s.PingDatabase(ctx); err != nil {
+		return err
+	}
+	return s.PingRedis(ctx)
+}
+
+// PingDatabase checks the PostgreSQL connection
+func (s *Storage) PingDatabase(ctx context.Context) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
 		return fmt.Errorf("failed to get database connection: %w", err)
-	} else if err := sqlDB.Ping(); err != nil {
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
 		return fmt.Errorf("database health check failed: %w", err)
 	}
+	return nil
+}
 
-	// Check Redis health
-	ctx := context.Background()
+// PingRedis checks the Redis connection
+func (s *Storage) PingRedis(ctx context.Context) error {
 	if err := s.Redis.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("Redis health check failed: %w", err)
 	}
-
 	return nil
 }
+
+// EscapeLike escapes LIKE wildcards so user input is matched literally. Patterns built with
+// it are used with ESCAPE '\'.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/account"
+)
+
+// --- Account Requests ---
+
+type DeleteAccountRequest struct {
+	Password string `json:"password,omitempty"` // required for accounts with a password
+}
+
+// --- Account Responses ---
+
+type AccountDeletionResponse struct {
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAfter time.Time `json:"purge_after"` // signing in before this restores the account
+}
+
+type DataExportResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	State       string     `json:"state"`
+	FileSize    *int64     `json:"file_size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func mapDataExportToResponse(e *account.DataExport) DataExportResponse {
+	return DataExportResponse{
+		ID:          e.ID,
+		State:       string(e.State),
+		FileSize:    e.FileSize,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+}
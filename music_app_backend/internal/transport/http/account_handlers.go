@@ -0,0 +1,159 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mosesmmoisebidth/music_backend/internal/account"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/user"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"github.com/mosesmmoisebidth/music_backend/pkg/response"
+)
+
+// AccountHandlers contains account deletion and data export HTTP handlers
+type AccountHandlers struct {
+	service     *account.Service
+	userService *user.Service
+	authService *auth.AuthService
+	logger      logger.Logger
+}
+
+// NewAccountHandlers creates new account handlers
+func NewAccountHandlers(service *account.Service, userService *user.Service, authService *auth.AuthService, logger logger.Logger) *AccountHandlers {
+	return &AccountHandlers{service: service, userService: userService, authService: authService, logger: logger}
+}
+
+// DeleteAccount schedules the current user's account for deletion.
+// @Summary      Delete account
+// @Description  Signs the user out everywhere and schedules the account for deletion. Signing in again before purge_after restores it; after that the account and everything it owns are permanently removed. Accounts with a password must confirm it.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request body DeleteAccountRequest false "Password confirmation"
+// @Success      202  {object}  response.APIResponse{data=AccountDeletionResponse}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me [delete]
+func (h *AccountHandlers) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	var req DeleteAccountRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ValidationError(c, err)
+			return
+		}
+	}
+
+	deletedUser, err := h.userService.ScheduleDeletion(c.Request.Context(), userID.(string), req.Password)
+	if err != nil {
+		switch err {
+		case user.ErrAuthenticationFailed:
+			response.Forbidden(c, "INVALID_PASSWORD", "Password is incorrect")
+		case user.ErrDeletionPending:
+			response.Conflict(c, "DELETION_PENDING", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to delete account", "error", err, "user_id", userID)
+			response.InternalError(c, "ACCOUNT_DELETION_FAILED", "Failed to delete account")
+		}
+		return
+	}
+
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), deletedUser.ID); err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to revoke sessions of deleted account", "error", err, "user_id", deletedUser.ID)
+		response.InternalError(c, "SESSION_REVOKE_FAILED", "Account was deleted but its sessions could not be revoked")
+		return
+	}
+
+	response.Accepted(c, AccountDeletionResponse{
+		DeletedAt:  *deletedUser.DeletedAt,
+		PurgeAfter: h.service.PurgeAfter(*deletedUser.DeletedAt),
+	})
+}
+
+// GetExport returns the current user's data export, starting one if needed.
+// @Summary      Get a data export
+// @Description  Returns the user's current data export: a ZIP archive of their profile, playlists, favorites, listening history, downloads and sessions, generated in the background. When there is none, or the last one failed or expired, a new export is queued. Answers 202 until the export completes; poll again until then, and download the archive from /users/me/export/file.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=DataExportResponse}
+// @Success      202  {object}  response.APIResponse{data=DataExportResponse}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/export [get]
+func (h *AccountHandlers) GetExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	export, err := h.service.RequestExport(c.Request.Context(), userID.(string))
+	if err != nil {
+		h.handleExportError(c, err, userID, "EXPORT_REQUEST_FAILED", "Failed to request data export")
+		return
+	}
+
+	if export.State != account.ExportCompleted {
+		response.Accepted(c, mapDataExportToResponse(export))
+		return
+	}
+	response.Success(c, mapDataExportToResponse(export))
+}
+
+// GetExportFile downloads the current user's latest data export.
+// @Summary      Download data export
+// @Description  Streams the ZIP archive of the user's most recent completed data export.
+// @Tags         Users
+// @Produce      application/zip
+// @Security     Bearer
+// @Success      200  {file}    binary
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/export/file [get]
+func (h *AccountHandlers) GetExportFile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	export, object, err := h.service.OpenExportFile(c.Request.Context(), userID.(string))
+	if err != nil {
+		h.handleExportError(c, err, userID, "EXPORT_FILE_FAILED", "Failed to open data export")
+		return
+	}
+	defer object.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="music-app-export-%s.zip"`, export.CreatedAt.UTC().Format("2006-01-02")))
+	c.Header("Cache-Control", "private, no-store")
+
+	http.ServeContent(c.Writer, c.Request, "", object.ModTime, object)
+}
+
+// handleExportError maps data export errors to responses.
+func (h *AccountHandlers) handleExportError(c *gin.Context, err error, userID interface{}, code, message string) {
+	switch err {
+	case account.ErrExportNotFound:
+		response.NotFound(c, "EXPORT_NOT_FOUND", err.Error())
+	case account.ErrExportNotReady:
+		response.Conflict(c, "EXPORT_NOT_READY", err.Error())
+	default:
+		h.logger.WithContext(c.Request.Context()).Error("data export request failed", "error", err, "user_id", userID)
+		response.InternalError(c, code, message)
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/jobs"
+)
+
+// --- Admin Requests ---
+
+type ListUsersRequest struct {
+	Query    string `form:"q" binding:"max=100"`
+	Role     string `form:"role" binding:"omitempty,oneof=user admin"`
+	IsActive *bool  `form:"is_active"`
+	Page     int    `form:"page,default=1"`
+	Size     int    `form:"size,default=20" binding:"max=100"`
+}
+
+type UpdateUserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+type ListPublicPlaylistsRequest struct {
+	Query string `form:"q" binding:"max=100"`
+	Page  int    `form:"page,default=1"`
+	Size  int    `form:"size,default=20" binding:"max=100"`
+}
+
+// --- Admin Responses ---
+
+type JobResponse struct {
+	Name         string     `json:"name"`
+	Running      bool       `json:"running"`
+	Runs         int64      `json:"runs"`
+	Failures     int64      `json:"failures"`
+	Skipped      int64      `json:"skipped"`
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastResult   string     `json:"last_result,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	NextRunAt    time.Time  `json:"next_run_at"`
+}
+
+func mapJobStatsToResponse(st *jobs.Stats) JobResponse {
+	resp := JobResponse{
+		Name:       st.Name,
+		Running:    st.Running,
+		Runs:       st.Runs,
+		Failures:   st.Failures,
+		Skipped:    st.Skipped,
+		LastRunAt:  st.LastRunAt,
+		LastResult: st.LastResult,
+		LastError:  st.LastError,
+		NextRunAt:  st.NextRunAt,
+	}
+	if st.LastRunAt != nil {
+		resp.LastDuration = st.LastDuration.String()
+	}
+	return resp
+}
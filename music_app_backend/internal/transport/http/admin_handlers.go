@@ -0,0 +1,402 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/jobs"
+	"github.com/mosesmmoisebidth/music_backend/internal/playlist"
+	"github.com/mosesmmoisebidth/music_backend/internal/user"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"github.com/mosesmmoisebidth/music_backend/pkg/response"
+)
+
+// AdminHandlers contains user and content moderation HTTP handlers
+type AdminHandlers struct {
+	userService     *user.Service
+	authService     *auth.AuthService
+	playlistService *playlist.Service
+	scheduler       *jobs.Scheduler
+	logger          logger.Logger
+}
+
+// NewAdminHandlers creates new admin handlers. scheduler is nil when scheduled jobs are disabled.
+func NewAdminHandlers(userService *user.Service, authService *auth.AuthService, playlistService *playlist.Service, scheduler *jobs.Scheduler, logger logger.Logger) *AdminHandlers {
+	return &AdminHandlers{
+		userService:     userService,
+		authService:     authService,
+		playlistService: playlistService,
+		scheduler:       scheduler,
+		logger:          logger,
+	}
+}
+
+// ListUsers lists and searches users.
+// @Summary      List users
+// @Description  Retrieves a paginated list of users, optionally searching by email or display name and filtering by role or status.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Param        q query string false "Search email or display name"
+// @Param        role query string false "Filter by role" Enums(user, admin)
+// @Param        is_active query bool false "Filter by active status"
+// @Param        page query int false "Page number" default(1)
+// @Param        size query int false "Page size" default(20)
+// @Success      200 {object} response.APIResponse{data=response.PaginatedData{users=[]UserResponse}}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/users [get]
+func (h *AdminHandlers) ListUsers(c *gin.Context) {
+	var req ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	filter := user.UserFilter{Query: req.Query, Role: req.Role, IsActive: req.IsActive}
+	users, total, err := h.userService.ListUsers(c.Request.Context(), filter, req.Page, req.Size)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to list users", "error", err)
+		response.InternalError(c, "USERS_FETCH_FAILED", "Failed to fetch users")
+		return
+	}
+
+	userResponses := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		userResponses = append(userResponses, mapUserToResponse(&u))
+	}
+
+	response.Success(c, response.NewPaginatedData(userResponses, req.Page, req.Size, total))
+}
+
+// GetUser retrieves any user's profile.
+// @Summary      Get user
+// @Description  Retrieves the profile of a user by ID.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Param        userId path string true "User ID"
+// @Success      200 {object} response.APIResponse{data=UserResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/users/{userId} [get]
+func (h *AdminHandlers) GetUser(c *gin.Context) {
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	u, err := h.userService.GetUserByID(c.Request.Context(), targetID)
+	if err != nil {
+		h.handleUserError(c, err, targetID, "USER_FETCH_FAILED", "Failed to fetch user")
+		return
+	}
+
+	response.Success(c, mapUserToResponse(u))
+}
+
+// UpdateUserStatus activates or deactivates a user.
+// @Summary      Activate or deactivate user
+// @Description  Sets whether a user may sign in. Deactivating a user also revokes all of their sessions. Administrators cannot deactivate themselves.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        userId path string true "User ID"
+// @Param        request body UpdateUserStatusRequest true "New status"
+// @Success      200 {object} response.APIResponse{data=UserResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/users/{userId}/status [patch]
+func (h *AdminHandlers) UpdateUserStatus(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	u, err := h.userService.SetUserActive(c.Request.Context(), actorID.(string), targetID, *req.IsActive)
+	if err != nil {
+		h.handleUserError(c, err, targetID, "USER_STATUS_UPDATE_FAILED", "Failed to update user status")
+		return
+	}
+
+	if !u.IsActive {
+		if err := h.authService.RevokeAllUserTokens(c.Request.Context(), u.ID); err != nil {
+			h.logger.WithContext(c.Request.Context()).Error("failed to revoke sessions of deactivated user", "error", err, "user_id", u.ID)
+			response.InternalError(c, "SESSION_REVOKE_FAILED", "User was deactivated but their sessions could not be revoked")
+			return
+		}
+	}
+
+	response.Success(c, mapUserToResponse(u))
+}
+
+// RevokeUserSessions signs a user out everywhere.
+// @Summary      Revoke user sessions
+// @Description  Revokes every refresh token issued to a user, forcing them to sign in again.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Param        userId path string true "User ID"
+// @Success      200 {object} response.APIResponse{data=response.SuccessMessage}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/users/{userId}/sessions [delete]
+func (h *AdminHandlers) RevokeUserSessions(c *gin.Context) {
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	u, err := h.userService.GetUserByID(c.Request.Context(), targetID)
+	if err != nil {
+		h.handleUserError(c, err, targetID, "USER_FETCH_FAILED", "Failed to fetch user")
+		return
+	}
+
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), u.ID); err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to revoke user sessions", "error", err, "user_id", u.ID)
+		response.InternalError(c, "SESSION_REVOKE_FAILED", "Failed to revoke user sessions")
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "User sessions revoked successfully"})
+}
+
+// GrantUserRole grants a role to a user.
+// @Summary      Grant role
+// @Description  Adds a role to a user. The user's access tokens are invalidated so their next refresh picks up the new role.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        userId path string true "User ID"
+// @Param        request body UserRoleRequest true "Role to grant"
+// @Success      200 {object} response.APIResponse{data=UserResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/users/{userId}/roles [post]
+func (h *AdminHandlers) GrantUserRole(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	u, err := h.userService.GrantRole(c.Request.Context(), actorID.(string), targetID, req.Role)
+	if err != nil {
+		h.handleUserError(c, err, targetID, "ROLE_GRANT_FAILED", "Failed to grant role")
+		return
+	}
+
+	// Existing access tokens carry the old roles, so force a refresh
+	if err := h.authService.InvalidateAccessTokens(c.Request.Context(), u.ID); err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to invalidate access tokens after role change", "error", err, "user_id", u.ID)
+		response.InternalError(c, "SESSION_REVOKE_FAILED", "Role was changed but existing sessions could not be refreshed")
+		return
+	}
+
+	response.Success(c, mapUserToResponse(u))
+}
+
+// RevokeUserRole removes a role from a user.
+// @Summary      Revoke role
+// @Description  Removes a role from a user, taking effect on their next request. Administrators cannot remove their own admin role.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Param        userId path string true "User ID"
+// @Param        role path string true "Role to revoke" Enums(user, admin)
+// @Success      200 {object} response.APIResponse{data=UserResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/users/{userId}/roles/{role} [delete]
+func (h *AdminHandlers) RevokeUserRole(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	targetID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	u, err := h.userService.RevokeRole(c.Request.Context(), actorID.(string), targetID, c.Param("role"))
+	if err != nil {
+		h.handleUserError(c, err, targetID, "ROLE_REVOKE_FAILED", "Failed to revoke role")
+		return
+	}
+
+	// Existing access tokens carry the old roles, so force a refresh
+	if err := h.authService.InvalidateAccessTokens(c.Request.Context(), u.ID); err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to invalidate access tokens after role change", "error", err, "user_id", u.ID)
+		response.InternalError(c, "SESSION_REVOKE_FAILED", "Role was changed but existing sessions could not be refreshed")
+		return
+	}
+
+	response.Success(c, mapUserToResponse(u))
+}
+
+// ListPublicPlaylists lists public playlists for moderation.
+// @Summary      List public playlists
+// @Description  Retrieves a paginated list of public playlists, optionally searching by title.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Param        q query string false "Search playlist titles"
+// @Param        page query int false "Page number" default(1)
+// @Param        size query int false "Page size" default(20)
+// @Success      200 {object} response.APIResponse{data=response.PaginatedData{playlists=[]PlaylistResponse}}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/playlists [get]
+func (h *AdminHandlers) ListPublicPlaylists(c *gin.Context) {
+	var req ListPublicPlaylistsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	playlists, total, err := h.playlistService.ListPublicPlaylists(c.Request.Context(), req.Query, req.Page, req.Size)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to list public playlists", "error", err)
+		response.InternalError(c, "PLAYLISTS_FETCH_FAILED", "Failed to fetch playlists")
+		return
+	}
+
+	playlistResponses := make([]PlaylistResponse, 0, len(playlists))
+	for _, p := range playlists {
+		playlistResponses = append(playlistResponses, mapPlaylistToResponse(&p))
+	}
+
+	response.Success(c, response.NewPaginatedData(playlistResponses, req.Page, req.Size, total))
+}
+
+// UnpublishPlaylist makes a public playlist private.
+// @Summary      Unpublish playlist
+// @Description  Makes any playlist private and revokes its share code, regardless of owner.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Param        playlistId path string true "Playlist ID"
+// @Success      200 {object} response.APIResponse{data=PlaylistResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/playlists/{playlistId}/unpublish [post]
+func (h *AdminHandlers) UnpublishPlaylist(c *gin.Context) {
+	actorID, _ := c.Get("user_id")
+
+	playlistID := c.Param("playlistId")
+	if _, err := uuid.Parse(playlistID); err != nil {
+		response.BadRequest(c, "INVALID_PLAYLIST_ID", "Invalid playlist ID format")
+		return
+	}
+
+	p, err := h.playlistService.UnpublishPlaylist(c.Request.Context(), playlistID)
+	if err != nil {
+		switch err {
+		case playlist.ErrPlaylistNotFound:
+			response.NotFound(c, "PLAYLIST_NOT_FOUND", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to unpublish playlist", "error", err, "playlist_id", playlistID)
+			response.InternalError(c, "PLAYLIST_UNPUBLISH_FAILED", "Failed to unpublish playlist")
+		}
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).Info("Playlist unpublished", "playlist_id", playlistID, "actor_id", actorID)
+	response.Success(c, mapPlaylistToResponse(p))
+}
+
+// GetJobs reports the scheduled maintenance jobs.
+// @Summary      List scheduled jobs
+// @Description  Reports each maintenance job's schedule and recent runs as seen by the replica serving the request. Runs taken by other replicas are counted as skipped.
+// @Tags         Admin
+// @Produce      json
+// @Security     Bearer
+// @Success      200 {object} response.APIResponse{data=[]JobResponse}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Router       /admin/jobs [get]
+func (h *AdminHandlers) GetJobs(c *gin.Context) {
+	jobResponses := make([]JobResponse, 0)
+	if h.scheduler != nil {
+		for _, st := range h.scheduler.Stats() {
+			jobResponses = append(jobResponses, mapJobStatsToResponse(&st))
+		}
+	}
+
+	response.Success(c, jobResponses)
+}
+
+// handleUserError maps user administration errors to HTTP responses.
+func (h *AdminHandlers) handleUserError(c *gin.Context, err error, userID, code, message string) {
+	switch err {
+	case user.ErrUserNotFound:
+		response.NotFound(c, "USER_NOT_FOUND", err.Error())
+	case user.ErrInvalidRole:
+		response.BadRequest(c, "INVALID_ROLE", err.Error())
+	case user.ErrCannotModifySelf:
+		response.Forbidden(c, "CANNOT_MODIFY_SELF", err.Error())
+	default:
+		h.logger.WithContext(c.Request.Context()).Error("user administration failed", "error", err, "user_id", userID)
+		response.InternalError(c, code, message)
+	}
+}
+
+// userIDParam returns the userId path parameter, responding with 400 if it is not a UUID.
+func userIDParam(c *gin.Context) (string, bool) {
+	userID := c.Param("userId")
+	if _, err := uuid.Parse(userID); err != nil {
+		response.BadRequest(c, "INVALID_USER_ID", "Invalid user ID format")
+		return "", false
+	}
+	return userID, true
+}
@@ -18,10 +18,38 @@ type GoogleSignInRequest struct {
 	IDToken string `json:"id_token" binding:"required"`
 }
 
+type OIDCSignInRequest struct {
+	IDToken     string `json:"id_token" binding:"required"`
+	Nonce       string `json:"nonce,omitempty"`                                        // the nonce from /auth/oidc/nonce the ID token was requested with
+	DisplayName string `json:"display_name,omitempty" binding:"omitempty,max=50"` // used when the token has no name, as with Apple
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // a TOTP code or a recovery code
+}
+
 // --- Auth Responses ---
 
 // AuthResponse contains the full authentication payload including tokens and user info.
@@ -41,6 +69,20 @@ type TokenPair struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
+// OIDCNonceResponse is a single-use nonce to request a provider ID token with
+type OIDCNonceResponse struct {
+	Nonce     string `json:"nonce"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// MFAChallengeResponse is returned instead of tokens when the user must also enter a second
+// factor. The token is exchanged for tokens at /auth/mfa/verify.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
 // LogoutResponse is the response for a successful logout.
 type LogoutResponse struct {
 	Message string `json:"message"`
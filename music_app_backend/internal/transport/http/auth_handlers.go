@@ -3,9 +3,12 @@ package http
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/mosesmmoisebidth/music_backend/internal/auth"
 	"github.com/mosesmmoisebidth/music_backend/internal/user"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
@@ -14,23 +17,29 @@ import (
 
 // AuthHandlers contains authentication HTTP handlers
 type AuthHandlers struct {
-	userService *user.Service
-	authService *auth.AuthService
-	logger      logger.Logger
+	userService        *user.Service
+	authService        *auth.AuthService
+	credentialsService *user.CredentialsService
+	mfaService         *auth.MFAService
+	throttle           *auth.Throttle
+	logger             logger.Logger
 }
 
 // NewAuthHandlers creates new authentication handlers
-func NewAuthHandlers(userService *user.Service, authService *auth.AuthService, logger logger.Logger) *AuthHandlers {
+func NewAuthHandlers(userService *user.Service, authService *auth.AuthService, credentialsService *user.CredentialsService, mfaService *auth.MFAService, throttle *auth.Throttle, logger logger.Logger) *AuthHandlers {
 	return &AuthHandlers{
-		userService: userService,
-		authService: authService,
-		logger:      logger,
+		userService:        userService,
+		authService:        authService,
+		credentialsService: credentialsService,
+		mfaService:         mfaService,
+		throttle:           throttle,
+		logger:             logger,
 	}
 }
 
 // Register handles user registration
 // @Summary      Register a new user
-// @Description  Register a new user with email, password, and display name.
+// @Description  Register a new user with email, password, and display name. A link to verify the email address is sent to it.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -38,6 +47,7 @@ func NewAuthHandlers(userService *user.Service, authService *auth.AuthService, l
 // @Success      201  {object}  response.APIResponse{data=AuthResponse}
 // @Failure      400  {object}  response.APIResponse{error=response.APIError}
 // @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      429  {object}  response.APIResponse{error=response.APIError}
 // @Failure      500  {object}  response.APIResponse{error=response.APIError}
 // @Router       /auth/register [post]
 func (h *AuthHandlers) Register(c *gin.Context) {
@@ -50,17 +60,32 @@ func (h *AuthHandlers) Register(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
+	attempt := clientAttempt(c, req.Email)
+	decision, err := h.throttle.CheckRegister(ctx, attempt)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Registration throttle check failed", "error", err)
+	}
+	if !decision.Allowed {
+		respondThrottled(c, decision)
+		return
+	}
+
 	newUser, err := h.userService.CreateUser(ctx, req.Email, req.Password, req.DisplayName)
 	if err != nil {
 		if err == user.ErrEmailExists {
 			response.Conflict(c, "USER_EXISTS", err.Error())
 			return
 		}
-		h.logger.Error("Failed to create user", "error", err, "email", req.Email)
+		h.logger.WithContext(ctx).Error("Failed to create user", "error", err, "email", req.Email)
 		response.InternalError(c, "REGISTRATION_FAILED", "Failed to create user account")
 		return
 	}
 
+	// The account is usable without verification, so a failure here shouldn't fail registration
+	if err := h.credentialsService.SendEmailVerification(ctx, newUser.ID.String()); err != nil {
+		h.logger.WithContext(ctx).Error("Failed to send verification email", "error", err, "user_id", newUser.ID)
+	}
+
 	userAgent := c.GetHeader("User-Agent")
 	clientIP := c.ClientIP()
 	email := ""
@@ -70,7 +95,7 @@ func (h *AuthHandlers) Register(c *gin.Context) {
 
 	tokens, err := h.authService.GenerateTokens(ctx, newUser.ID, email, newUser.Roles, userAgent, clientIP)
 	if err != nil {
-		h.logger.Error("Failed to generate tokens", "error", err, "user_id", newUser.ID)
+		h.logger.WithContext(ctx).Error("Failed to generate tokens", "error", err, "user_id", newUser.ID)
 		response.InternalError(c, "TOKEN_GENERATION_FAILED", "Failed to generate authentication tokens")
 		return
 	}
@@ -83,20 +108,23 @@ func (h *AuthHandlers) Register(c *gin.Context) {
 		ExpiresIn:    tokens.ExpiresIn,
 	}
 
-	h.logger.Info("User registered successfully", "user_id", newUser.ID)
+	h.logger.WithContext(ctx).Info("User registered successfully", "user_id", newUser.ID)
 	response.Created(c, authResponse)
 }
 
 // Login handles user login
 // @Summary      User login
-// @Description  Authenticate a user with email and password to receive JWT tokens.
+// @Description  Authenticate a user with email and password to receive JWT tokens. Repeated failures slow down further attempts and eventually lock the account; locked accounts can be unlocked with the link emailed to them. Users with two-factor authentication get a 202 with an MFA token instead, to exchange at /auth/mfa/verify.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Param        request body LoginRequest true "Login Request"
 // @Success      200  {object}  response.APIResponse{data=AuthResponse}
+// @Success      202  {object}  response.APIResponse{data=MFAChallengeResponse}
 // @Failure      400  {object}  response.APIResponse{error=response.APIError}
 // @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      429  {object}  response.APIResponse{error=response.APIError}
 // @Failure      500  {object}  response.APIResponse{error=response.APIError}
 // @Router       /auth/login [post]
 func (h *AuthHandlers) Login(c *gin.Context) {
@@ -109,13 +137,47 @@ func (h *AuthHandlers) Login(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
+	attempt := clientAttempt(c, req.Email)
+	decision, err := h.throttle.CheckLogin(ctx, attempt)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Login throttle check failed", "error", err)
+	}
+	if !decision.Allowed {
+		respondThrottled(c, decision)
+		return
+	}
+
 	authenticatedUser, err := h.userService.AuthenticateUser(ctx, req.Email, req.Password)
 	if err != nil {
-		h.logger.Warn("Authentication failed", "error", err, "email", req.Email)
+		h.logger.WithContext(ctx).Warn("Authentication failed", "error", err, "email", req.Email)
+		if err == user.ErrAccountDisabled {
+			response.Forbidden(c, "ACCOUNT_DISABLED", "This account has been disabled")
+			return
+		}
+		if err == user.ErrAuthenticationFailed {
+			h.loginFailed(ctx, attempt)
+		}
 		response.Unauthorized(c, "AUTHENTICATION_FAILED", "Invalid email or password")
 		return
 	}
 
+	// Failures are only cleared once the whole sign-in succeeds, so wrong MFA codes keep
+	// counting towards the lockout
+	if h.requireMFA(c, ctx, authenticatedUser.ID, req.Email) {
+		return
+	}
+	if err := h.throttle.LoginSucceeded(ctx, req.Email); err != nil {
+		h.logger.WithContext(ctx).Error("Failed to reset sign-in failures", "error", err, "user_id", authenticatedUser.ID)
+	}
+	if err := h.userService.CompleteSignIn(ctx, authenticatedUser); err != nil {
+		if err == user.ErrUserNotFound {
+			response.Unauthorized(c, "AUTHENTICATION_FAILED", "This account no longer exists")
+			return
+		}
+		response.InternalError(c, "SIGN_IN_FAILED", "Failed to complete sign-in")
+		return
+	}
+
 	userAgent := c.GetHeader("User-Agent")
 	clientIP := c.ClientIP()
 	email := ""
@@ -125,7 +187,7 @@ func (h *AuthHandlers) Login(c *gin.Context) {
 
 	tokens, err := h.authService.GenerateTokens(ctx, authenticatedUser.ID, email, authenticatedUser.Roles, userAgent, clientIP)
 	if err != nil {
-		h.logger.Error("Failed to generate tokens", "error", err, "user_id", authenticatedUser.ID)
+		h.logger.WithContext(ctx).Error("Failed to generate tokens", "error", err, "user_id", authenticatedUser.ID)
 		response.InternalError(c, "TOKEN_GENERATION_FAILED", "Failed to generate authentication tokens")
 		return
 	}
@@ -138,20 +200,23 @@ func (h *AuthHandlers) Login(c *gin.Context) {
 		ExpiresIn:    tokens.ExpiresIn,
 	}
 
-	h.logger.Info("User logged in successfully", "user_id", authenticatedUser.ID)
+	h.logger.WithContext(ctx).Info("User logged in successfully", "user_id", authenticatedUser.ID)
 	response.Success(c, authResponse)
 }
 
 // GoogleSignIn handles Google Sign-In
 // @Summary      Google Sign-In
-// @Description  Authenticate or register a user using a Google ID token.
+// @Description  Authenticate or register a user using a Google ID token. Same as /auth/oidc/google. A Google account not yet linked is linked to the account with the same email if both have verified it. Users with two-factor authentication get a 202 with an MFA token instead, to exchange at /auth/mfa/verify.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Param        request body GoogleSignInRequest true "Google Sign-In Request"
 // @Success      200  {object}  response.APIResponse{data=AuthResponse}
+// @Success      202  {object}  response.APIResponse{data=MFAChallengeResponse}
 // @Failure      400  {object}  response.APIResponse{error=response.APIError}
 // @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
 // @Failure      500  {object}  response.APIResponse{error=response.APIError}
 // @Router       /auth/google [post]
 func (h *AuthHandlers) GoogleSignIn(c *gin.Context) {
@@ -161,33 +226,109 @@ func (h *AuthHandlers) GoogleSignIn(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	h.providerSignIn(c, user.ProviderGoogle, OIDCSignInRequest{IDToken: req.IDToken})
+}
+
+// OIDCSignIn handles sign-in with an OpenID Connect provider, such as Apple
+// @Summary      OpenID Connect Sign-In
+// @Description  Authenticate or register a user using an ID token from a configured OpenID Connect provider, such as google or apple. The token's signature, issuer, audience and expiry are checked, and its nonce when one is given. An account not yet linked is linked to the account with the same email if both have verified it. Users with two-factor authentication get a 202 with an MFA token instead, to exchange at /auth/mfa/verify.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        provider path string true "Provider name"
+// @Param        request body OIDCSignInRequest true "OIDC Sign-In Request"
+// @Success      200  {object}  response.APIResponse{data=AuthResponse}
+// @Success      202  {object}  response.APIResponse{data=MFAChallengeResponse}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/oidc/{provider} [post]
+func (h *AuthHandlers) OIDCSignIn(c *gin.Context) {
+	var req OIDCSignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	h.providerSignIn(c, c.Param("provider"), req)
+}
+
+// IssueNonce handles nonce requests for provider sign-in
+// @Summary      Get a sign-in nonce
+// @Description  Issue a single-use nonce to request a provider ID token with. Send it back as the nonce of /auth/oidc/{provider}; each nonce is accepted once, so a captured ID token cannot be replayed. Sign in with Apple expects its SHA-256 hash in the authorization request.
+// @Tags         Authentication
+// @Produce      json
+// @Success      200  {object}  response.APIResponse{data=OIDCNonceResponse}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/oidc/nonce [get]
+func (h *AuthHandlers) IssueNonce(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	googleUser, err := h.authService.VerifyGoogleIDToken(ctx, req.IDToken)
+	nonce, ttl, err := h.authService.IssueNonce(ctx)
 	if err != nil {
-		h.logger.Warn("Google ID token verification failed", "error", err)
-		response.Unauthorized(c, "INVALID_GOOGLE_TOKEN", "Invalid Google ID token")
+		h.logger.WithContext(ctx).Error("Failed to issue nonce", "error", err)
+		response.InternalError(c, "NONCE_ISSUE_FAILED", "Failed to issue nonce")
 		return
 	}
 
-	appUser, err := h.userService.CreateGoogleUser(ctx, googleUser.ID, googleUser.Email, googleUser.Name, googleUser.Picture)
+	response.Success(c, &OIDCNonceResponse{Nonce: nonce, ExpiresIn: int64(ttl.Seconds())})
+}
+
+// providerSignIn signs in the user an ID token from a login provider was issued for,
+// registering them on first sign-in
+func (h *AuthHandlers) providerSignIn(c *gin.Context, provider string, req OIDCSignInRequest) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	external, ok := verifyProviderToken(c, ctx, h.authService, h.logger, provider, req)
+	if !ok {
+		return
+	}
+	if external.DisplayName == "" {
+		external.DisplayName = req.DisplayName
+	}
+
+	appUser, err := h.userService.SignInWithIdentity(ctx, *external)
 	if err != nil {
-		h.logger.Error("Failed to create/get Google user", "error", err, "google_sub", googleUser.ID)
-		response.InternalError(c, "GOOGLE_USER_CREATION_FAILED", "Failed to process Google user")
+		if err == user.ErrAccountDisabled {
+			response.Forbidden(c, "ACCOUNT_DISABLED", "This account has been disabled")
+			return
+		}
+		if err == user.ErrAccountLinkRequired {
+			response.Conflict(c, "ACCOUNT_LINK_REQUIRED", err.Error())
+			return
+		}
+		h.logger.WithContext(ctx).Error("Failed to sign in provider user", "error", err, "provider", provider, "subject", external.Subject)
+		response.InternalError(c, "PROVIDER_USER_CREATION_FAILED", "Failed to process provider user")
 		return
 	}
 
-	userAgent := c.GetHeader("User-Agent")
-	clientIP := c.ClientIP()
 	email := ""
 	if appUser.Email != nil {
 		email = *appUser.Email
 	}
+	if h.requireMFA(c, ctx, appUser.ID, email) {
+		return
+	}
+	if err := h.userService.CompleteSignIn(ctx, appUser); err != nil {
+		if err == user.ErrUserNotFound {
+			response.Unauthorized(c, "AUTHENTICATION_FAILED", "This account no longer exists")
+			return
+		}
+		response.InternalError(c, "SIGN_IN_FAILED", "Failed to complete sign-in")
+		return
+	}
+
+	userAgent := c.GetHeader("User-Agent")
+	clientIP := c.ClientIP()
 
 	tokens, err := h.authService.GenerateTokens(ctx, appUser.ID, email, appUser.Roles, userAgent, clientIP)
 	if err != nil {
-		h.logger.Error("Failed to generate tokens", "error", err, "user_id", appUser.ID)
+		h.logger.WithContext(ctx).Error("Failed to generate tokens", "error", err, "user_id", appUser.ID)
 		response.InternalError(c, "TOKEN_GENERATION_FAILED", "Failed to generate authentication tokens")
 		return
 	}
@@ -200,13 +341,37 @@ func (h *AuthHandlers) GoogleSignIn(c *gin.Context) {
 		ExpiresIn:    tokens.ExpiresIn,
 	}
 
-	h.logger.Info("Google user signed in successfully", "user_id", appUser.ID)
+	h.logger.WithContext(ctx).Info("Provider user signed in successfully", "user_id", appUser.ID, "provider", provider)
 	response.Success(c, authResponse)
 }
 
+// verifyProviderToken verifies an ID token from a login provider, responding with 404 for
+// unknown providers and 401 for tokens that fail verification.
+func verifyProviderToken(c *gin.Context, ctx context.Context, authService *auth.AuthService, log logger.Logger, provider string, req OIDCSignInRequest) (*user.ExternalIdentity, bool) {
+	identity, err := authService.VerifyIDToken(ctx, provider, req.IDToken, req.Nonce)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnknownIdentityProvider) {
+			response.NotFound(c, "PROVIDER_NOT_FOUND", "Unknown login provider")
+			return nil, false
+		}
+		log.Warn("ID token verification failed", "error", err, "provider", provider)
+		response.Unauthorized(c, "INVALID_ID_TOKEN", "Invalid ID token")
+		return nil, false
+	}
+
+	return &user.ExternalIdentity{
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		DisplayName:   identity.Name,
+		PhotoURL:      identity.Picture,
+	}, true
+}
+
 // RefreshToken handles token refresh
 // @Summary      Refresh access token
-// @Description  Obtain a new access token using a valid refresh token.
+// @Description  Obtain a new token pair using a valid refresh token. Each refresh token can be used once; presenting one again revokes every token descended from the same sign-in.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -214,6 +379,8 @@ func (h *AuthHandlers) GoogleSignIn(c *gin.Context) {
 // @Success      200  {object}  response.APIResponse{data=TokenPair}
 // @Failure      400  {object}  response.APIResponse{error=response.APIError}
 // @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      429  {object}  response.APIResponse{error=response.APIError}
 // @Failure      500  {object}  response.APIResponse{error=response.APIError}
 // @Router       /auth/refresh [post]
 func (h *AuthHandlers) RefreshToken(c *gin.Context) {
@@ -229,14 +396,35 @@ func (h *AuthHandlers) RefreshToken(c *gin.Context) {
 	userAgent := c.GetHeader("User-Agent")
 	clientIP := c.ClientIP()
 
+	attempt := clientAttempt(c, "")
+	decision, err := h.throttle.CheckRefresh(ctx, attempt)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Refresh throttle check failed", "error", err)
+	}
+	if !decision.Allowed {
+		respondThrottled(c, decision)
+		return
+	}
+
 	newTokens, err := h.authService.RefreshTokens(ctx, req.RefreshToken, userAgent, clientIP)
 	if err != nil {
-		h.logger.Warn("Token refresh failed", "error", err)
+		h.logger.WithContext(ctx).Warn("Token refresh failed", "error", err)
+		if throttleErr := h.throttle.RefreshFailed(ctx, attempt, err.Error()); throttleErr != nil {
+			h.logger.WithContext(ctx).Error("Failed to record refresh failure", "error", throttleErr)
+		}
+		if errors.Is(err, auth.ErrAccountDisabled) {
+			response.Forbidden(c, "ACCOUNT_DISABLED", "This account has been disabled")
+			return
+		}
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			response.Unauthorized(c, "REFRESH_TOKEN_REUSED", "Refresh token was already used; all sessions from this sign-in have been revoked")
+			return
+		}
 		response.Unauthorized(c, "TOKEN_REFRESH_FAILED", "Invalid or expired refresh token")
 		return
 	}
 
-	h.logger.Info("Tokens refreshed successfully")
+	h.logger.WithContext(ctx).Info("Tokens refreshed successfully")
 	response.Success(c, newTokens)
 }
 
@@ -262,11 +450,341 @@ func (h *AuthHandlers) Logout(c *gin.Context) {
 	defer cancel()
 
 	if err := h.authService.RevokeToken(ctx, req.RefreshToken); err != nil {
-		h.logger.Warn("Token revocation failed", "error", err)
+		h.logger.WithContext(ctx).Warn("Token revocation failed", "error", err)
 	}
 
-	h.logger.Info("User logged out successfully")
+	h.logger.WithContext(ctx).Info("User logged out successfully")
 	response.Success(c, &LogoutResponse{
 		Message: "Logged out successfully",
 	})
 }
+
+// ForgotPassword handles password reset requests
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset link to the account with this address. The response is the same whether or not an account exists.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "Forgot Password Request"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/password/forgot [post]
+func (h *AuthHandlers) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.credentialsService.RequestPasswordReset(ctx, req.Email); err != nil {
+		h.logger.WithContext(ctx).Error("Failed to start password reset", "error", err, "email", req.Email)
+		response.InternalError(c, "PASSWORD_RESET_FAILED", "Failed to start password reset")
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{
+		Message: "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword handles password resets
+// @Summary      Reset password
+// @Description  Set a new password using the token from a password reset email. Every session of the account is signed out, and a sign-in lockout is lifted.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Reset Password Request"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/password/reset [post]
+func (h *AuthHandlers) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.credentialsService.ResetPassword(ctx, req.Token, req.Password, clientAttempt(c, "")); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOneTimeTokenInvalid):
+			response.BadRequest(c, "INVALID_TOKEN", "Password reset link is invalid or has expired")
+		case errors.Is(err, user.ErrAccountDisabled):
+			response.Forbidden(c, "ACCOUNT_DISABLED", "This account has been disabled")
+		default:
+			h.logger.WithContext(ctx).Error("Failed to reset password", "error", err)
+			response.InternalError(c, "PASSWORD_RESET_FAILED", "Failed to reset password")
+		}
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "Password has been reset; sign in with your new password"})
+}
+
+// VerifyEmail handles email verification
+// @Summary      Verify email address
+// @Description  Mark an account's email address as verified using the token from a verification email.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body VerifyEmailRequest true "Verify Email Request"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/email/verify [post]
+func (h *AuthHandlers) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	verifiedUser, err := h.credentialsService.VerifyEmail(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, auth.ErrOneTimeTokenInvalid) {
+			response.BadRequest(c, "INVALID_TOKEN", "Verification link is invalid or has expired")
+			return
+		}
+		h.logger.WithContext(ctx).Error("Failed to verify email", "error", err)
+		response.InternalError(c, "EMAIL_VERIFICATION_FAILED", "Failed to verify email")
+		return
+	}
+
+	h.logger.WithContext(ctx).Info("Email verified successfully", "user_id", verifiedUser.ID)
+	response.Success(c, &response.SuccessMessage{Message: "Email address verified"})
+}
+
+// UnlockAccount handles account unlocks
+// @Summary      Unlock account
+// @Description  Lift a sign-in lockout using the token from the email sent when the account was locked.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body UnlockAccountRequest true "Unlock Account Request"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/unlock [post]
+func (h *AuthHandlers) UnlockAccount(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.credentialsService.UnlockAccount(ctx, req.Token, clientAttempt(c, "")); err != nil {
+		if errors.Is(err, auth.ErrOneTimeTokenInvalid) {
+			response.BadRequest(c, "INVALID_TOKEN", "Unlock link is invalid or has expired")
+			return
+		}
+		h.logger.WithContext(ctx).Error("Failed to unlock account", "error", err)
+		response.InternalError(c, "ACCOUNT_UNLOCK_FAILED", "Failed to unlock account")
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "Account unlocked; you can sign in again"})
+}
+
+// VerifyMFA completes a sign-in that requires two-factor authentication
+// @Summary      Complete two-factor sign-in
+// @Description  Exchange the MFA token from a sign-in and a code from the user's authenticator app, or one of their recovery codes, for tokens. Each MFA token can be used once and stops working after 5 wrong codes. Wrong codes count as failed sign-ins, so they slow down and eventually lock the account like wrong passwords.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body VerifyMFARequest true "Verify MFA Request"
+// @Success      200  {object}  response.APIResponse{data=TokenPair}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      429  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /auth/mfa/verify [post]
+func (h *AuthHandlers) VerifyMFA(c *gin.Context) {
+	var req VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	challenge, err := h.mfaService.GetChallenge(ctx, req.MFAToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrMFAChallengeInvalid) {
+			response.Unauthorized(c, "MFA_TOKEN_INVALID", "MFA token is invalid or has expired; sign in again")
+			return
+		}
+		h.logger.WithContext(ctx).Error("Failed to read MFA challenge", "error", err)
+		response.InternalError(c, "MFA_VERIFICATION_FAILED", "Failed to verify authentication code")
+		return
+	}
+
+	// Wrong codes count as failed sign-ins to the account, so a known password doesn't give
+	// unlimited guesses across fresh challenges
+	attempt := clientAttempt(c, challenge.Email)
+	if challenge.Email != "" {
+		decision, err := h.throttle.CheckLogin(ctx, attempt)
+		if err != nil {
+			h.logger.WithContext(ctx).Error("Login throttle check failed", "error", err)
+		}
+		if !decision.Allowed {
+			respondThrottled(c, decision)
+			return
+		}
+	}
+
+	userID, err := h.mfaService.CompleteChallenge(ctx, req.MFAToken, req.Code, attempt)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			h.mfaFailed(ctx, attempt)
+			response.Unauthorized(c, "INVALID_MFA_CODE", "Invalid authentication code")
+		case errors.Is(err, auth.ErrMFAChallengeInvalid):
+			h.mfaFailed(ctx, attempt)
+			response.Unauthorized(c, "MFA_TOKEN_INVALID", "MFA token is invalid or has expired; sign in again")
+		default:
+			h.logger.WithContext(ctx).Error("Failed to verify MFA code", "error", err)
+			response.InternalError(c, "MFA_VERIFICATION_FAILED", "Failed to verify authentication code")
+		}
+		return
+	}
+
+	verifiedUser, err := h.userService.GetUserByID(ctx, userID.String())
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Failed to load user after MFA", "error", err, "user_id", userID)
+		response.InternalError(c, "MFA_VERIFICATION_FAILED", "Failed to complete sign-in")
+		return
+	}
+	if !verifiedUser.IsActive {
+		response.Forbidden(c, "ACCOUNT_DISABLED", "This account has been disabled")
+		return
+	}
+
+	if challenge.Email != "" {
+		if err := h.throttle.LoginSucceeded(ctx, challenge.Email); err != nil {
+			h.logger.WithContext(ctx).Error("Failed to reset sign-in failures", "error", err, "user_id", verifiedUser.ID)
+		}
+	}
+	if err := h.userService.CompleteSignIn(ctx, verifiedUser); err != nil {
+		if err == user.ErrUserNotFound {
+			response.Unauthorized(c, "AUTHENTICATION_FAILED", "This account no longer exists")
+			return
+		}
+		response.InternalError(c, "SIGN_IN_FAILED", "Failed to complete sign-in")
+		return
+	}
+
+	email := ""
+	if verifiedUser.Email != nil {
+		email = *verifiedUser.Email
+	}
+
+	tokens, err := h.authService.GenerateTokens(ctx, verifiedUser.ID, email, verifiedUser.Roles, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Failed to generate tokens", "error", err, "user_id", verifiedUser.ID)
+		response.InternalError(c, "TOKEN_GENERATION_FAILED", "Failed to generate authentication tokens")
+		return
+	}
+
+	h.logger.WithContext(ctx).Info("User completed two-factor sign-in", "user_id", verifiedUser.ID)
+	response.Success(c, tokens)
+}
+
+// JWKS publishes the keys access tokens are signed with
+// @Summary      JSON Web Key Set
+// @Description  Public keys for verifying access tokens, identified by the kid header of each token. Keys being rotated out stay listed until tokens signed with them expire. Empty when tokens are signed with a shared secret.
+// @Tags         Authentication
+// @Produce      json
+// @Success      200  {object}  auth.JWKS
+// @Router       /.well-known/jwks.json [get]
+func (h *AuthHandlers) JWKS(c *gin.Context) {
+	// Served as a bare key set, the format JWT libraries expect, and cacheable so verifiers
+	// don't fetch it for every token
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
+// requireMFA responds with an MFA challenge if the user has two-factor authentication enabled,
+// and reports whether it responded. Wrong codes count as failed sign-ins to email.
+func (h *AuthHandlers) requireMFA(c *gin.Context, ctx context.Context, userID uuid.UUID, email string) bool {
+	enabled, err := h.mfaService.IsEnabled(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Failed to check two-factor authentication", "error", err, "user_id", userID)
+		response.InternalError(c, "MFA_CHECK_FAILED", "Failed to sign in")
+		return true
+	}
+	if !enabled {
+		return false
+	}
+
+	token, err := h.mfaService.CreateChallenge(ctx, userID, email)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Failed to create MFA challenge", "error", err, "user_id", userID)
+		response.InternalError(c, "MFA_CHALLENGE_FAILED", "Failed to sign in")
+		return true
+	}
+
+	response.Accepted(c, &MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(h.mfaService.ChallengeTTL().Seconds()),
+	})
+	return true
+}
+
+// loginFailed counts a failed sign-in and, if it locked the account, emails an unlock link
+func (h *AuthHandlers) loginFailed(ctx context.Context, attempt auth.Attempt) {
+	h.recordLoginFailure(ctx, attempt, "invalid email or password")
+}
+
+// mfaFailed counts a wrong code at the second step of a sign-in as a failed sign-in
+func (h *AuthHandlers) mfaFailed(ctx context.Context, attempt auth.Attempt) {
+	if attempt.Email == "" {
+		return
+	}
+	h.recordLoginFailure(ctx, attempt, "invalid authentication code")
+}
+
+// recordLoginFailure counts a failed sign-in and, if it locked the account, emails an unlock link
+func (h *AuthHandlers) recordLoginFailure(ctx context.Context, attempt auth.Attempt, detail string) {
+	locked, err := h.throttle.LoginFailed(ctx, attempt, detail)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Failed to record sign-in failure", "error", err)
+		return
+	}
+	if !locked {
+		return
+	}
+	if err := h.credentialsService.SendUnlockEmail(ctx, attempt.Email); err != nil {
+		h.logger.WithContext(ctx).Error("Failed to send account unlock email", "error", err)
+	}
+}
+
+// clientAttempt describes the client making the request for throttling and auditing
+func clientAttempt(c *gin.Context, email string) auth.Attempt {
+	return auth.Attempt{Email: email, IP: c.ClientIP(), UserAgent: c.GetHeader("User-Agent")}
+}
+
+// respondThrottled rejects a throttled request with 429 and a Retry-After header
+func respondThrottled(c *gin.Context, decision auth.ThrottleDecision) {
+	if decision.Reason == auth.ThrottleAccountLocked {
+		response.TooManyRequests(c, "ACCOUNT_LOCKED", "Too many failed sign-in attempts. Try again later, or use the link emailed to you to unlock your account", decision.RetryAfter)
+		return
+	}
+	response.TooManyRequests(c, "TOO_MANY_ATTEMPTS", "Too many attempts, please try again later", decision.RetryAfter)
+}
@@ -39,6 +39,27 @@ type AddHistoryRequest struct {
 	ArtworkURL      string `json:"artwork_url"`
 }
 
+type GetDownloadsRequest struct {
+	Page  int    `form:"page,default=1"`
+	Size  int    `form:"size,default=20"`
+	State string `form:"state" binding:"omitempty,oneof=pending downloading completed failed paused"`
+}
+
+type AddDownloadRequest struct {
+	Provider        string `json:"provider" binding:"required"`
+	ProviderTrackID string `json:"provider_track_id" binding:"required"`
+	Title           string `json:"title" binding:"required"`
+	Artist          string `json:"artist" binding:"required"`
+	Album           string `json:"album"`
+	DurationMs      int    `json:"duration_ms"`
+	ArtworkURL      string `json:"artwork_url"`
+	Quality         string `json:"quality" binding:"required,max=50"`
+}
+
+type UpdateDownloadStateRequest struct {
+	State string `json:"state" binding:"required,oneof=pending paused"` // paused to pause, pending to resume or retry
+}
+
 // --- Library Responses ---
 
 type FavoriteResponse struct {
@@ -65,6 +86,30 @@ type HistoryResponse struct {
 	PlayedAt        time.Time `json:"played_at"`
 }
 
+type DownloadResponse struct {
+	ID              uuid.UUID `json:"id"`
+	Provider        string    `json:"provider"`
+	ProviderTrackID string    `json:"provider_track_id"`
+	Title           string    `json:"title"`
+	Artist          string    `json:"artist"`
+	Album           string    `json:"album"`
+	DurationMs      int       `json:"duration_ms"`
+	ArtworkURL      string    `json:"artwork_url"`
+	State           string    `json:"state"`
+	Progress        int       `json:"progress"`
+	FileSize        *int64    `json:"file_size,omitempty"`
+	Quality         string    `json:"quality"`
+	FailureReason   *string   `json:"failure_reason,omitempty"`
+	RetryCount      int       `json:"retry_count"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type DownloadFileURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 func mapFavoriteToResponse(f *library.Favorite) FavoriteResponse {
 	return FavoriteResponse{
 		ID:              f.ID,
@@ -91,4 +136,25 @@ func mapHistoryToResponse(h *library.History) HistoryResponse {
 		ArtworkURL:      h.ArtworkURL,
 		PlayedAt:        h.PlayedAt,
 	}
-}
\ No newline at end of file
+}
+
+func mapDownloadToResponse(d *library.Download) DownloadResponse {
+	return DownloadResponse{
+		ID:              d.ID,
+		Provider:        d.Provider,
+		ProviderTrackID: d.ProviderTrackID,
+		Title:           d.Title,
+		Artist:          d.Artist,
+		Album:           d.Album,
+		DurationMs:      d.DurationMs,
+		ArtworkURL:      d.ArtworkURL,
+		State:           string(d.State),
+		Progress:        d.Progress,
+		FileSize:        d.FileSize,
+		Quality:         d.Quality,
+		FailureReason:   d.FailureReason,
+		RetryCount:      d.RetryCount,
+		CreatedAt:       d.CreatedAt,
+		UpdatedAt:       d.UpdatedAt,
+	}
+}
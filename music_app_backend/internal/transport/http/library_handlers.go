@@ -1,7 +1,16 @@
 package http
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/mosesmmoisebidth/music_backend/internal/blob"
 	"github.com/mosesmmoisebidth/music_backend/internal/library"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 	"github.com/mosesmmoisebidth/music_backend/pkg/response"
@@ -45,7 +54,7 @@ func (h *LibraryHandlers) GetFavorites(c *gin.Context) {
 
 	favorites, total, err := h.service.GetFavorites(c.Request.Context(), userID.(string), req.Page, req.Size)
 	if err != nil {
-		h.logger.Error("failed to get favorites", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to get favorites", "error", err, "user_id", userID)
 		response.InternalError(c, "FAVORITES_FETCH_FAILED", "Failed to fetch favorites")
 		return
 	}
@@ -101,7 +110,7 @@ func (h *LibraryHandlers) AddFavorite(c *gin.Context) {
 			response.Conflict(c, "FAVORITE_EXISTS", err.Error())
 			return
 		}
-		h.logger.Error("failed to add favorite", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to add favorite", "error", err, "user_id", userID)
 		response.InternalError(c, "FAVORITE_ADD_FAILED", "Failed to add favorite")
 		return
 	}
@@ -135,7 +144,7 @@ func (h *LibraryHandlers) RemoveFavorite(c *gin.Context) {
 			response.NotFound(c, "FAVORITE_NOT_FOUND", err.Error())
 			return
 		}
-		h.logger.Error("failed to remove favorite", "error", err, "favorite_id", favoriteID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to remove favorite", "error", err, "favorite_id", favoriteID)
 		response.InternalError(c, "FAVORITE_REMOVE_FAILED", "Failed to remove favorite")
 		return
 	}
@@ -170,7 +179,7 @@ func (h *LibraryHandlers) GetHistory(c *gin.Context) {
 
 	history, total, err := h.service.GetUserHistory(c.Request.Context(), userID.(string), req.Page, req.Size)
 	if err != nil {
-		h.logger.Error("failed to get history", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to get history", "error", err, "user_id", userID)
 		response.InternalError(c, "HISTORY_FETCH_FAILED", "Failed to fetch history")
 		return
 	}
@@ -221,10 +230,371 @@ func (h *LibraryHandlers) AddHistory(c *gin.Context) {
 
 	history, err := h.service.AddHistory(c.Request.Context(), userID.(string), trackData)
 	if err != nil {
-		h.logger.Error("failed to add history", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to add history", "error", err, "user_id", userID)
 		response.InternalError(c, "HISTORY_ADD_FAILED", "Failed to add to history")
 		return
 	}
 
 	response.Created(c, mapHistoryToResponse(history))
-}
\ No newline at end of file
+}
+
+// GetDownloads retrieves the user's downloads.
+// @Summary      Get downloads
+// @Description  Retrieves a paginated list of the authenticated user's downloads, optionally filtered by state.
+// @Tags         Library
+// @Produce      json
+// @Security     Bearer
+// @Param        page query int false "Page number" default(1)
+// @Param        size query int false "Page size" default(20)
+// @Param        state query string false "Download state" Enums(pending, downloading, completed, failed, paused)
+// @Success      200 {object} response.APIResponse{data=response.PaginatedData{downloads=[]DownloadResponse}}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads [get]
+func (h *LibraryHandlers) GetDownloads(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	var req GetDownloadsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	var state *library.DownloadState
+	if req.State != "" {
+		s := library.DownloadState(req.State)
+		state = &s
+	}
+
+	downloads, total, err := h.service.GetUserDownloads(c.Request.Context(), userID.(string), req.Page, req.Size, state)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to get downloads", "error", err, "user_id", userID)
+		response.InternalError(c, "DOWNLOADS_FETCH_FAILED", "Failed to fetch downloads")
+		return
+	}
+
+	var downloadResponses []DownloadResponse
+	for _, d := range downloads {
+		downloadResponses = append(downloadResponses, mapDownloadToResponse(&d))
+	}
+
+	response.Success(c, response.NewPaginatedData(downloadResponses, req.Page, req.Size, total))
+}
+
+// AddDownload queues a track for download.
+// @Summary      Add a download
+// @Description  Queues a track for download in the pending state.
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request body AddDownloadRequest true "Download track information"
+// @Success      201 {object} response.APIResponse{data=DownloadResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads [post]
+func (h *LibraryHandlers) AddDownload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	var req AddDownloadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	trackData := library.TrackData{
+		Provider:        req.Provider,
+		ProviderTrackID: req.ProviderTrackID,
+		Title:           req.Title,
+		Artist:          req.Artist,
+		Album:           req.Album,
+		DurationMs:      req.DurationMs,
+		ArtworkURL:      req.ArtworkURL,
+	}
+
+	download, err := h.service.AddDownload(c.Request.Context(), userID.(string), trackData, req.Quality)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to add download", "error", err, "user_id", userID)
+		response.InternalError(c, "DOWNLOAD_ADD_FAILED", "Failed to add download")
+		return
+	}
+
+	response.Created(c, mapDownloadToResponse(download))
+}
+
+// GetDownload retrieves a single download.
+// @Summary      Get a download
+// @Description  Retrieves a single download owned by the authenticated user.
+// @Tags         Library
+// @Produce      json
+// @Security     Bearer
+// @Param        downloadId path string true "Download ID"
+// @Success      200 {object} response.APIResponse{data=DownloadResponse}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads/{downloadId} [get]
+func (h *LibraryHandlers) GetDownload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	downloadID := c.Param("downloadId")
+
+	download, err := h.service.GetDownload(c.Request.Context(), userID.(string), downloadID)
+	if err != nil {
+		h.handleDownloadError(c, err, downloadID, "DOWNLOAD_FETCH_FAILED", "Failed to fetch download")
+		return
+	}
+
+	response.Success(c, mapDownloadToResponse(download))
+}
+
+// UpdateDownloadState moves a download to a new state.
+// @Summary      Update download state
+// @Description  Pauses a pending or downloading download, resumes a paused one (paused→pending) or retries a failed one (failed→pending). Downloads start, complete and fail through the download worker only.
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        downloadId path string true "Download ID"
+// @Param        request body UpdateDownloadStateRequest true "Target state"
+// @Success      200 {object} response.APIResponse{data=DownloadResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      409 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads/{downloadId}/state [patch]
+func (h *LibraryHandlers) UpdateDownloadState(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	downloadID := c.Param("downloadId")
+
+	var req UpdateDownloadStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	download, err := h.service.UpdateDownloadState(c.Request.Context(), userID.(string), downloadID, library.DownloadState(req.State))
+	if err != nil {
+		h.handleDownloadError(c, err, downloadID, "DOWNLOAD_UPDATE_FAILED", "Failed to update download")
+		return
+	}
+
+	response.Success(c, mapDownloadToResponse(download))
+}
+
+// RetryDownload re-queues a failed download.
+// @Summary      Retry a failed download
+// @Description  Moves a failed download back to the pending state so it is downloaded again.
+// @Tags         Library
+// @Produce      json
+// @Security     Bearer
+// @Param        downloadId path string true "Download ID"
+// @Success      200 {object} response.APIResponse{data=DownloadResponse}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      409 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads/{downloadId}/retry [post]
+func (h *LibraryHandlers) RetryDownload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	downloadID := c.Param("downloadId")
+
+	download, err := h.service.RetryDownload(c.Request.Context(), userID.(string), downloadID)
+	if err != nil {
+		h.handleDownloadError(c, err, downloadID, "DOWNLOAD_RETRY_FAILED", "Failed to retry download")
+		return
+	}
+
+	response.Success(c, mapDownloadToResponse(download))
+}
+
+// RemoveDownload removes a download.
+// @Summary      Remove a download
+// @Description  Removes a download from the authenticated user's library.
+// @Tags         Library
+// @Produce      json
+// @Security     Bearer
+// @Param        downloadId path string true "Download ID"
+// @Success      200 {object} response.APIResponse{data=response.SuccessMessage}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads/{downloadId} [delete]
+func (h *LibraryHandlers) RemoveDownload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	downloadID := c.Param("downloadId")
+
+	if err := h.service.RemoveDownload(c.Request.Context(), userID.(string), downloadID); err != nil {
+		h.handleDownloadError(c, err, downloadID, "DOWNLOAD_REMOVE_FAILED", "Failed to remove download")
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "Download removed successfully"})
+}
+
+// GetDownloadFile streams the audio file of a completed download.
+// @Summary      Stream a downloaded file
+// @Description  Streams the audio of a completed download with HTTP Range support. Requires either a bearer token for the owner or a signature from the signed URL endpoint.
+// @Tags         Library
+// @Produce      audio/mpeg
+// @Produce      audio/mp4
+// @Security     Bearer
+// @Param        downloadId path string true "Download ID"
+// @Param        expires query int false "Signed URL expiry (Unix seconds)"
+// @Param        signature query string false "Signed URL signature"
+// @Success      200 {file} binary
+// @Success      206 {file} binary
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      409 {object} response.APIResponse{error=response.APIError}
+// @Failure      416 {string} string
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads/{downloadId}/file [get]
+func (h *LibraryHandlers) GetDownloadFile(c *gin.Context) {
+	downloadID := c.Param("downloadId")
+
+	var (
+		download *library.Download
+		object   *blob.Object
+		err      error
+	)
+	if signature := c.Query("signature"); signature != "" {
+		expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+		download, object, err = h.service.OpenSignedDownloadFile(c.Request.Context(), downloadID, expires, signature)
+	} else {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+			return
+		}
+		download, object, err = h.service.OpenDownloadFile(c.Request.Context(), userID.(string), downloadID)
+	}
+	if err != nil {
+		h.handleDownloadError(c, err, downloadID, "DOWNLOAD_FILE_FAILED", "Failed to open download file")
+		return
+	}
+	defer object.Close()
+
+	c.Header("Content-Type", audioContentType(*download.LocalPath))
+	c.Header("ETag", fmt.Sprintf(`"%s-%x-%x"`, download.ID, object.Size, object.ModTime.UnixNano()))
+	c.Header("Cache-Control", "private, max-age=3600")
+
+	// ServeContent handles Range, If-Range, If-None-Match and If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, "", object.ModTime, object)
+}
+
+// GetDownloadFileURL issues a short-lived signed URL for a downloaded file.
+// @Summary      Get a signed file URL
+// @Description  Returns a short-lived URL for streaming a completed download without an Authorization header, for native players.
+// @Tags         Library
+// @Produce      json
+// @Security     Bearer
+// @Param        downloadId path string true "Download ID"
+// @Success      200 {object} response.APIResponse{data=DownloadFileURLResponse}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      409 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /downloads/{downloadId}/file-url [get]
+func (h *LibraryHandlers) GetDownloadFileURL(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	downloadID := c.Param("downloadId")
+
+	expires, signature, err := h.service.SignDownloadFileURL(c.Request.Context(), userID.(string), downloadID)
+	if err != nil {
+		h.handleDownloadError(c, err, downloadID, "DOWNLOAD_URL_FAILED", "Failed to create file URL")
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	fileURL := url.URL{
+		Scheme:   scheme,
+		Host:     c.Request.Host,
+		Path:     path.Join(path.Dir(c.Request.URL.Path), "file"),
+		RawQuery: url.Values{"expires": {strconv.FormatInt(expires, 10)}, "signature": {signature}}.Encode(),
+	}
+
+	response.Success(c, DownloadFileURLResponse{
+		URL:       fileURL.String(),
+		ExpiresAt: time.Unix(expires, 0).UTC(),
+	})
+}
+
+// handleDownloadError maps download service errors to HTTP responses.
+func (h *LibraryHandlers) handleDownloadError(c *gin.Context, err error, downloadID, code, message string) {
+	switch err {
+	case library.ErrDownloadNotFound:
+		response.NotFound(c, "DOWNLOAD_NOT_FOUND", err.Error())
+	case library.ErrInvalidDownloadID:
+		response.BadRequest(c, "INVALID_DOWNLOAD_ID", err.Error())
+	case library.ErrInvalidStateTransition:
+		response.Conflict(c, "INVALID_STATE_TRANSITION", err.Error())
+	case library.ErrDownloadNotReady:
+		response.Conflict(c, "DOWNLOAD_NOT_READY", err.Error())
+	case library.ErrInvalidFileURL:
+		response.Unauthorized(c, "INVALID_FILE_URL", err.Error())
+	case blob.ErrNotFound:
+		response.NotFound(c, "DOWNLOAD_FILE_NOT_FOUND", "The downloaded file no longer exists")
+	default:
+		h.logger.WithContext(c.Request.Context()).Error("download operation failed", "error", err, "download_id", downloadID)
+		response.InternalError(c, code, message)
+	}
+}
+
+// audioContentType returns the MIME type for an audio file based on its extension.
+func audioContentType(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".mp3":
+		return "audio/mpeg"
+	case ".m4a", ".mp4":
+		return "audio/mp4"
+	case ".aac":
+		return "audio/aac"
+	case ".ogg", ".oga":
+		return "audio/ogg"
+	case ".wav":
+		return "audio/wav"
+	case ".flac":
+		return "audio/flac"
+	default:
+		return "application/octet-stream"
+	}
+}
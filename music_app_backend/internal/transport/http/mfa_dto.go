@@ -0,0 +1,40 @@
+package http
+
+import (
+	"time"
+
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+)
+
+// --- MFA Requests ---
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"` // a TOTP code, or a recovery code where accepted
+}
+
+// --- MFA Responses ---
+
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func mapMFAStatusToResponse(s *auth.MFAStatus) MFAStatusResponse {
+	return MFAStatusResponse{
+		Enabled:                s.Enabled,
+		Pending:                s.Pending,
+		EnabledAt:              s.EnabledAt,
+		RecoveryCodesRemaining: s.RecoveryCodesRemaining,
+	}
+}
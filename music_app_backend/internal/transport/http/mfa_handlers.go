@@ -0,0 +1,236 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/user"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"github.com/mosesmmoisebidth/music_backend/pkg/response"
+)
+
+// MFAHandlers contains two-factor authentication HTTP handlers
+type MFAHandlers struct {
+	mfaService  *auth.MFAService
+	userService *user.Service
+	logger      logger.Logger
+}
+
+// NewMFAHandlers creates new two-factor authentication handlers
+func NewMFAHandlers(mfaService *auth.MFAService, userService *user.Service, logger logger.Logger) *MFAHandlers {
+	return &MFAHandlers{mfaService: mfaService, userService: userService, logger: logger}
+}
+
+// GetStatus reports the current user's two-factor authentication setup.
+// @Summary      Get two-factor authentication status
+// @Description  Reports whether two-factor authentication is enabled or pending confirmation, and how many unused recovery codes remain.
+// @Tags         MFA
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=MFAStatusResponse}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/mfa [get]
+func (h *MFAHandlers) GetStatus(c *gin.Context) {
+	userID, ok := mfaUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaService.Status(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to get MFA status", "error", err, "user_id", userID)
+		response.InternalError(c, "MFA_STATUS_FAILED", "Failed to fetch two-factor authentication status")
+		return
+	}
+
+	response.Success(c, mapMFAStatusToResponse(status))
+}
+
+// BeginTOTPEnrollment starts adding an authenticator app for the current user.
+// @Summary      Start TOTP enrollment
+// @Description  Generates a TOTP secret and its otpauth:// provisioning URI, to show as a QR code. Two-factor authentication is only enabled once a code from the app is confirmed at /users/me/mfa/totp/verify. Starting again replaces a pending secret.
+// @Tags         MFA
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=TOTPEnrollmentResponse}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/mfa/totp [post]
+func (h *MFAHandlers) BeginTOTPEnrollment(c *gin.Context) {
+	userID, ok := mfaUserID(c)
+	if !ok {
+		return
+	}
+
+	currentUser, err := h.userService.GetUserByID(c.Request.Context(), userID.String())
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to get current user", "error", err, "user_id", userID)
+		response.InternalError(c, "USER_FETCH_FAILED", "Failed to fetch user information")
+		return
+	}
+	accountName := userID.String()
+	if currentUser.Email != nil {
+		accountName = *currentUser.Email
+	}
+
+	enrollment, err := h.mfaService.BeginEnrollment(c.Request.Context(), userID, accountName)
+	if err != nil {
+		if errors.Is(err, auth.ErrMFAAlreadyEnabled) {
+			response.Conflict(c, "MFA_ALREADY_ENABLED", err.Error())
+			return
+		}
+		h.logger.WithContext(c.Request.Context()).Error("failed to start TOTP enrollment", "error", err, "user_id", userID)
+		response.InternalError(c, "MFA_ENROLLMENT_FAILED", "Failed to start two-factor enrollment")
+		return
+	}
+
+	response.Success(c, TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	})
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication for the current user.
+// @Summary      Confirm TOTP enrollment
+// @Description  Enables two-factor authentication once a code from the authenticator app matches the pending secret. Returns recovery codes, which are only shown this once.
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request body MFACodeRequest true "Code from the authenticator app"
+// @Success      200  {object}  response.APIResponse{data=RecoveryCodesResponse}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/mfa/totp/verify [post]
+func (h *MFAHandlers) ConfirmTOTPEnrollment(c *gin.Context) {
+	userID, ok := mfaUserID(c)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(c.Request.Context(), userID, req.Code, clientAttempt(c, ""))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			response.BadRequest(c, "INVALID_MFA_CODE", "Invalid authentication code")
+		case errors.Is(err, auth.ErrMFANotEnrolling):
+			response.BadRequest(c, "MFA_NOT_ENROLLING", "Start enrollment before confirming it")
+		case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+			response.Conflict(c, "MFA_ALREADY_ENABLED", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to confirm TOTP enrollment", "error", err, "user_id", userID)
+			response.InternalError(c, "MFA_ENROLLMENT_FAILED", "Failed to enable two-factor authentication")
+		}
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).Info("Two-factor authentication enabled", "user_id", userID)
+	response.Success(c, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns off two-factor authentication for the current user.
+// @Summary      Disable two-factor authentication
+// @Description  Removes the authenticator app and recovery codes after checking a TOTP or recovery code. A pending enrollment is cancelled without a code.
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request body MFACodeRequest true "TOTP or recovery code"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/mfa/totp [delete]
+func (h *MFAHandlers) DisableTOTP(c *gin.Context) {
+	userID, ok := mfaUserID(c)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	if err := h.mfaService.Disable(c.Request.Context(), userID, req.Code, clientAttempt(c, "")); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			response.BadRequest(c, "INVALID_MFA_CODE", "Invalid authentication code")
+		case errors.Is(err, auth.ErrMFANotEnabled):
+			response.NotFound(c, "MFA_NOT_ENABLED", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to disable two-factor authentication", "error", err, "user_id", userID)
+			response.InternalError(c, "MFA_DISABLE_FAILED", "Failed to disable two-factor authentication")
+		}
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).Info("Two-factor authentication disabled", "user_id", userID)
+	response.Success(c, &response.SuccessMessage{Message: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes.
+// @Summary      Regenerate recovery codes
+// @Description  Replaces all recovery codes after checking a TOTP or recovery code. Earlier codes stop working, and the new ones are only shown this once.
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request body MFACodeRequest true "TOTP or recovery code"
+// @Success      200  {object}  response.APIResponse{data=RecoveryCodesResponse}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/mfa/recovery-codes [post]
+func (h *MFAHandlers) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := mfaUserID(c)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code, clientAttempt(c, ""))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			response.BadRequest(c, "INVALID_MFA_CODE", "Invalid authentication code")
+		case errors.Is(err, auth.ErrMFANotEnabled):
+			response.NotFound(c, "MFA_NOT_ENABLED", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to regenerate recovery codes", "error", err, "user_id", userID)
+			response.InternalError(c, "RECOVERY_CODES_FAILED", "Failed to regenerate recovery codes")
+		}
+		return
+	}
+
+	response.Success(c, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// mfaUserID returns the authenticated user's ID, responding with 401 if there is none.
+func mfaUserID(c *gin.Context) (uuid.UUID, bool) {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return claims.UserID, true
+}
@@ -9,6 +9,7 @@ import (
 
 type SearchTracksRequest struct {
 	Query    string  `form:"q" binding:"required"`
+	Cursor   *string `form:"cursor,omitempty"` // next_cursor from a previous merged search
 	Page     int     `form:"page,default=1"`
 	Size     int     `form:"size,default=20"`
 	Provider *string `form:"provider,omitempty"`
@@ -29,26 +30,59 @@ type GetTopChartsRequest struct {
 	Provider *string `form:"provider,omitempty"`
 }
 
+type GetCategoryPlaylistsRequest struct {
+	CategoryID string  `uri:"categoryId" binding:"required"`
+	Provider   *string `form:"provider,omitempty"`
+	Page       int     `form:"page,default=1"`
+	Size       int     `form:"size,default=20"`
+}
+
+type GetPlaylistTracksRequest struct {
+	Provider   string `uri:"provider" binding:"required"`
+	PlaylistID string `uri:"playlistId" binding:"required"`
+	Page       int    `form:"page,default=1"`
+	Size       int    `form:"size,default=20"`
+}
+
 // --- Music Responses ---
 
 type TrackResponse struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Artist      string `json:"artist"`
-	Album       string `json:"album"`
-	Duration    int64  `json:"duration"` // Changed to int64
-	ArtworkURL  string `json:"artwork_url"`
-	PreviewURL  string `json:"preview_url"`
-	TrackNumber int    `json:"track_number"`
-	ReleaseDate string `json:"release_date"` // Changed to string
-	Genre       string `json:"genre"`
+	ID          string                `json:"id"`
+	Title       string                `json:"title"`
+	Artist      string                `json:"artist"`
+	Album       string                `json:"album"`
+	Duration    int64                 `json:"duration"` // Changed to int64
+	ArtworkURL  string                `json:"artwork_url"`
+	PreviewURL  string                `json:"preview_url"`
+	TrackNumber int                   `json:"track_number"`
+	ReleaseDate string                `json:"release_date"` // Changed to string
+	Genre       string                `json:"genre"`
+	Provider    string                `json:"provider"`
+	ExternalURL string                `json:"external_url"`
+	Explicit    bool                  `json:"explicit"`
+	Popularity  int                   `json:"popularity"`
+	ISRC        string                `json:"isrc,omitempty"`
+	Sources     []TrackSourceResponse `json:"sources,omitempty"`
+}
+
+type TrackSourceResponse struct {
 	Provider    string `json:"provider"`
-	ExternalURL string `json:"external_url"`
-	Explicit    bool   `json:"explicit"`
-	Popularity  int    `json:"popularity"`
+	ID          string `json:"id"`
+	ExternalURL string `json:"external_url,omitempty"`
+	PreviewURL  string `json:"preview_url,omitempty"`
 }
 
 func mapTrackToResponse(t *music.Track) TrackResponse {
+	var sources []TrackSourceResponse
+	for _, source := range t.Sources {
+		sources = append(sources, TrackSourceResponse{
+			Provider:    source.Provider,
+			ID:          source.ID,
+			ExternalURL: source.ExternalURL,
+			PreviewURL:  source.PreviewURL,
+		})
+	}
+
 	return TrackResponse{
 		ID:          t.ID,
 		Title:       t.Title,
@@ -64,5 +98,62 @@ func mapTrackToResponse(t *music.Track) TrackResponse {
 		ExternalURL: t.ExternalURL,
 		Explicit:    t.Explicit,
 		Popularity:  t.Popularity,
+		ISRC:        t.ISRC,
+		Sources:     sources,
+	}
+}
+
+type CategoryResponse struct {
+	ID      string                   `json:"id"`
+	Name    string                   `json:"name"`
+	IconURL string                   `json:"icon_url,omitempty"`
+	Sources []CategorySourceResponse `json:"sources"`
+}
+
+type CategorySourceResponse struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+}
+
+type PlaylistSummaryResponse struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	CoverURL    string `json:"cover_url"`
+	TrackCount  int    `json:"track_count"`
+	Provider    string `json:"provider"`
+	ExternalURL string `json:"external_url,omitempty"`
+	Creator     string `json:"creator,omitempty"`
+}
+
+func mapCategoryToResponse(c *music.BrowseCategory) CategoryResponse {
+	sources := make([]CategorySourceResponse, 0, len(c.Sources))
+	for _, source := range c.Sources {
+		sources = append(sources, CategorySourceResponse{
+			Provider: source.Provider,
+			ID:       source.ID,
+			Name:     source.Name,
+		})
+	}
+
+	return CategoryResponse{
+		ID:      c.ID,
+		Name:    c.Name,
+		IconURL: c.IconURL,
+		Sources: sources,
+	}
+}
+
+func mapPlaylistSummaryToResponse(p *music.PlaylistSummary) PlaylistSummaryResponse {
+	return PlaylistSummaryResponse{
+		ID:          p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		CoverURL:    p.CoverURL,
+		TrackCount:  p.TrackCount,
+		Provider:    p.Provider,
+		ExternalURL: p.ExternalURL,
+		Creator:     p.Creator,
 	}
 }
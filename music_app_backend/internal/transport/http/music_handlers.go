@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -22,12 +23,13 @@ func NewMusicHandlers(service *music.MusicService, logger logger.Logger) *MusicH
 
 // SearchTracks searches for tracks across music providers.
 // @Summary      Search for tracks
-// @Description  Searches for tracks by a query string, with optional filters.
+// @Description  Searches for tracks by a query string, with optional filters. Without a provider, results from all providers are merged, de-duplicated and paged with the returned next_cursor.
 // @Tags         Music
 // @Produce      json
 // @Param        q query string true "Search query"
 // @Param        provider query string false "Provider to search (e.g., itunes, spotify)"
-// @Param        page query int false "Page number" default(1)
+// @Param        cursor query string false "Cursor for the next page of a merged search"
+// @Param        page query int false "Page number, only with a provider" default(1)
 // @Param        size query int false "Page size" default(20)
 // @Param        genre query string false "Filter by genre"
 // @Param        year query int false "Filter by release year"
@@ -58,10 +60,22 @@ func (h *MusicHandlers) SearchTracks(c *gin.Context) {
 	if req.Provider != nil {
 		provider = *req.Provider
 	}
+	cursor := ""
+	if req.Cursor != nil {
+		cursor = *req.Cursor
+	}
 
-	tracks, pageInfo, err := h.service.SearchTracks(c.Request.Context(), provider, req.Query, req.Page, req.Size, filters)
+	tracks, pageInfo, err := h.service.SearchTracks(c.Request.Context(), provider, req.Query, cursor, req.Page, req.Size, filters)
 	if err != nil {
-		h.logger.Error("failed to search tracks", "error", err, "query", req.Query)
+		switch err {
+		case music.ErrInvalidCursor:
+			response.BadRequest(c, "INVALID_CURSOR", "Search cursor is invalid or belongs to a different search")
+			return
+		case music.ErrMergedPage:
+			response.BadRequest(c, "INVALID_PAGE", "Searches across all providers are paged with cursor; page is only supported with a provider")
+			return
+		}
+		h.logger.WithContext(c.Request.Context()).Error("failed to search tracks", "error", err, "query", req.Query)
 		response.InternalError(c, "SEARCH_FAILED", "Failed to search for tracks")
 		return
 	}
@@ -71,7 +85,7 @@ func (h *MusicHandlers) SearchTracks(c *gin.Context) {
 		trackResponses = append(trackResponses, mapTrackToResponse(&t))
 	}
 
-	response.Success(c, response.NewPaginatedData(trackResponses, pageInfo.Page, pageInfo.Size, pageInfo.Total))
+	response.Success(c, response.NewCursorPaginatedData(trackResponses, pageInfo.Page, pageInfo.Size, pageInfo.Total, pageInfo.NextCursor))
 }
 
 // GetTrack retrieves a single track by its ID from a specific provider.
@@ -99,7 +113,7 @@ func (h *MusicHandlers) GetTrack(c *gin.Context) {
 
 	track, err := h.service.GetTrack(c.Request.Context(), req.Provider, req.TrackID)
 	if err != nil {
-		h.logger.Error("failed to get track", "error", err, "track_id", req.TrackID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to get track", "error", err, "track_id", req.TrackID)
 		response.NotFound(c, "TRACK_NOT_FOUND", "Track not found")
 		return
 	}
@@ -134,7 +148,7 @@ func (h *MusicHandlers) GetTopCharts(c *gin.Context) {
 
 	tracks, pageInfo, err := h.service.GetTopCharts(c.Request.Context(), provider, req.Country, req.Page, req.Size)
 	if err != nil {
-		h.logger.Error("failed to get top charts", "error", err, "provider", provider)
+		h.logger.WithContext(c.Request.Context()).Error("failed to get top charts", "error", err, "provider", provider)
 		response.InternalError(c, "CHARTS_FETCH_FAILED", "Failed to fetch top charts")
 		return
 	}
@@ -145,4 +159,127 @@ func (h *MusicHandlers) GetTopCharts(c *gin.Context) {
 	}
 
 	response.Success(c, response.NewPaginatedData(trackResponses, pageInfo.Page, pageInfo.Size, pageInfo.Total))
-}
\ No newline at end of file
+}
+
+// GetCategories retrieves browse categories merged across music providers.
+// @Summary      Get browse categories
+// @Description  Retrieves the categories of every available provider, mapped onto a shared taxonomy so the same genre from different providers is one category. Each category lists the provider categories it was merged from.
+// @Tags         Music
+// @Produce      json
+// @Success      200 {object} response.APIResponse{data=[]CategoryResponse}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /music/categories [get]
+func (h *MusicHandlers) GetCategories(c *gin.Context) {
+	categories, err := h.service.GetBrowseCategories(c.Request.Context())
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to get categories", "error", err)
+		response.InternalError(c, "CATEGORIES_FETCH_FAILED", "Failed to fetch categories")
+		return
+	}
+
+	categoryResponses := make([]CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		categoryResponses = append(categoryResponses, mapCategoryToResponse(&category))
+	}
+
+	response.Success(c, categoryResponses)
+}
+
+// GetCategoryPlaylists retrieves the playlists of a browse category.
+// @Summary      Get category playlists
+// @Description  Retrieves a paginated list of playlists for a category from GET /music/categories, from the first of its providers that offers playlists.
+// @Tags         Music
+// @Produce      json
+// @Param        categoryId path string true "Category ID"
+// @Param        provider query string false "Provider to fetch playlists from (e.g., spotify)"
+// @Param        page query int false "Page number" default(1)
+// @Param        size query int false "Page size" default(20)
+// @Success      200 {object} response.APIResponse{data=response.PaginatedData{playlists=[]PlaylistSummaryResponse}}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /music/categories/{categoryId}/playlists [get]
+func (h *MusicHandlers) GetCategoryPlaylists(c *gin.Context) {
+	var req GetCategoryPlaylistsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	provider := ""
+	if req.Provider != nil {
+		provider = *req.Provider
+	}
+
+	playlists, pageInfo, err := h.service.GetBrowseCategoryPlaylists(c.Request.Context(), req.CategoryID, provider, req.Page, req.Size)
+	if err != nil {
+		if errors.Is(err, music.ErrCategoryNotFound) {
+			response.NotFound(c, "CATEGORY_NOT_FOUND", "Category not found")
+			return
+		}
+		h.logger.WithContext(c.Request.Context()).Error("failed to get category playlists", "error", err, "category_id", req.CategoryID)
+		response.InternalError(c, "PLAYLISTS_FETCH_FAILED", "Failed to fetch category playlists")
+		return
+	}
+
+	playlistResponses := make([]PlaylistSummaryResponse, 0, len(playlists))
+	for _, p := range playlists {
+		playlistResponses = append(playlistResponses, mapPlaylistSummaryToResponse(&p))
+	}
+
+	response.Success(c, response.NewPaginatedData(playlistResponses, pageInfo.Page, pageInfo.Size, pageInfo.Total))
+}
+
+// GetPlaylistTracks retrieves the tracks of a provider's playlist.
+// @Summary      Get external playlist tracks
+// @Description  Retrieves a paginated list of the tracks in a playlist hosted by a music provider, such as one returned by GET /music/categories/{categoryId}/playlists.
+// @Tags         Music
+// @Produce      json
+// @Param        provider path string true "Provider name (e.g., spotify)"
+// @Param        playlistId path string true "Provider's playlist ID"
+// @Param        page query int false "Page number" default(1)
+// @Param        size query int false "Page size" default(20)
+// @Success      200 {object} response.APIResponse{data=response.PaginatedData{tracks=[]TrackResponse}}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /music/playlists/{provider}/{playlistId} [get]
+func (h *MusicHandlers) GetPlaylistTracks(c *gin.Context) {
+	var req GetPlaylistTracksRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	tracks, pageInfo, err := h.service.GetPlaylistTracks(c.Request.Context(), req.Provider, req.PlaylistID, req.Page, req.Size)
+	if err != nil {
+		var providerErr *music.ProviderError
+		switch {
+		case errors.Is(err, music.ErrProviderNotFound):
+			response.BadRequest(c, "INVALID_PROVIDER", "Unknown or disabled music provider")
+		case errors.As(err, &providerErr) && providerErr.Code == "NOT_SUPPORTED":
+			response.BadRequest(c, "PLAYLISTS_NOT_SUPPORTED", "This provider does not support playlists")
+		case errors.As(err, &providerErr) && providerErr.Code == "NOT_FOUND":
+			response.NotFound(c, "PLAYLIST_NOT_FOUND", "Playlist not found")
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to get playlist tracks", "error", err, "provider", req.Provider, "playlist_id", req.PlaylistID)
+			response.InternalError(c, "PLAYLIST_TRACKS_FETCH_FAILED", "Failed to fetch playlist tracks")
+		}
+		return
+	}
+
+	trackResponses := make([]TrackResponse, 0, len(tracks))
+	for _, t := range tracks {
+		trackResponses = append(trackResponses, mapTrackToResponse(&t))
+	}
+
+	response.Success(c, response.NewPaginatedData(trackResponses, pageInfo.Page, pageInfo.Size, pageInfo.Total))
+}
@@ -38,8 +38,13 @@ type AddTrackToPlaylistRequest struct {
 }
 
 type ReorderPlaylistRequest struct {
-	TrackID  string `json:"track_id" binding:"required"`
-	Position int    `json:"position" binding:"required,gte=0"`
+	TrackIDs []string `json:"track_ids" binding:"min=1,dive,uuid"`
+	Version  int      `json:"version" binding:"required,gte=1"`
+}
+
+type MoveTrackRequest struct {
+	Position int `json:"position" binding:"required,gte=1"`
+	Version  int `json:"version" binding:"required,gte=1"`
 }
 
 // --- Playlist Responses ---
@@ -66,6 +71,7 @@ type PlaylistResponse struct {
 	CoverURL    *string                 `json:"cover_url,omitempty"`
 	IsPublic    bool                    `json:"is_public"`
 	ShareCode   *string                 `json:"share_code,omitempty"`
+	Version     int                     `json:"version"`
 	TrackCount  int                     `json:"track_count"`
 	Tracks      []PlaylistTrackResponse `json:"tracks"`
 	CreatedAt   time.Time               `json:"created_at"`
@@ -103,6 +109,7 @@ func mapPlaylistToResponse(p *playlist.Playlist) PlaylistResponse {
 		CoverURL:    p.CoverURL,
 		IsPublic:    p.IsPublic,
 		ShareCode:   p.ShareCode,
+		Version:     p.Version,
 		TrackCount:  len(tracks),
 		Tracks:      tracks,
 		CreatedAt:   p.CreatedAt,
@@ -2,6 +2,7 @@ package http
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/mosesmmoisebidth/music_backend/internal/playlist"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 	"github.com/mosesmmoisebidth/music_backend/pkg/response"
@@ -45,7 +46,7 @@ func (h *PlaylistHandlers) GetPlaylists(c *gin.Context) {
 
 	playlists, total, err := h.service.GetUserPlaylists(c.Request.Context(), userID.(string), req.Page, req.Size)
 	if err != nil {
-		h.logger.Error("failed to get playlists", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to get playlists", "error", err, "user_id", userID)
 		response.InternalError(c, "PLAYLISTS_FETCH_FAILED", "Failed to fetch playlists")
 		return
 	}
@@ -91,7 +92,7 @@ func (h *PlaylistHandlers) CreatePlaylist(c *gin.Context) {
 
 	newPlaylist, err := h.service.CreatePlaylist(c.Request.Context(), userID.(string), req.Title, desc)
 	if err != nil {
-		h.logger.Error("failed to create playlist", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to create playlist", "error", err, "user_id", userID)
 		response.InternalError(c, "PLAYLIST_CREATE_FAILED", "Failed to create playlist")
 		return
 	}
@@ -128,7 +129,7 @@ func (h *PlaylistHandlers) GetPlaylist(c *gin.Context) {
 		case playlist.ErrNotPlaylistOwner:
 			response.Forbidden(c, "FORBIDDEN", err.Error())
 		default:
-			h.logger.Error("failed to get playlist", "error", err, "playlist_id", playlistID)
+			h.logger.WithContext(c.Request.Context()).Error("failed to get playlist", "error", err, "playlist_id", playlistID)
 			response.InternalError(c, "PLAYLIST_FETCH_FAILED", "Failed to fetch playlist")
 		}
 		return
@@ -173,7 +174,7 @@ func (h *PlaylistHandlers) UpdatePlaylist(c *gin.Context) {
 		case playlist.ErrPlaylistNotFound, playlist.ErrNotPlaylistOwner:
 			response.Forbidden(c, "FORBIDDEN", "You do not have permission to update this playlist")
 		default:
-			h.logger.Error("failed to update playlist", "error", err, "playlist_id", playlistID)
+			h.logger.WithContext(c.Request.Context()).Error("failed to update playlist", "error", err, "playlist_id", playlistID)
 			response.InternalError(c, "PLAYLIST_UPDATE_FAILED", "Failed to update playlist")
 		}
 		return
@@ -208,7 +209,7 @@ func (h *PlaylistHandlers) DeletePlaylist(c *gin.Context) {
 		case playlist.ErrPlaylistNotFound, playlist.ErrNotPlaylistOwner:
 			response.Forbidden(c, "FORBIDDEN", "You do not have permission to delete this playlist")
 		default:
-			h.logger.Error("failed to delete playlist", "error", err, "playlist_id", playlistID)
+			h.logger.WithContext(c.Request.Context()).Error("failed to delete playlist", "error", err, "playlist_id", playlistID)
 			response.InternalError(c, "PLAYLIST_DELETE_FAILED", "Failed to delete playlist")
 		}
 		return
@@ -263,7 +264,7 @@ func (h *PlaylistHandlers) AddTrackToPlaylist(c *gin.Context) {
 		case playlist.ErrPlaylistNotFound, playlist.ErrNotPlaylistOwner:
 			response.Forbidden(c, "FORBIDDEN", "You do not have permission to modify this playlist")
 		default:
-			h.logger.Error("failed to add track to playlist", "error", err, "playlist_id", playlistID)
+			h.logger.WithContext(c.Request.Context()).Error("failed to add track to playlist", "error", err, "playlist_id", playlistID)
 			response.InternalError(c, "TRACK_ADD_FAILED", "Failed to add track to playlist")
 		}
 		return
@@ -304,11 +305,118 @@ func (h *PlaylistHandlers) RemoveTrackFromPlaylist(c *gin.Context) {
 		case playlist.ErrTrackNotFound:
 			response.NotFound(c, "TRACK_NOT_FOUND", err.Error())
 		default:
-			h.logger.Error("failed to remove track from playlist", "error", err, "playlist_id", playlistID)
+			h.logger.WithContext(c.Request.Context()).Error("failed to remove track from playlist", "error", err, "playlist_id", playlistID)
 			response.InternalError(c, "TRACK_REMOVE_FAILED", "Failed to remove track from playlist")
 		}
 		return
 	}
 
 	response.Success(c, mapPlaylistToResponse(updatedPlaylist))
-}
\ No newline at end of file
+}
+
+// ReorderPlaylistTracks replaces the order of a playlist's tracks.
+// @Summary      Reorder playlist tracks
+// @Description  Sets the order of every track in a playlist. The request must list each track exactly once and carry the playlist version it was based on.
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        playlistId path string true "Playlist ID"
+// @Param        request body ReorderPlaylistRequest true "New track order"
+// @Success      200 {object} response.APIResponse{data=PlaylistResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      409 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /playlists/{playlistId}/tracks/order [patch]
+func (h *PlaylistHandlers) ReorderPlaylistTracks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	playlistID := c.Param("playlistId")
+
+	var req ReorderPlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	updatedPlaylist, err := h.service.ReorderPlaylistTracks(c.Request.Context(), playlistID, userID.(string), req.TrackIDs, req.Version)
+	if err != nil {
+		switch err {
+		case playlist.ErrPlaylistNotFound, playlist.ErrNotPlaylistOwner:
+			response.Forbidden(c, "FORBIDDEN", "You do not have permission to modify this playlist")
+		case playlist.ErrInvalidTrackOrder:
+			response.BadRequest(c, "INVALID_TRACK_ORDER", err.Error())
+		case playlist.ErrVersionConflict:
+			response.Conflict(c, "PLAYLIST_VERSION_CONFLICT", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to reorder playlist tracks", "error", err, "playlist_id", playlistID)
+			response.InternalError(c, "TRACK_REORDER_FAILED", "Failed to reorder playlist tracks")
+		}
+		return
+	}
+
+	response.Success(c, mapPlaylistToResponse(updatedPlaylist))
+}
+
+// MoveTrack moves a single track to a new position in a playlist.
+// @Summary      Move a track within a playlist
+// @Description  Moves one track to a 1-based position, shifting the tracks in between. Positions past the end move the track to the end.
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        playlistId path string true "Playlist ID"
+// @Param        trackId path string true "Playlist Track ID"
+// @Param        request body MoveTrackRequest true "Target position"
+// @Success      200 {object} response.APIResponse{data=PlaylistResponse}
+// @Failure      400 {object} response.APIResponse{error=response.APIError}
+// @Failure      401 {object} response.APIResponse{error=response.APIError}
+// @Failure      403 {object} response.APIResponse{error=response.APIError}
+// @Failure      404 {object} response.APIResponse{error=response.APIError}
+// @Failure      409 {object} response.APIResponse{error=response.APIError}
+// @Failure      500 {object} response.APIResponse{error=response.APIError}
+// @Router       /playlists/{playlistId}/tracks/{trackId}/position [patch]
+func (h *PlaylistHandlers) MoveTrack(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	playlistID := c.Param("playlistId")
+	trackID := c.Param("trackId")
+	if _, err := uuid.Parse(trackID); err != nil {
+		response.BadRequest(c, "INVALID_TRACK_ID", "Invalid track ID format")
+		return
+	}
+
+	var req MoveTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	updatedPlaylist, err := h.service.MoveTrack(c.Request.Context(), playlistID, userID.(string), trackID, req.Position, req.Version)
+	if err != nil {
+		switch err {
+		case playlist.ErrPlaylistNotFound, playlist.ErrNotPlaylistOwner:
+			response.Forbidden(c, "FORBIDDEN", "You do not have permission to modify this playlist")
+		case playlist.ErrTrackNotFound:
+			response.NotFound(c, "TRACK_NOT_FOUND", err.Error())
+		case playlist.ErrVersionConflict:
+			response.Conflict(c, "PLAYLIST_VERSION_CONFLICT", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to move playlist track", "error", err, "playlist_id", playlistID, "track_id", trackID)
+			response.InternalError(c, "TRACK_MOVE_FAILED", "Failed to move track")
+		}
+		return
+	}
+
+	response.Success(c, mapPlaylistToResponse(updatedPlaylist))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
 	"github.com/mosesmmoisebidth/music_backend/internal/user"
 )
 
@@ -15,18 +16,24 @@ type UpdateUserRequest struct {
 	Preferences map[string]interface{} `json:"preferences,omitempty"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
 // --- User Responses ---
 
 type UserResponse struct {
-	ID          uuid.UUID              `json:"id"`
-	Email       *string                `json:"email,omitempty"`
-	DisplayName *string                `json:"display_name,omitempty"`
-	PhotoURL    *string                `json:"photo_url,omitempty"`
-	Roles       []string               `json:"roles"`
-	IsActive    bool                   `json:"is_active"`
-	Preferences map[string]interface{} `json:"preferences,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID            uuid.UUID              `json:"id"`
+	Email         *string                `json:"email,omitempty"`
+	EmailVerified bool                   `json:"email_verified"`
+	DisplayName   *string                `json:"display_name,omitempty"`
+	PhotoURL      *string                `json:"photo_url,omitempty"`
+	Roles         []string               `json:"roles"`
+	IsActive      bool                   `json:"is_active"`
+	Preferences   map[string]interface{} `json:"preferences,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
 }
 
 func mapUserToResponse(u *user.User) UserResponse {
@@ -36,14 +43,80 @@ func mapUserToResponse(u *user.User) UserResponse {
 	}
 
 	return UserResponse{
-		ID:          u.ID,
-		Email:       u.Email,
-		DisplayName: u.DisplayName,
-		PhotoURL:    u.PhotoURL,
-		Roles:       u.Roles,
-		IsActive:    u.IsActive,
-		Preferences: prefs,
-		CreatedAt:   u.CreatedAt,
-		UpdatedAt:   u.UpdatedAt,
+		ID:            u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		DisplayName:   u.DisplayName,
+		PhotoURL:      u.PhotoURL,
+		Roles:         u.Roles,
+		IsActive:      u.IsActive,
+		Preferences:   prefs,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+// --- Session Responses ---
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Browser    string    `json:"browser"`
+	OS         string    `json:"os"`
+	DeviceType string    `json:"device_type"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+func mapSessionToResponse(s *auth.Session) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		Browser:    s.Device.Browser,
+		OS:         s.Device.OS,
+		DeviceType: s.Device.Type,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.Current,
 	}
-}
\ No newline at end of file
+}
+
+// --- Login Method Responses ---
+
+type IdentityResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Provider   string     `json:"provider"`
+	Email      *string    `json:"email,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type LoginMethodsResponse struct {
+	HasPassword bool               `json:"has_password"`
+	Identities  []IdentityResponse `json:"identities"`
+}
+
+func mapIdentityToResponse(i *user.Identity) IdentityResponse {
+	return IdentityResponse{
+		ID:         i.ID,
+		Provider:   i.Provider,
+		Email:      i.Email,
+		LastUsedAt: i.LastUsedAt,
+		CreatedAt:  i.CreatedAt,
+	}
+}
+
+func mapLoginMethodsToResponse(m *user.LoginMethods) LoginMethodsResponse {
+	identities := make([]IdentityResponse, 0, len(m.Identities))
+	for i := range m.Identities {
+		identities = append(identities, mapIdentityToResponse(&m.Identities[i]))
+	}
+	return LoginMethodsResponse{HasPassword: m.HasPassword, Identities: identities}
+}
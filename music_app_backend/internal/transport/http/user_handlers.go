@@ -2,7 +2,12 @@
 package http
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
 	"github.com/mosesmmoisebidth/music_backend/internal/user"
 	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
 	"github.com/mosesmmoisebidth/music_backend/pkg/response"
@@ -10,13 +15,15 @@ import (
 
 // UserHandlers contains user HTTP handlers
 type UserHandlers struct {
-	service *user.Service
-	logger  logger.Logger
+	service            *user.Service
+	authService        *auth.AuthService
+	credentialsService *user.CredentialsService
+	logger             logger.Logger
 }
 
 // NewUserHandlers creates new user handlers
-func NewUserHandlers(service *user.Service, logger logger.Logger) *UserHandlers {
-	return &UserHandlers{service: service, logger: logger}
+func NewUserHandlers(service *user.Service, authService *auth.AuthService, credentialsService *user.CredentialsService, logger logger.Logger) *UserHandlers {
+	return &UserHandlers{service: service, authService: authService, credentialsService: credentialsService, logger: logger}
 }
 
 // GetCurrentUser retrieves the profile of the currently authenticated user.
@@ -38,7 +45,7 @@ func (h *UserHandlers) GetCurrentUser(c *gin.Context) {
 
 	userData, err := h.service.GetUserByID(c.Request.Context(), userID.(string))
 	if err != nil {
-		h.logger.Error("failed to get current user", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to get current user", "error", err, "user_id", userID)
 		response.InternalError(c, "USER_FETCH_FAILED", "Failed to fetch user information")
 		return
 	}
@@ -74,10 +81,337 @@ func (h *UserHandlers) UpdateCurrentUser(c *gin.Context) {
 
 	updatedUser, err := h.service.UpdateUser(c.Request.Context(), userID.(string), req.DisplayName, req.PhotoURL, req.Preferences)
 	if err != nil {
-		h.logger.Error("failed to update current user", "error", err, "user_id", userID)
+		h.logger.WithContext(c.Request.Context()).Error("failed to update current user", "error", err, "user_id", userID)
 		response.InternalError(c, "USER_UPDATE_FAILED", "Failed to update user information")
 		return
 	}
 
 	response.Success(c, mapUserToResponse(updatedUser))
 }
+
+// ChangePassword changes the current user's password.
+// @Summary      Change password
+// @Description  Replaces the current user's password after checking the current one. Every session is signed out, and a new token pair is returned so this device stays signed in.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request body ChangePasswordRequest true "Current and new password"
+// @Success      200  {object}  response.APIResponse{data=TokenPair}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      403  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/password [put]
+func (h *UserHandlers) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	updatedUser, err := h.credentialsService.ChangePassword(c.Request.Context(), userID.(string), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		switch err {
+		case user.ErrAuthenticationFailed:
+			response.Forbidden(c, "INVALID_CURRENT_PASSWORD", "Current password is incorrect")
+		case user.ErrPasswordNotSet:
+			response.BadRequest(c, "PASSWORD_NOT_SET", "This account has no password; use a password reset to set one")
+		case user.ErrInvalidUserID:
+			response.BadRequest(c, "INVALID_USER_ID", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to change password", "error", err, "user_id", userID)
+			response.InternalError(c, "PASSWORD_CHANGE_FAILED", "Failed to change password")
+		}
+		return
+	}
+
+	email := ""
+	if updatedUser.Email != nil {
+		email = *updatedUser.Email
+	}
+
+	tokens, err := h.authService.GenerateTokens(c.Request.Context(), updatedUser.ID, email, updatedUser.Roles, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to generate tokens after password change", "error", err, "user_id", userID)
+		response.InternalError(c, "TOKEN_GENERATION_FAILED", "Password changed, but signing in again failed")
+		return
+	}
+
+	response.Success(c, tokens)
+}
+
+// SendEmailVerification resends the verification email to the current user.
+// @Summary      Resend verification email
+// @Description  Sends a new email verification link to the current user's address. Earlier links stop working.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/email/verification [post]
+func (h *UserHandlers) SendEmailVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	if err := h.credentialsService.SendEmailVerification(c.Request.Context(), userID.(string)); err != nil {
+		switch err {
+		case user.ErrEmailAlreadyVerified:
+			response.Conflict(c, "EMAIL_ALREADY_VERIFIED", err.Error())
+			return
+		case user.ErrInvalidUserID:
+			response.BadRequest(c, "INVALID_USER_ID", err.Error())
+			return
+		}
+		h.logger.WithContext(c.Request.Context()).Error("failed to send verification email", "error", err, "user_id", userID)
+		response.InternalError(c, "EMAIL_VERIFICATION_FAILED", "Failed to send verification email")
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "Verification email sent"})
+}
+
+// GetSessions lists the current user's active sessions.
+// @Summary      List sessions
+// @Description  Lists the devices the current user is signed in on, with the session making the request marked as current.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=[]SessionResponse}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/sessions [get]
+func (h *UserHandlers) GetSessions(c *gin.Context) {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to list sessions", "error", err, "user_id", claims.UserID)
+		response.InternalError(c, "SESSIONS_FETCH_FAILED", "Failed to fetch sessions")
+		return
+	}
+
+	sessionResponses := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		sessionResponses = append(sessionResponses, mapSessionToResponse(&s))
+	}
+
+	response.Success(c, sessionResponses)
+}
+
+// RevokeSession signs the current user out of one session.
+// @Summary      Revoke a session
+// @Description  Signs the current user out of a session. Revoking the current session signs out this device.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Param        sessionId path string true "Session ID"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/sessions/{sessionId} [delete]
+func (h *UserHandlers) RevokeSession(c *gin.Context) {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		response.BadRequest(c, "INVALID_SESSION_ID", "Invalid session ID format")
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), claims.UserID, sessionID); err != nil {
+		if err == auth.ErrSessionNotFound {
+			response.NotFound(c, "SESSION_NOT_FOUND", err.Error())
+			return
+		}
+		h.logger.WithContext(c.Request.Context()).Error("failed to revoke session", "error", err, "user_id", claims.UserID, "session_id", sessionID)
+		response.InternalError(c, "SESSION_REVOKE_FAILED", "Failed to revoke session")
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "Session revoked successfully"})
+}
+
+// RevokeOtherSessions signs the current user out everywhere except this session.
+// @Summary      Sign out other sessions
+// @Description  Signs the current user out of every session except the one making the request.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=RevokeSessionsResponse}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/sessions/others [delete]
+func (h *UserHandlers) RevokeOtherSessions(c *gin.Context) {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return
+	}
+
+	if claims.SessionID == uuid.Nil {
+		response.BadRequest(c, "SESSION_UNKNOWN", "This token predates session tracking; sign in again to manage sessions")
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to revoke other sessions", "error", err, "user_id", claims.UserID)
+		response.InternalError(c, "SESSION_REVOKE_FAILED", "Failed to revoke sessions")
+		return
+	}
+
+	response.Success(c, RevokeSessionsResponse{Revoked: revoked})
+}
+
+// GetLoginMethods lists the ways the current user can sign in.
+// @Summary      List login methods
+// @Description  Reports whether the current user has a password and lists the external logins, such as Google, linked to their account.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  response.APIResponse{data=LoginMethodsResponse}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/identities [get]
+func (h *UserHandlers) GetLoginMethods(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	methods, err := h.service.GetLoginMethods(c.Request.Context(), userID.(string))
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("failed to get login methods", "error", err, "user_id", userID)
+		response.InternalError(c, "IDENTITIES_FETCH_FAILED", "Failed to fetch login methods")
+		return
+	}
+
+	response.Success(c, mapLoginMethodsToResponse(methods))
+}
+
+// LinkIdentity links an account at a login provider to the current user.
+// @Summary      Link a login
+// @Description  Verifies an ID token from a configured login provider, such as google or apple, and links that account to the current user, so either can be used to sign in. Linking an account that is already linked to this user does nothing.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        provider path string true "Provider name"
+// @Param        request body OIDCSignInRequest true "ID token from the provider"
+// @Success      201  {object}  response.APIResponse{data=IdentityResponse}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/identities/{provider} [post]
+func (h *UserHandlers) LinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	var req OIDCSignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	external, ok := verifyProviderToken(c, ctx, h.authService, h.logger, c.Param("provider"), req)
+	if !ok {
+		return
+	}
+
+	identity, err := h.service.LinkIdentity(ctx, userID.(string), *external)
+	if err != nil {
+		if err == user.ErrIdentityInUse {
+			response.Conflict(c, "IDENTITY_IN_USE", err.Error())
+			return
+		}
+		h.logger.WithContext(ctx).Error("failed to link identity", "error", err, "user_id", userID, "provider", external.Provider)
+		response.InternalError(c, "IDENTITY_LINK_FAILED", "Failed to link login")
+		return
+	}
+
+	response.Created(c, mapIdentityToResponse(identity))
+}
+
+// UnlinkIdentity removes an external login from the current user.
+// @Summary      Unlink a login
+// @Description  Removes an external login, such as Google, from the current user. The last way to sign in cannot be removed; set a password or link another login first.
+// @Tags         Users
+// @Produce      json
+// @Security     Bearer
+// @Param        identityId path string true "Identity ID"
+// @Success      200  {object}  response.APIResponse{data=response.SuccessMessage}
+// @Failure      400  {object}  response.APIResponse{error=response.APIError}
+// @Failure      401  {object}  response.APIResponse{error=response.APIError}
+// @Failure      404  {object}  response.APIResponse{error=response.APIError}
+// @Failure      409  {object}  response.APIResponse{error=response.APIError}
+// @Failure      500  {object}  response.APIResponse{error=response.APIError}
+// @Router       /users/me/identities/{identityId} [delete]
+func (h *UserHandlers) UnlinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return
+	}
+
+	identityID, err := uuid.Parse(c.Param("identityId"))
+	if err != nil {
+		response.BadRequest(c, "INVALID_IDENTITY_ID", "Invalid identity ID format")
+		return
+	}
+
+	if err := h.service.UnlinkIdentity(c.Request.Context(), userID.(string), identityID); err != nil {
+		switch err {
+		case user.ErrIdentityNotFound:
+			response.NotFound(c, "IDENTITY_NOT_FOUND", err.Error())
+		case user.ErrLastLoginMethod:
+			response.Conflict(c, "LAST_LOGIN_METHOD", err.Error())
+		default:
+			h.logger.WithContext(c.Request.Context()).Error("failed to unlink identity", "error", err, "user_id", userID, "identity_id", identityID)
+			response.InternalError(c, "IDENTITY_UNLINK_FAILED", "Failed to unlink login")
+		}
+		return
+	}
+
+	response.Success(c, &response.SuccessMessage{Message: "Login unlinked successfully"})
+}
+
+// sessionClaims returns the authenticated request's token claims, responding with 401 if there are none.
+func sessionClaims(c *gin.Context) (*auth.Claims, bool) {
+	value, exists := c.Get("claims")
+	claims, ok := value.(*auth.Claims)
+	if !exists || !ok {
+		response.Unauthorized(c, "USER_NOT_FOUND", "User not authenticated")
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,362 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/auth"
+	"github.com/mosesmmoisebidth/music_backend/internal/mail"
+	"github.com/mosesmmoisebidth/music_backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPasswordNotSet       = errors.New("account has no password")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+)
+
+// mailTimeout bounds the delivery of a single email, which happens after the request returns.
+const mailTimeout = 30 * time.Second
+
+// TokenRevoker signs a user out of every session. *auth.AuthService satisfies it.
+type TokenRevoker interface {
+	RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+// CredentialsConfig configures the links sent by email and how long they stay valid.
+type CredentialsConfig struct {
+	PasswordResetURL     string // the client page that completes a reset; the token is added as ?token=
+	EmailVerificationURL string // the client page that completes verification; the token is added as ?token=
+	AccountUnlockURL     string // the client page that unlocks a locked account; the token is added as ?token=
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	AccountUnlockTTL     time.Duration
+}
+
+// CredentialsService manages passwords, email verification and unlocking accounts locked
+// after repeated failed sign-ins.
+type CredentialsService struct {
+	repo           Repository
+	passwordHasher *auth.PasswordHasher
+	tokens         *auth.OneTimeTokenService
+	revoker        TokenRevoker
+	throttle       *auth.Throttle
+	mailer         mail.Mailer
+	config         CredentialsConfig
+	logger         logger.Logger
+}
+
+// NewCredentialsService creates a new credentials service.
+func NewCredentialsService(
+	repo Repository,
+	passwordHasher *auth.PasswordHasher,
+	tokens *auth.OneTimeTokenService,
+	revoker TokenRevoker,
+	throttle *auth.Throttle,
+	mailer mail.Mailer,
+	config CredentialsConfig,
+	logger logger.Logger,
+) *CredentialsService {
+	return &CredentialsService{
+		repo:           repo,
+		passwordHasher: passwordHasher,
+		tokens:         tokens,
+		revoker:        revoker,
+		throttle:       throttle,
+		mailer:         mailer,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// RequestPasswordReset emails a password reset link to the account with the given email.
+// It succeeds whether or not such an account exists, so callers cannot probe for accounts.
+func (s *CredentialsService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		s.logger.WithContext(ctx).Info("Password reset requested for disabled account", "userID", user.ID)
+		return nil
+	}
+
+	token, err := s.tokens.Issue(ctx, user.ID, auth.PurposePasswordReset, s.config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(user.ID, mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for your account.\n\n"+
+				"To choose a new password, open this link within %s:\n\n%s\n\n"+
+				"If this wasn't you, ignore this email and your password will stay the same.\n",
+			formatTTL(s.config.PasswordResetTTL), linkWithToken(s.config.PasswordResetURL, token),
+		),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password using a token from a reset email and signs the user
+// out everywhere. Receiving the email also proves the user owns the address, so it lifts any
+// sign-in lockout too. client identifies the caller for the audit log.
+func (s *CredentialsService) ResetPassword(ctx context.Context, token, newPassword string, client auth.Attempt) error {
+	userID, err := s.tokens.Consume(ctx, token, auth.PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return auth.ErrOneTimeTokenInvalid
+		}
+		return err
+	}
+	if !user.IsActive {
+		return ErrAccountDisabled
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	if user.Email != nil {
+		client.Email = *user.Email
+		if err := s.throttle.Unlock(ctx, user.ID, client); err != nil {
+			s.logger.WithContext(ctx).Error("failed to unlock account after password reset", "error", err, "userID", user.ID)
+		}
+	}
+
+	s.logger.WithContext(ctx).Info("Password reset", "userID", user.ID)
+	return nil
+}
+
+// ChangePassword replaces the user's password after checking the current one, and signs the
+// user out everywhere. Accounts without a password must use a password reset to set one.
+func (s *CredentialsService) ChangePassword(ctx context.Context, userIDStr, currentPassword, newPassword string) (*User, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, ErrInvalidUserID
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if user.Password == nil {
+		return nil, ErrPasswordNotSet
+	}
+	match, err := s.passwordHasher.Matches(currentPassword, *user.Password)
+	if err != nil || !match {
+		return nil, ErrAuthenticationFailed
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Password changed", "userID", user.ID)
+	return user, nil
+}
+
+// SendEmailVerification emails the user a link that verifies their address.
+func (s *CredentialsService) SendEmailVerification(ctx context.Context, userIDStr string) error {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	if user.Email == nil {
+		return errors.New("user has no email address")
+	}
+
+	token, err := s.tokens.Issue(ctx, user.ID, auth.PurposeEmailVerification, s.config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(user.ID, mail.Message{
+		To:      *user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Confirm that this is your email address by opening this link within %s:\n\n%s\n\n"+
+				"If you didn't create an account, you can ignore this email.\n",
+			formatTTL(s.config.EmailVerificationTTL), linkWithToken(s.config.EmailVerificationURL, token),
+		),
+	})
+	return nil
+}
+
+// VerifyEmail marks the user's email as verified using a token from a verification email.
+func (s *CredentialsService) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	userID, err := s.tokens.Consume(ctx, token, auth.PurposeEmailVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrOneTimeTokenInvalid
+		}
+		return nil, err
+	}
+	if user.EmailVerifiedAt != nil {
+		return user, nil
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"email_verified_at": now}); err != nil {
+		s.logger.WithContext(ctx).Error("failed to mark email verified", "error", err, "userID", user.ID)
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+
+	s.logger.WithContext(ctx).Info("Email verified", "userID", user.ID)
+	return user, nil
+}
+
+// SendUnlockEmail emails a link that unlocks the account with the given email, which has just
+// been locked after repeated failed sign-ins. Nothing is sent if no such account exists.
+func (s *CredentialsService) SendUnlockEmail(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	token, err := s.tokens.Issue(ctx, user.ID, auth.PurposeAccountUnlock, s.config.AccountUnlockTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(user.ID, mail.Message{
+		To:      email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf(
+			"We locked your account after several failed attempts to sign in. It unlocks by itself "+
+				"within %s, or you can unlock it now with this link:\n\n%s\n\n"+
+				"If those attempts weren't yours, someone may know or be guessing your password. "+
+				"Consider resetting it.\n",
+			formatTTL(s.config.AccountUnlockTTL), linkWithToken(s.config.AccountUnlockURL, token),
+		),
+	})
+	return nil
+}
+
+// UnlockAccount lifts a sign-in lockout using a token from an unlock email. client identifies
+// the caller for the audit log.
+func (s *CredentialsService) UnlockAccount(ctx context.Context, token string, client auth.Attempt) error {
+	userID, err := s.tokens.Consume(ctx, token, auth.PurposeAccountUnlock)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return auth.ErrOneTimeTokenInvalid
+		}
+		return err
+	}
+	if user.Email == nil {
+		return nil
+	}
+
+	client.Email = *user.Email
+	return s.throttle.Unlock(ctx, user.ID, client)
+}
+
+// setPassword stores a new password for user and revokes all of their tokens, so sessions
+// opened with the old password end.
+func (s *CredentialsService) setPassword(ctx context.Context, user *User, newPassword string) error {
+	hashedPassword, err := s.passwordHasher.Hash(newPassword)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("failed to hash password", "error", err)
+		return err
+	}
+
+	if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"password": hashedPassword}); err != nil {
+		s.logger.WithContext(ctx).Error("failed to update password", "error", err, "userID", user.ID)
+		return err
+	}
+	user.Password = &hashedPassword
+
+	return s.revoker.RevokeAllUserTokens(ctx, user.ID)
+}
+
+// send delivers msg in the background. Mail servers can be slow, and waiting for them would
+// also let callers tell from response times whether an account exists.
+func (s *CredentialsService) send(userID uuid.UUID, msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			s.logger.WithContext(ctx).Error("failed to send email", "error", err, "userID", userID, "subject", msg.Subject)
+		}
+	}()
+}
+
+// linkWithToken adds token to base as the token query parameter.
+func linkWithToken(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// formatTTL describes a link lifetime in words, e.g. "1 hour" or "2 days".
+func formatTTL(ttl time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case ttl >= 48*time.Hour && ttl%(24*time.Hour) == 0:
+		return plural(int(ttl/(24*time.Hour)), "day")
+	case ttl >= time.Hour && ttl%time.Hour == 0:
+		return plural(int(ttl/time.Hour), "hour")
+	default:
+		return plural(int(ttl.Round(time.Minute)/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,51 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrDeletionPending is returned when an account is already scheduled for deletion
+var ErrDeletionPending = errors.New("account is already scheduled for deletion")
+
+// ScheduleDeletion marks the user's account for deletion. Accounts with a password must
+// confirm it. The account stays restorable until the grace period ends, when its data is
+// purged; signing in before then cancels the deletion.
+func (s *Service) ScheduleDeletion(ctx context.Context, userIDStr, password string) (*User, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, ErrDeletionPending
+	}
+
+	if user.Password != nil {
+		match, err := s.passwordHasher.Matches(password, *user.Password)
+		if err != nil || !match {
+			return nil, ErrAuthenticationFailed
+		}
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"deleted_at": now}); err != nil {
+		s.logger.WithContext(ctx).Error("failed to schedule account deletion", "error", err, "userID", user.ID)
+		return nil, err
+	}
+	user.DeletedAt = &now
+
+	s.logger.WithContext(ctx).Info("Account scheduled for deletion", "userID", user.ID)
+	return user, nil
+}
@@ -0,0 +1,238 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("linked login not found")
+	ErrIdentityInUse    = errors.New("this login is already linked to another account")
+	ErrLastLoginMethod  = errors.New("cannot remove the only way to sign in to this account")
+	// ErrAccountLinkRequired is returned when a provider sign-in matches an existing account by
+	// email but either side has not verified the address. Linking automatically would let
+	// whoever registered the address first keep access, so the owner must sign in and link the
+	// provider themselves.
+	ErrAccountLinkRequired = errors.New("an account with this email already exists; sign in and link this login from your account")
+)
+
+// ExternalIdentity is an account at a login provider, as asserted by a verified ID token
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	DisplayName   string
+	PhotoURL      string
+}
+
+// LoginMethods lists the ways a user can sign in
+type LoginMethods struct {
+	HasPassword bool
+	Identities  []Identity
+}
+
+// SignInWithIdentity signs in the user linked to an external identity. If none is linked yet,
+// the identity is linked to the account with the same verified email, or a new account is
+// created for it.
+func (s *Service) SignInWithIdentity(ctx context.Context, external ExternalIdentity) (*User, error) {
+	identity, err := s.repo.GetIdentity(ctx, external.Provider, external.Subject)
+	if err == nil {
+		return s.signInLinked(ctx, identity, external)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.WithContext(ctx).Error("failed to get identity", "error", err, "provider", external.Provider)
+		return nil, err
+	}
+
+	user, err := s.linkOrCreate(ctx, external)
+	if errors.Is(err, ErrIdentityInUse) {
+		// A concurrent sign-in with the same identity linked it first, so sign in through it
+		identity, err := s.repo.GetIdentity(ctx, external.Provider, external.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return s.signInLinked(ctx, identity, external)
+	}
+	return user, err
+}
+
+// linkOrCreate links a new identity to the account with the same verified email, or creates
+// an account for it
+func (s *Service) linkOrCreate(ctx context.Context, external ExternalIdentity) (*User, error) {
+	if external.Email != "" {
+		existing, err := s.repo.GetUserByEmail(ctx, external.Email)
+		if err == nil {
+			return s.linkOnSignIn(ctx, existing, external)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	return s.createFromIdentity(ctx, external)
+}
+
+// signInLinked signs in the user an identity is already linked to
+func (s *Service) signInLinked(ctx context.Context, identity *Identity, external ExternalIdentity) (*User, error) {
+	user, err := s.repo.GetUserByID(ctx, identity.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+
+	now := time.Now()
+	identity.LastUsedAt = &now
+	if external.Email != "" {
+		identity.Email = &external.Email
+	}
+	if err := s.repo.UpdateIdentity(ctx, identity); err != nil {
+		s.logger.WithContext(ctx).Error("failed to update identity", "error", err, "userID", user.ID)
+	}
+
+	return user, nil
+}
+
+// linkOnSignIn links an identity to the existing account with the same email during sign-in.
+// Both sides must have verified the address, and providers only vouch for it when they are
+// trusted to verify emails.
+func (s *Service) linkOnSignIn(ctx context.Context, user *User, external ExternalIdentity) (*User, error) {
+	if !external.EmailVerified || user.EmailVerifiedAt == nil {
+		return nil, ErrAccountLinkRequired
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+
+	now := time.Now()
+	identity := newIdentity(user.ID, external)
+	identity.LastUsedAt = &now
+	if err := s.repo.CreateIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Identity linked on sign-in", "userID", user.ID, "provider", external.Provider)
+	return user, nil
+}
+
+// createFromIdentity creates an account for someone signing up through a login provider
+func (s *Service) createFromIdentity(ctx context.Context, external ExternalIdentity) (*User, error) {
+	now := time.Now()
+	newUser := &User{
+		ID:          uuid.New(),
+		Roles:       pq.StringArray{RoleUser},
+		IsActive:    true,
+		LastLoginAt: &now,
+	}
+	if external.Email != "" {
+		newUser.Email = &external.Email
+		if external.EmailVerified {
+			newUser.EmailVerifiedAt = &now
+		}
+	}
+	if external.DisplayName != "" {
+		newUser.DisplayName = &external.DisplayName
+	}
+	if external.PhotoURL != "" {
+		newUser.PhotoURL = &external.PhotoURL
+	}
+
+	identity := newIdentity(newUser.ID, external)
+	identity.LastUsedAt = &now
+	if err := s.repo.CreateUserWithIdentity(ctx, newUser, identity); err != nil {
+		if errors.Is(err, ErrIdentityInUse) {
+			return nil, err
+		}
+		s.logger.WithContext(ctx).Error("failed to create user from identity", "error", err, "provider", external.Provider)
+		return nil, err
+	}
+
+	return newUser, nil
+}
+
+// LinkIdentity adds an external login to a signed-in user's account. Linking an identity the
+// user already has is a no-op.
+func (s *Service) LinkIdentity(ctx context.Context, userIDStr string, external ExternalIdentity) (*Identity, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	existing, err := s.repo.GetIdentity(ctx, external.Provider, external.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityInUse
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	identity := newIdentity(userID, external)
+	if err := s.repo.CreateIdentity(ctx, identity); err != nil {
+		if !errors.Is(err, ErrIdentityInUse) {
+			s.logger.WithContext(ctx).Error("failed to link identity", "error", err, "userID", userID)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Identity linked", "userID", userID, "provider", external.Provider)
+	return identity, nil
+}
+
+// GetLoginMethods lists the ways a user can sign in.
+func (s *Service) GetLoginMethods(ctx context.Context, userIDStr string) (*LoginMethods, error) {
+	user, err := s.GetUserByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.repo.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginMethods{HasPassword: user.Password != nil, Identities: identities}, nil
+}
+
+// UnlinkIdentity removes an external login from a user's account, unless it is the only way
+// left to sign in.
+func (s *Service) UnlinkIdentity(ctx context.Context, userIDStr string, identityID uuid.UUID) error {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	if err := s.repo.DeleteIdentity(ctx, userID, identityID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("Identity unlinked", "userID", userID, "identityID", identityID)
+	return nil
+}
+
+func newIdentity(userID uuid.UUID, external ExternalIdentity) *Identity {
+	identity := &Identity{
+		ID:       uuid.New(),
+		UserID:   userID,
+		Provider: external.Provider,
+		Subject:  external.Subject,
+	}
+	if external.Email != "" {
+		identity.Email = &external.Email
+	}
+	return identity
+}
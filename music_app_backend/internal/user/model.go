@@ -17,10 +17,46 @@ type User struct {
 	PhotoURL       *string                `gorm:"size:1024" json:"photo_url"`
 	Roles          pq.StringArray  `gorm:"type:text[]" json:"roles"`
 	IsActive       bool                   `gorm:"default:true" json:"is_active"`
-	GoogleID       *string                `gorm:"uniqueIndex;size:255" json:"google_id,omitempty"`
+	EmailVerifiedAt *time.Time            `json:"email_verified_at,omitempty"`
 	LastLoginAt    *time.Time             `json:"last_login_at,omitempty"`
 	Preferences    datatypes.JSON         `gorm:"type:jsonb" json:"preferences,omitempty"`
 	FavoriteGenres pq.StringArray  `gorm:"type:text[]" json:"favorite_genres,omitempty"`
+	DeletedAt      *time.Time             `json:"deleted_at,omitempty"` // when the user asked to delete the account; cleared if they sign in again
 	CreatedAt      time.Time              `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt      time.Time              `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
+
+// Roles that can be granted to users.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// UserFilter narrows user listings. Zero values match every user.
+type UserFilter struct {
+	Query    string // matched against email and display name
+	Role     string
+	IsActive *bool
+}
+
+// Login providers a user can sign in with besides their password.
+const (
+	ProviderGoogle = "google"
+)
+
+// Identity links a user to an account at an external login provider, such as Google. A user
+// can have several, and signs in with any of them or with their password.
+type Identity struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Provider   string     `gorm:"not null;size:32;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject    string     `gorm:"not null;size:255;uniqueIndex:idx_user_identities_provider_subject" json:"subject"` // the provider's stable ID for the account
+	Email      *string    `gorm:"size:255" json:"email,omitempty"`                                                    // the email the provider reported when last used
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for the Identity model
+func (Identity) TableName() string {
+	return "user_identities"
+}
@@ -2,9 +2,12 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/mosesmmoisebidth/music_backend/internal/storage"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // repository implements the Repository interface for user data.
@@ -36,14 +39,141 @@ func (r *repository) GetUserByID(ctx context.Context, id uuid.UUID) (*User, erro
 	return &user, err
 }
 
-// GetUserByGoogleID retrieves a user by their Google ID.
-func (r *repository) GetUserByGoogleID(ctx context.Context, googleID string) (*User, error) {
-	var user User
-	err := r.db.WithContext(ctx).Where("google_id = ?", googleID).First(&user).Error
-	return &user, err
+// GetIdentity retrieves the identity a provider knows by subject.
+func (r *repository) GetIdentity(ctx context.Context, provider, subject string) (*Identity, error) {
+	var identity Identity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+// ListIdentities retrieves a user's identities, oldest first.
+func (r *repository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]Identity, error) {
+	var identities []Identity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+// CreateIdentity links an identity to its user. It returns ErrIdentityInUse if the provider
+// account is already linked.
+func (r *repository) CreateIdentity(ctx context.Context, identity *Identity) error {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(identity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityInUse
+	}
+	return nil
 }
 
-// UpdateUser updates an existing user record.
-func (r *repository) UpdateUser(ctx context.Context, user *User) error {
-	return r.db.WithContext(ctx).Save(user).Error
-}
\ No newline at end of file
+// CreateUserWithIdentity creates a user signing up through a login provider, along with the
+// identity they signed up with.
+func (r *repository) CreateUserWithIdentity(ctx context.Context, user *User, identity *Identity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(identity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrIdentityInUse
+		}
+		return nil
+	})
+}
+
+// UpdateIdentity updates an existing identity record.
+func (r *repository) UpdateIdentity(ctx context.Context, identity *Identity) error {
+	return r.db.WithContext(ctx).Save(identity).Error
+}
+
+// DeleteIdentity unlinks one of a user's identities. It returns ErrLastLoginMethod rather than
+// leave a user without a password or any identity, and gorm.ErrRecordNotFound if the user has
+// no such identity.
+func (r *repository) DeleteIdentity(ctx context.Context, userID, identityID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the user so concurrent unlinks cannot remove the last two logins together
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		var identities []Identity
+		if err := tx.Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+			return err
+		}
+		found := false
+		for _, identity := range identities {
+			found = found || identity.ID == identityID
+		}
+		if !found {
+			return gorm.ErrRecordNotFound
+		}
+		if user.Password == nil && len(identities) == 1 {
+			return ErrLastLoginMethod
+		}
+
+		return tx.Where("id = ? AND user_id = ?", identityID, userID).Delete(&Identity{}).Error
+	})
+}
+
+// UpdateUser updates only the given columns of a user, so concurrent changes to other columns
+// are kept. It returns gorm.ErrRecordNotFound if the user no longer exists.
+func (r *repository) UpdateUser(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RestoreUser cancels a user's pending deletion and records a sign-in. It returns
+// gorm.ErrRecordNotFound if the deletion is no longer pending, for example because the
+// account was purged in the meantime.
+func (r *repository) RestoreUser(ctx context.Context, userID uuid.UUID, lastLoginAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{"deleted_at": nil, "last_login_at": lastLoginAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListUsers retrieves a paginated list of users matching filter, newest first.
+func (r *repository) ListUsers(ctx context.Context, filter UserFilter, page, size int) ([]User, int64, error) {
+	var users []User
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&User{})
+	if filter.Query != "" {
+		pattern := "%" + storage.EscapeLike(filter.Query) + "%"
+		db = db.Where(`email ILIKE ? ESCAPE '\' OR display_name ILIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	if filter.Role != "" {
+		db = db.Where("? = ANY(roles)", filter.Role)
+	}
+	if filter.IsActive != nil {
+		db = db.Where("is_active = ?", *filter.IsActive)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Order("created_at DESC").Limit(size).Offset(offset).Find(&users).Error
+
+	return users, total, err
+}
@@ -14,8 +14,12 @@ import (
 
 var (
 	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidUserID        = errors.New("invalid user ID format")
 	ErrEmailExists          = errors.New("user with this email already exists")
 	ErrAuthenticationFailed = errors.New("authentication failed")
+	ErrAccountDisabled      = errors.New("account is disabled")
+	ErrInvalidRole          = errors.New("unknown role")
+	ErrCannotModifySelf     = errors.New("administrators cannot change their own status or admin role")
 )
 
 // Repository defines the interface for user data storage.
@@ -23,8 +27,15 @@ type Repository interface {
 	CreateUser(ctx context.Context, user *User) error
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*User, error)
-	GetUserByGoogleID(ctx context.Context, googleID string) (*User, error)
-	UpdateUser(ctx context.Context, user *User) error
+	UpdateUser(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) error
+	RestoreUser(ctx context.Context, userID uuid.UUID, lastLoginAt time.Time) error
+	ListUsers(ctx context.Context, filter UserFilter, page, size int) ([]User, int64, error)
+	GetIdentity(ctx context.Context, provider, subject string) (*Identity, error)
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]Identity, error)
+	CreateIdentity(ctx context.Context, identity *Identity) error
+	CreateUserWithIdentity(ctx context.Context, user *User, identity *Identity) error
+	UpdateIdentity(ctx context.Context, identity *Identity) error
+	DeleteIdentity(ctx context.Context, userID, identityID uuid.UUID) error
 }
 
 // Service provides user business logic.
@@ -51,7 +62,7 @@ func (s *Service) CreateUser(ctx context.Context, email, password, displayName s
 
 	hashedPassword, err := s.passwordHasher.Hash(password)
 	if err != nil {
-		s.logger.Error("failed to hash password", "error", err)
+		s.logger.WithContext(ctx).Error("failed to hash password", "error", err)
 		return nil, err
 	}
 
@@ -65,45 +76,13 @@ func (s *Service) CreateUser(ctx context.Context, email, password, displayName s
 	}
 
 	if err := s.repo.CreateUser(ctx, user); err != nil {
-		s.logger.Error("failed to create user in repo", "error", err)
+		s.logger.WithContext(ctx).Error("failed to create user in repo", "error", err)
 		return nil, err
 	}
 
 	return user, nil
 }
 
-// CreateGoogleUser creates or retrieves a user from a Google Sign-In.
-func (s *Service) CreateGoogleUser(ctx context.Context, googleID, email, displayName, photoURL string) (*User, error) {
-	user, err := s.repo.GetUserByGoogleID(ctx, googleID)
-	if err == nil {
-		now := time.Now()
-		user.LastLoginAt = &now
-		return user, s.repo.UpdateUser(ctx, user)
-	}
-
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		s.logger.Error("failed to get user by google id", "error", err)
-		return nil, err
-	}
-
-	newUser := &User{
-		ID:          uuid.New(),
-		GoogleID:    &googleID,
-		Email:       &email,
-		DisplayName: &displayName,
-		PhotoURL:    &photoURL,
-		Roles:       pq.StringArray{"user"},
-		IsActive:    true,
-	}
-
-	if err := s.repo.CreateUser(ctx, newUser); err != nil {
-		s.logger.Error("failed to create google user in repo", "error", err)
-		return nil, err
-	}
-
-	return newUser, nil
-}
-
 // AuthenticateUser checks a user's credentials and returns the user if valid.
 func (s *Service) AuthenticateUser(ctx context.Context, email, password string) (*User, error) {
 	user, err := s.repo.GetUserByEmail(ctx, email)
@@ -123,15 +102,40 @@ func (s *Service) AuthenticateUser(ctx context.Context, email, password string)
 		return nil, ErrAuthenticationFailed
 	}
 
-	now := time.Now()
-	user.LastLoginAt = &now
-	if err := s.repo.UpdateUser(ctx, user); err != nil {
-		s.logger.Error("failed to update last login time", "error", err, "userID", user.ID)
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
 	}
 
 	return user, nil
 }
 
+// CompleteSignIn records a finished sign-in, once every step including two-factor
+// authentication has passed. It cancels a pending deletion of the account, so only the
+// account's owner can restore it. It returns ErrUserNotFound if the account was purged
+// since the credentials were checked.
+func (s *Service) CompleteSignIn(ctx context.Context, user *User) error {
+	now := time.Now()
+	if user.DeletedAt != nil {
+		if err := s.repo.RestoreUser(ctx, user.ID, now); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			s.logger.WithContext(ctx).Error("failed to cancel account deletion", "error", err, "userID", user.ID)
+			return err
+		}
+		s.logger.WithContext(ctx).Info("Account deletion cancelled by sign-in", "userID", user.ID)
+	} else if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"last_login_at": now}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		s.logger.WithContext(ctx).Error("failed to update last login time", "error", err, "userID", user.ID)
+	}
+
+	user.LastLoginAt = &now
+	user.DeletedAt = nil
+	return nil
+}
+
 // GetUserByID retrieves a user by their ID.
 func (s *Service) GetUserByID(ctx context.Context, userIDStr string) (*User, error) {
 	userID, err := uuid.Parse(userIDStr)
@@ -149,6 +153,23 @@ func (s *Service) GetUserByID(ctx context.Context, userIDStr string) (*User, err
 	return user, nil
 }
 
+// GetAccount returns the state of a user's account that is embedded in their tokens.
+func (s *Service) GetAccount(ctx context.Context, userID uuid.UUID) (*auth.Account, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	account := &auth.Account{Roles: user.Roles, IsActive: user.IsActive && user.DeletedAt == nil}
+	if user.Email != nil {
+		account.Email = *user.Email
+	}
+	return account, nil
+}
+
 // UpdateUser updates a user's profile information.
 func (s *Service) UpdateUser(ctx context.Context, userIDStr string, displayName, photoURL *string, preferences map[string]interface{}) (*User, error) {
 	userID, err := uuid.Parse(userIDStr)
@@ -164,11 +185,14 @@ func (s *Service) UpdateUser(ctx context.Context, userIDStr string, displayName,
 		return nil, err
 	}
 
+	updates := map[string]interface{}{}
 	if displayName != nil {
 		user.DisplayName = displayName
+		updates["display_name"] = *displayName
 	}
 	if photoURL != nil {
 		user.PhotoURL = photoURL
+		updates["photo_url"] = *photoURL
 	}
 
 	if preferences != nil {
@@ -187,13 +211,137 @@ func (s *Service) UpdateUser(ctx context.Context, userIDStr string, displayName,
 		if err != nil {
 			return nil, errors.New("failed to serialize new user preferences")
 		}
+		changedPrefs, err := json.Marshal(preferences)
+		if err != nil {
+			return nil, errors.New("failed to serialize new user preferences")
+		}
 		user.Preferences = newPrefs
+		// Merge in the database too, so concurrent updates to other keys are kept
+		updates["preferences"] = gorm.Expr("COALESCE(preferences, '{}'::jsonb) || ?::jsonb", string(changedPrefs))
+	}
+	if len(updates) == 0 {
+		return user, nil
 	}
 
-	if err := s.repo.UpdateUser(ctx, user); err != nil {
-		s.logger.Error("failed to update user in repo", "error", err, "userID", user.ID)
+	if err := s.repo.UpdateUser(ctx, user.ID, updates); err != nil {
+		s.logger.WithContext(ctx).Error("failed to update user in repo", "error", err, "userID", user.ID)
 		return nil, err
 	}
 
 	return user, nil
-}
\ No newline at end of file
+}
+
+// --- Administration ---
+
+// ListUsers retrieves a paginated list of users matching filter.
+func (s *Service) ListUsers(ctx context.Context, filter UserFilter, page, size int) ([]User, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 20
+	}
+
+	users, total, err := s.repo.ListUsers(ctx, filter, page, size)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("failed to list users", "error", err)
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// SetUserActive activates or deactivates a user. actorIDStr is the administrator making
+// the change, who cannot deactivate themselves.
+func (s *Service) SetUserActive(ctx context.Context, actorIDStr, userIDStr string, active bool) (*User, error) {
+	user, err := s.GetUserByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if !active && isActor(actorIDStr, user.ID) {
+		return nil, ErrCannotModifySelf
+	}
+
+	if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"is_active": active}); err != nil {
+		s.logger.WithContext(ctx).Error("failed to update user status", "error", err, "userID", user.ID)
+		return nil, err
+	}
+	user.IsActive = active
+
+	s.logger.WithContext(ctx).Info("User status changed", "userID", user.ID, "active", active, "actorID", actorIDStr)
+	return user, nil
+}
+
+// GrantRole adds a role to a user. Granting a role the user already has is a no-op.
+func (s *Service) GrantRole(ctx context.Context, actorIDStr, userIDStr, role string) (*User, error) {
+	if !isKnownRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.GetUserByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range user.Roles {
+		if existing == role {
+			return user, nil
+		}
+	}
+
+	// Change the stored roles in place, so a concurrent change to another role is kept
+	roles := gorm.Expr("CASE WHEN ? = ANY(roles) THEN roles ELSE array_append(roles, ?) END", role, role)
+	if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"roles": roles}); err != nil {
+		s.logger.WithContext(ctx).Error("failed to grant role", "error", err, "userID", user.ID, "role", role)
+		return nil, err
+	}
+	user.Roles = append(user.Roles, role)
+
+	s.logger.WithContext(ctx).Info("Role granted", "userID", user.ID, "role", role, "actorID", actorIDStr)
+	return user, nil
+}
+
+// RevokeRole removes a role from a user. Administrators cannot remove their own admin role.
+func (s *Service) RevokeRole(ctx context.Context, actorIDStr, userIDStr, role string) (*User, error) {
+	if !isKnownRole(role) {
+		return nil, ErrInvalidRole
+	}
+	user, err := s.GetUserByID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if role == RoleAdmin && isActor(actorIDStr, user.ID) {
+		return nil, ErrCannotModifySelf
+	}
+
+	roles := make(pq.StringArray, 0, len(user.Roles))
+	for _, existing := range user.Roles {
+		if existing != role {
+			roles = append(roles, existing)
+		}
+	}
+	if len(roles) == len(user.Roles) {
+		return user, nil
+	}
+
+	if err := s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"roles": gorm.Expr("array_remove(roles, ?)", role)}); err != nil {
+		s.logger.WithContext(ctx).Error("failed to revoke role", "error", err, "userID", user.ID, "role", role)
+		return nil, err
+	}
+	user.Roles = roles
+
+	s.logger.WithContext(ctx).Info("Role revoked", "userID", user.ID, "role", role, "actorID", actorIDStr)
+	return user, nil
+}
+
+// isKnownRole reports whether role can be granted to users.
+func isKnownRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// isActor reports whether actorIDStr identifies the user with userID. IDs are compared
+// parsed, so differences in case or formatting cannot get around self-protection checks.
+func isActor(actorIDStr string, userID uuid.UUID) bool {
+	actorID, err := uuid.Parse(actorIDStr)
+	return err == nil && actorID == userID
+}
@@ -1,10 +1,12 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger interface for structured logging
@@ -15,6 +17,7 @@ type Logger interface {
 	Error(msg string, fields ...interface{})
 	Fatal(msg string, fields ...interface{})
 	With(fields ...interface{}) Logger
+	WithContext(ctx context.Context) Logger
 }
 
 type logger struct {
@@ -51,6 +54,8 @@ func NewLogger() Logger {
 		log.SetLevel(logrus.InfoLevel)
 	}
 
+	log.AddHook(traceHook{})
+
 	return &logger{
 		entry: logrus.NewEntry(log),
 	}
@@ -90,6 +95,35 @@ func (l *logger) With(fields ...interface{}) Logger {
 	}
 }
 
+// WithContext returns a logger whose lines carry the trace_id and span_id of the span in ctx,
+// if any, so log lines can be found from a trace and the other way round
+func (l *logger) WithContext(ctx context.Context) Logger {
+	return &logger{
+		entry: l.entry.WithContext(ctx),
+	}
+}
+
+// traceHook adds the trace and span IDs of an entry's context when the line is written, so
+// every logger derived from one with a context picks them up
+type traceHook struct{}
+
+func (traceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (traceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	entry.Data["trace_id"] = spanContext.TraceID().String()
+	entry.Data["span_id"] = spanContext.SpanID().String()
+	return nil
+}
+
 // parseFields converts key-value pairs to logrus.Fields
 func parseFields(fields ...interface{}) logrus.Fields {
 	if len(fields) == 0 {
@@ -2,7 +2,10 @@ package response
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -23,10 +26,18 @@ type APIError struct {
 
 // PaginatedData is a standard structure for paginated responses.
 type PaginatedData struct {
-	Items    interface{} `json:"items"`
-	Page     int         `json:"page"`
-	Size     int         `json:"size"`
-	Total    int64       `json:"total"`
+	Items      interface{} `json:"items"`
+	Page       int         `json:"page"`
+	Size       int         `json:"size"`
+	Total      int64       `json:"total"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// NewCursorPaginatedData creates a PaginatedData struct for cursor-paged results.
+func NewCursorPaginatedData(items interface{}, page, size int, total int64, nextCursor string) *PaginatedData {
+	data := NewPaginatedData(items, page, size, total)
+	data.NextCursor = nextCursor
+	return data
 }
 
 // SuccessMessage is a standard structure for simple success messages.
@@ -71,6 +82,11 @@ func Created(c *gin.Context, data interface{}) {
 	successResponse(c, http.StatusCreated, data)
 }
 
+// Accepted responds with 202 for requests that need another step to complete.
+func Accepted(c *gin.Context, data interface{}) {
+	successResponse(c, http.StatusAccepted, data)
+}
+
 // --- Error Responses ---
 
 func BadRequest(c *gin.Context, code, message string) {
@@ -93,10 +109,33 @@ func Conflict(c *gin.Context, code, message string) {
 	errorResponse(c, http.StatusConflict, code, message)
 }
 
+// TooManyRequests responds with 429 and a Retry-After header telling the client how many
+// seconds to wait.
+func TooManyRequests(c *gin.Context, code, message string, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	errorResponse(c, http.StatusTooManyRequests, code, message)
+}
+
 func InternalError(c *gin.Context, code, message string) {
 	errorResponse(c, http.StatusInternalServerError, code, message)
 }
 
+// ServiceUnavailable responds with 503, with details of what is unavailable.
+func ServiceUnavailable(c *gin.Context, code, message string, details interface{}) {
+	c.JSON(http.StatusServiceUnavailable, APIResponse{
+		Success: false,
+		Error: &APIError{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
 // ValidationError handles validation errors from the validator package.
 func ValidationError(c *gin.Context, err error) {
 	var errors []string
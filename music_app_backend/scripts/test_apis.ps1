@@ -13,6 +13,8 @@ Write-Host "1. Testing Health Check..." -ForegroundColor Yellow
 try {
     $healthResponse = Invoke-RestMethod -Uri "$HealthUrl/healthz" -Method Get
     $healthResponse | ConvertTo-Json -Depth 10
+    $readyResponse = Invoke-RestMethod -Uri "$HealthUrl/readyz?verbose=true" -Method Get
+    $readyResponse | ConvertTo-Json -Depth 10
 } catch {
     Write-Host "❌ Health check failed: $($_.Exception.Message)" -ForegroundColor Red
 }
@@ -13,6 +13,7 @@ echo "========================================"
 echo ""
 echo "1. Testing Health Check..."
 curl -s "$HEALTH_URL/healthz" | jq '.'
+curl -s "$HEALTH_URL/readyz?verbose=true" | jq '.'
 
 echo ""
 echo "2. Testing Version Info..."